@@ -0,0 +1,77 @@
+// Command mora genera el esqueleto de un recurso de MoraRouter.
+//
+// Uso:
+//
+//	mora scaffold [flags] <nombre> [campo:tipo ...]
+//
+// Por ejemplo, "mora scaffold -dry-run user name:string age:int" muestra el
+// controlador, el modelo y las pruebas de user sin escribirlos.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sazardev/mora-router/router"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "mora:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "uso: mora scaffold [flags] <nombre> [campo:tipo ...]")
+}
+
+func run(args []string) error {
+	if len(args) == 0 || args[0] != "scaffold" {
+		usage()
+		return fmt.Errorf("comando desconocido")
+	}
+
+	gen := router.NewRouteGenerator(nil)
+	fset := flag.NewFlagSet("scaffold", flag.ContinueOnError)
+	fset.Usage = func() {
+		usage()
+		fset.PrintDefaults()
+	}
+	fset.StringVar(&gen.OutputPath, "out", gen.OutputPath, "directorio para controladores y pruebas")
+	fset.StringVar(&gen.ResourcesPath, "resources", gen.ResourcesPath, "directorio para modelos")
+	fset.StringVar(&gen.ImportPath, "import", gen.ImportPath, "ruta de importación del paquete router")
+	fset.BoolVar(&gen.DryRun, "dry-run", false, "mostrar los archivos sin escribirlos")
+	fset.BoolVar(&gen.Force, "force", false, "sobrescribir archivos existentes")
+	if err := fset.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return fmt.Errorf("falta el nombre del recurso")
+	}
+
+	fields := make(map[string]string)
+	for _, arg := range fset.Args()[1:] {
+		name, typ, ok := strings.Cut(arg, ":")
+		if !ok || name == "" || typ == "" {
+			return fmt.Errorf("campo inválido %q, se espera campo:tipo", arg)
+		}
+		fields[name] = typ
+	}
+
+	files, err := gen.Scaffold(fset.Arg(0), fields)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if gen.DryRun {
+			fmt.Printf("--- %s\n%s\n", file.Path, file.Content)
+		} else {
+			fmt.Println("creado", file.Path)
+		}
+	}
+	return nil
+}
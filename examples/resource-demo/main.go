@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -119,14 +120,17 @@ type UserController struct {
 	store *UserStore
 }
 
-// Index lists all users
+// Index lists users one page at a time (?page= and ?per_page=)
 func (c *UserController) Index(w http.ResponseWriter, r *http.Request, p router.Params) {
 	users := c.store.GetUsers()
-	router.JSON(w, http.StatusOK, map[string]interface{}{
-		"message": "All users",
-		"count":   len(users),
-		"users":   users,
+	sort.Slice(users, func(i, j int) bool {
+		a, _ := strconv.Atoi(users[i].ID)
+		b, _ := strconv.Atoi(users[j].ID)
+		return a < b
 	})
+
+	offset, limit, meta := router.Paginate(r, len(users))
+	router.JSONPaginated(w, http.StatusOK, users[offset:offset+limit], meta)
 }
 
 // Show displays a single user
@@ -32,9 +32,9 @@ func runHubExample() {
 			// Immediately echo back to sender for confirmation
 			conn.SendText(fmt.Sprintf("Message sent: %s", string(msg)))
 
-			// Broadcast formatted message to all clients
+			// Broadcast formatted message to everyone else; the sender already got its confirmation
 			log.Printf("Broadcasting message to hub: %s", formattedMsg)
-			conn.Hub.BroadcastMessage([]byte(formattedMsg))
+			conn.Hub.BroadcastExcept(conn, []byte(formattedMsg))
 		},
 		OnConnect: func(conn *router.WebSocketConnection) {
 			log.Printf("New connection: %s", conn.ID)
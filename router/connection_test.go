@@ -1,8 +1,15 @@
 package router
 
 import (
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -49,11 +56,11 @@ func TestBasicConnection(t *testing.T) {
 func TestMultipleConnections(t *testing.T) {
 	r := New()
 
-	// Contador de peticiones
-	var requestCount int
+	// Contador de peticiones; atómico porque los handlers corren en paralelo
+	var requestCount atomic.Int32
 
 	r.Get("/count", func(w http.ResponseWriter, r *http.Request, p Params) {
-		requestCount++
+		requestCount.Add(1)
 		w.Write([]byte("ok"))
 	})
 
@@ -64,8 +71,11 @@ func TestMultipleConnections(t *testing.T) {
 	// Hacemos múltiples peticiones en paralelo
 	client := &http.Client{Timeout: 2 * time.Second}
 
+	var wg sync.WaitGroup
 	for i := 0; i < 10; i++ {
+		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			resp, err := client.Get(server.URL + "/count")
 			if err != nil {
 				t.Errorf("Error making request: %v", err)
@@ -75,12 +85,12 @@ func TestMultipleConnections(t *testing.T) {
 		}()
 	}
 
-	// Esperamos un poco para que las peticiones se procesen
-	time.Sleep(100 * time.Millisecond)
+	// Esperamos a que terminen las peticiones
+	wg.Wait()
 
 	// Verificamos que se hayan contabilizado todas (o casi todas)
-	if requestCount < 8 {
-		t.Errorf("Expected at least 8 requests processed, got %d", requestCount)
+	if n := requestCount.Load(); n < 8 {
+		t.Errorf("Expected at least 8 requests processed, got %d", n)
 	}
 }
 
@@ -197,3 +207,71 @@ func TestConnectionWithHeaders(t *testing.T) {
 		t.Errorf("Expected Content-Type header to be 'text/plain', got '%s'", resp.Header.Get("Content-Type"))
 	}
 }
+
+// TestListenAndServeGracefulShutdown verifica que ListenAndServe termine al
+// recibir SIGINT y espere a las peticiones en curso
+func TestListenAndServeGracefulShutdown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no se pueden enviar señales al propio proceso en Windows")
+	}
+	// con un receptor propio, SIGINT no termina el proceso de test aunque
+	// llegue antes de que ListenAndServe registre el suyo
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error reserving port: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	started := make(chan struct{})
+	r := New()
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request, p Params) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+	served := make(chan error, 1)
+	go func() {
+		served <- r.ListenAndServe(addr)
+	}()
+
+	// la petición lenta debe completarse aunque el apagado empiece durante ella
+	body := make(chan string, 1)
+	go func() {
+		for i := 0; i < 50; i++ {
+			resp, err := http.Get("http://" + addr + "/slow")
+			if err != nil {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			body <- string(data)
+			return
+		}
+		body <- ""
+	}()
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the server to start")
+	}
+
+	process, _ := os.FindProcess(os.Getpid())
+	process.Signal(os.Interrupt)
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Expected nil error after shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected ListenAndServe to return after SIGINT")
+	}
+	if got := <-body; got != "done" {
+		t.Errorf("Expected in-flight request to finish with 'done', got '%s'", got)
+	}
+}
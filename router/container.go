@@ -0,0 +1,62 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// container guarda los servicios registrados en un router, indexados por tipo.
+type container struct {
+	mu       sync.RWMutex
+	services map[reflect.Type]any
+	order    []reflect.Type // tipos en orden de registro, para que Resolve sea determinista
+}
+
+// Provide registra un servicio en el router, indexado por su tipo concreto,
+// para que los handlers lo obtengan con Resolve. Registrar otro valor del
+// mismo tipo reemplaza el anterior.
+func (r *MoraRouter) Provide(service any) {
+	r.services.set(reflect.TypeOf(service), service)
+}
+
+// ProvideAs registra un servicio bajo el tipo T, normalmente una interfaz,
+// para resolverlo por ese tipo en lugar del concreto.
+func ProvideAs[T any](r *MoraRouter, service T) {
+	r.services.set(reflect.TypeFor[T](), service)
+}
+
+// Resolve devuelve el servicio de tipo T registrado en el router que atiende
+// la petición. Si no hay uno registrado exactamente con ese tipo, sirve el
+// primero, en orden de registro, que lo implemente. ok es false si no hay
+// ninguno.
+func Resolve[T any](req *http.Request) (service T, ok bool) {
+	c, _ := req.Context().Value(servicesKey).(*container)
+	if c == nil {
+		return service, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	typ := reflect.TypeFor[T]()
+	if v, found := c.services[typ]; found {
+		return v.(T), true
+	}
+	for _, t := range c.order {
+		if s, match := c.services[t].(T); match {
+			return s, true
+		}
+	}
+	return service, false
+}
+
+// set registra un servicio bajo el tipo dado.
+func (c *container) set(typ reflect.Type, service any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.services[typ]; !exists {
+		c.order = append(c.order, typ)
+	}
+	c.services[typ] = service
+}
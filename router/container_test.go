@@ -0,0 +1,72 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+)
+
+type greeter interface {
+	Greet(name string) string
+}
+
+type spanishGreeter struct {
+	prefix string
+}
+
+func (g *spanishGreeter) Greet(name string) string {
+	return g.prefix + name
+}
+
+type englishGreeter struct{}
+
+func (englishGreeter) Greet(name string) string {
+	return "Hello, " + name
+}
+
+// TestServiceContainer verifica el registro y la resolución de servicios
+func TestServiceContainer(t *testing.T) {
+	r := New()
+	r.Provide(&spanishGreeter{prefix: "Hola, "})
+	r.Provide(englishGreeter{})
+
+	r.Get("/concrete/:name", func(w http.ResponseWriter, r *http.Request, p Params) {
+		g, ok := Resolve[*spanishGreeter](r)
+		if !ok {
+			http.Error(w, "greeter not found", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(g.Greet(p["name"])))
+	})
+	r.Get("/interface/:name", func(w http.ResponseWriter, r *http.Request, p Params) {
+		g, ok := Resolve[greeter](r)
+		if !ok {
+			http.Error(w, "greeter not found", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(g.Greet(p["name"])))
+	})
+	r.Get("/missing", func(w http.ResponseWriter, r *http.Request, p Params) {
+		if _, ok := Resolve[*TemplateManager](r); ok {
+			t.Errorf("Expected unregistered service not to resolve")
+		}
+	})
+
+	client := NewTestClient(r)
+
+	if resp := client.Get("/concrete/Ana"); resp.Text() != "Hola, Ana" {
+		t.Errorf("Expected 'Hola, Ana', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+	// Una interfaz se resuelve con el primer servicio registrado que la implementa
+	for range 20 {
+		if resp := client.Get("/interface/Luis"); resp.Text() != "Hola, Luis" {
+			t.Fatalf("Expected 'Hola, Luis', got %d '%s'", resp.StatusCode, resp.Text())
+		}
+	}
+	client.Get("/missing")
+
+	// ProvideAs registra por interfaz y tiene prioridad sobre la búsqueda
+	ProvideAs[greeter](r, &spanishGreeter{prefix: "Buenas, "})
+	if resp := client.Get("/interface/Eva"); resp.Text() != "Buenas, Eva" {
+		t.Errorf("Expected 'Buenas, Eva', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+}
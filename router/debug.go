@@ -1,6 +1,7 @@
 package router
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,12 +11,38 @@ import (
 // Debug creates a debugging middleware that adds request inspection
 func WithDebug() Option {
 	return func(r *MoraRouter) {
-		r.middlewareRegistry["debug"] = debugMiddleware
+		r.registerMiddleware("debug", debugMiddleware)
 		r.middlewares = append(r.middlewares, debugMiddleware)
 
 		// Register inspector at /_mora/debug
-		r.Get("/_mora/debug", r.debugHandler)
-		r.Get("/_mora/routes", r.routesHandler)
+		r.Get("/_mora/debug", r.debugOnly(r.debugHandler))
+		r.Get("/_mora/routes", r.debugOnly(r.routesHandler))
+	}
+}
+
+// WithDebugAuth protege los endpoints de depuración bajo /_mora con
+// autenticación HTTP Basic.
+func WithDebugAuth(username, password string) Option {
+	return func(r *MoraRouter) {
+		r.debugAuth = func(req *http.Request) bool {
+			user, pass, ok := req.BasicAuth()
+			return ok &&
+				subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1
+		}
+	}
+}
+
+// debugOnly exige la autenticación de WithDebugAuth, si se configuró, antes
+// de servir un endpoint de depuración
+func (r *MoraRouter) debugOnly(next HandlerFunc) HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request, p Params) {
+		if r.debugAuth != nil && !r.debugAuth(req) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="mora debug"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, req, p)
 	}
 }
 
@@ -39,45 +66,7 @@ func debugMiddleware(next HandlerFunc) HandlerFunc {
 
 // routesHandler devuelve todas las rutas registradas en formato JSON
 func (r *MoraRouter) routesHandler(w http.ResponseWriter, req *http.Request, p Params) {
-	type RouteInfo struct {
-		Method   string   `json:"method"`
-		Pattern  string   `json:"pattern"`
-		Segments []string `json:"segments"`
-		Params   []string `json:"params"`
-	}
-
-	routes := make([]RouteInfo, 0, len(r.routes))
-	for _, rt := range r.routes {
-		params := []string{}
-		segments := []string{}
-
-		for _, seg := range rt.segments {
-			if seg.name != "" {
-				params = append(params, seg.name)
-			}
-
-			if seg.literal != "" {
-				segments = append(segments, seg.literal)
-			} else if seg.wildcard {
-				segments = append(segments, "*"+seg.name)
-			} else {
-				var segDesc string
-				if seg.regex != nil {
-					segDesc = fmt.Sprintf(":%s(%s)", seg.name, seg.regex.String())
-				} else {
-					segDesc = ":" + seg.name
-				}
-				segments = append(segments, segDesc)
-			}
-		}
-
-		routes = append(routes, RouteInfo{
-			Method:   rt.method,
-			Pattern:  rt.pattern,
-			Segments: segments,
-			Params:   params,
-		})
-	}
+	routes := r.Routes()
 
 	// Sort routes by method and pattern for easier reading
 	sort.Slice(routes, func(i, j int) bool {
@@ -103,7 +92,7 @@ func (r *MoraRouter) debugHandler(w http.ResponseWriter, req *http.Request, p Pa
 			"params":     p,
 		},
 		"router": map[string]interface{}{
-			"routeCount":       len(r.routes),
+			"routeCount":       len(r.routesSnapshot()),
 			"mountCount":       len(r.mounts),
 			"middlewareCount":  len(r.middlewares),
 			"registeredMacros": len(MacroRegistry),
@@ -1,23 +1,65 @@
 package router
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
-// FormFile representa un archivo subido por un formulario.
+// FormFile representa un archivo subido por un formulario. Content solo se
+// rellena si el archivo cabe en memoria (ver FormStreamFiles); Open sirve
+// siempre para leerlo.
 type FormFile struct {
 	Filename string
 	Size     int64
 	Header   map[string][]string
 	Content  []byte
+
+	// origen del archivo cuando Content no está cargado
+	fileHeader *multipart.FileHeader
+	tempPath   string
+}
+
+// Open abre el contenido del archivo, ya esté en memoria o en disco.
+func (f *FormFile) Open() (io.ReadCloser, error) {
+	switch {
+	case f.fileHeader != nil:
+		return f.fileHeader.Open()
+	case f.tempPath != "":
+		return os.Open(f.tempPath)
+	default:
+		return io.NopCloser(bytes.NewReader(f.Content)), nil
+	}
+}
+
+// sniffContentType detecta el tipo MIME a partir de los primeros 512 bytes.
+func (f *FormFile) sniffContentType() (string, error) {
+	if f.Content != nil || (f.fileHeader == nil && f.tempPath == "") {
+		return http.DetectContentType(f.Content), nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(rc, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
 }
 
 // Form encapsula los datos de un formulario y sus posibles errores.
@@ -26,20 +68,100 @@ type Form struct {
 	Files     map[string][]*FormFile
 	Errors    map[string]string
 	validated bool
+
+	// archivos temporales creados al leer el multipart parte a parte
+	tempFiles []string
+}
+
+// ErrTooManyParts se devuelve cuando un formulario multipart supera FormOptions.MaxParts.
+var ErrTooManyParts = errors.New("multipart form has too many parts")
+
+// ErrFormTooLarge se devuelve cuando el cuerpo supera FormOptions.MaxTotalBytes
+// o, al leer el multipart parte a parte, sus valores de texto superan el
+// límite de ParseMultipartForm (maxMemory más 10MB).
+var ErrFormTooLarge = errors.New("form body too large")
+
+// FormOptions configura los límites aplicados al parsear un formulario.
+type FormOptions struct {
+	// Número máximo de partes en un cuerpo multipart (0 = sin límite)
+	MaxParts int
+	// Tamaño máximo del cuerpo completo en bytes (0 = sin límite)
+	MaxTotalBytes int64
+	// Si es mayor que 0, los archivos de más de este tamaño se guardan en
+	// disco y no se cargan en FormFile.Content
+	StreamThreshold int64
+}
+
+// FormOption modifica las FormOptions usadas por NewForm y BindForm.
+type FormOption func(*FormOptions)
+
+// FormMaxParts limita el número de partes aceptadas en un formulario multipart.
+func FormMaxParts(n int) FormOption {
+	return func(o *FormOptions) {
+		o.MaxParts = n
+	}
+}
+
+// FormMaxTotalBytes limita el tamaño total del cuerpo del formulario.
+func FormMaxTotalBytes(n int64) FormOption {
+	return func(o *FormOptions) {
+		o.MaxTotalBytes = n
+	}
+}
+
+// FormStreamFiles guarda en archivos temporales los archivos subidos de más
+// de threshold bytes en lugar de cargarlos en memoria; se leen con
+// FormFile.Open. Los más pequeños siguen teniendo Content.
+func FormStreamFiles(threshold int64) FormOption {
+	return func(o *FormOptions) {
+		o.StreamThreshold = threshold
+	}
 }
 
 // NewForm crea un nuevo Form desde una petición HTTP.
-func NewForm(r *http.Request, maxMemory int64) (*Form, error) {
+func NewForm(r *http.Request, maxMemory int64, opts ...FormOption) (*Form, error) {
 	if maxMemory <= 0 {
 		maxMemory = 32 << 20 // 32MB por defecto
 	}
 
+	var options FormOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	// Limitar el tamaño total del cuerpo antes de leerlo
+	if options.MaxTotalBytes > 0 {
+		if r.ContentLength > options.MaxTotalBytes {
+			return nil, ErrFormTooLarge
+		}
+		r.Body = http.MaxBytesReader(nil, r.Body, options.MaxTotalBytes)
+	}
+
+	// Los archivos que superan maxMemory quedan en disco
+	if options.StreamThreshold > 0 && options.StreamThreshold < maxMemory {
+		maxMemory = options.StreamThreshold
+	}
+
+	// Con límite de partes se lee el multipart parte a parte para poder cortar a tiempo
+	if options.MaxParts > 0 {
+		if mr, err := r.MultipartReader(); err == nil {
+			return newFormFromMultipart(r, mr, options.MaxParts, maxMemory)
+		}
+	}
+
 	// Parsear formulario y archivos
 	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, ErrFormTooLarge
+		}
 		// Si no es multipart, intentar como form normal
 		if err != http.ErrNotMultipart {
 			// Intentar ParseForm para formularios normales
 			if err := r.ParseForm(); err != nil {
+				if errors.As(err, &maxBytesErr) {
+					return nil, ErrFormTooLarge
+				}
 				return nil, fmt.Errorf("error parsing form: %w", err)
 			}
 		}
@@ -70,6 +192,16 @@ func NewForm(r *http.Request, maxMemory int64) (*Form, error) {
 	if r.MultipartForm != nil && r.MultipartForm.File != nil {
 		for field, fileHeaders := range r.MultipartForm.File {
 			for _, header := range fileHeaders {
+				if options.StreamThreshold > 0 && header.Size > options.StreamThreshold {
+					form.Files[field] = append(form.Files[field], &FormFile{
+						Filename:   header.Filename,
+						Size:       header.Size,
+						Header:     header.Header,
+						fileHeader: header,
+					})
+					continue
+				}
+
 				file, err := header.Open()
 				if err != nil {
 					return nil, fmt.Errorf("error opening uploaded file: %w", err)
@@ -97,6 +229,134 @@ func NewForm(r *http.Request, maxMemory int64) (*Form, error) {
 	return form, nil
 }
 
+// newFormFromMultipart construye un Form leyendo el cuerpo multipart parte a
+// parte y devuelve ErrTooManyParts en cuanto se supera maxParts. Como en
+// ParseMultipartForm, los archivos quedan en memoria mientras quepan en
+// maxMemory y el resto se vuelca a archivos temporales, y los valores de
+// texto juntos no pueden superar maxMemory más 10MB.
+func newFormFromMultipart(r *http.Request, mr *multipart.Reader, maxParts int, maxMemory int64) (*Form, error) {
+	valueBytes := maxMemory + 10<<20
+	if valueBytes <= 0 {
+		valueBytes = math.MaxInt64 - 1
+	}
+
+	form := &Form{
+		Values: make(map[string][]string),
+		Files:  make(map[string][]*FormFile),
+		Errors: make(map[string]string),
+	}
+
+	// Los valores de la query string siguen disponibles como en ParseForm
+	for k, v := range r.URL.Query() {
+		form.Values[k] = append(form.Values[k], v...)
+	}
+
+	parts := 0
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			form.RemoveAll()
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, ErrFormTooLarge
+			}
+			return nil, fmt.Errorf("error parsing form: %w", err)
+		}
+
+		parts++
+		if parts > maxParts {
+			part.Close()
+			form.RemoveAll()
+			return nil, ErrTooManyParts
+		}
+
+		if part.FileName() != "" {
+			file, err := form.readFilePart(part, maxMemory)
+			part.Close()
+			if err != nil {
+				form.RemoveAll()
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					return nil, ErrFormTooLarge
+				}
+				return nil, fmt.Errorf("error reading form part: %w", err)
+			}
+			maxMemory -= int64(len(file.Content))
+			form.Files[part.FormName()] = append(form.Files[part.FormName()], file)
+			continue
+		}
+
+		var content strings.Builder
+		n, err := io.CopyN(&content, part, valueBytes+1)
+		part.Close()
+		if err != nil && err != io.EOF {
+			form.RemoveAll()
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, ErrFormTooLarge
+			}
+			return nil, fmt.Errorf("error reading form part: %w", err)
+		}
+		if n > valueBytes {
+			form.RemoveAll()
+			return nil, ErrFormTooLarge
+		}
+		valueBytes -= n
+
+		field := part.FormName()
+		form.Values[field] = append(form.Values[field], content.String())
+	}
+
+	return form, nil
+}
+
+// readFilePart lee un archivo del multipart en memoria si no supera threshold
+// bytes y, si lo supera, lo vuelca a un archivo temporal.
+func (f *Form) readFilePart(part *multipart.Part, threshold int64) (*FormFile, error) {
+	file := &FormFile{Filename: part.FileName(), Header: part.Header}
+
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, part, threshold+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n <= threshold {
+		file.Content, file.Size = buf.Bytes(), n
+		return file, nil
+	}
+
+	tmp, err := os.CreateTemp("", "mora-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	f.tempFiles = append(f.tempFiles, tmp.Name())
+	defer tmp.Close()
+
+	written, err := io.Copy(tmp, io.MultiReader(&buf, part))
+	if err != nil {
+		return nil, err
+	}
+	file.Size, file.tempPath = written, tmp.Name()
+	return file, nil
+}
+
+// RemoveAll borra los archivos temporales creados por FormStreamFiles al
+// leer el formulario parte a parte. Los de ParseMultipartForm los borra el
+// servidor HTTP al terminar la petición.
+func (f *Form) RemoveAll() error {
+	var errs []error
+	for _, name := range f.tempFiles {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	f.tempFiles = nil
+	return errors.Join(errs...)
+}
+
 // Get devuelve el primer valor para un campo del formulario.
 func (f *Form) Get(key string) string {
 	if vals, ok := f.Values[key]; ok && len(vals) > 0 {
@@ -191,6 +451,80 @@ func (f *Form) IsFloat(field string) *Form {
 	return f
 }
 
+// MaxFiles valida que un campo no tenga más de n archivos subidos.
+func (f *Form) MaxFiles(field string, n int) *Form {
+	if count := len(f.GetAllFiles(field)); count > n {
+		f.Errors[field] = fmt.Sprintf("This field cannot have more than %d files", n)
+	}
+	return f
+}
+
+// MaxTotalFileSize valida que la suma de tamaños de los archivos de un campo no exceda un máximo.
+func (f *Form) MaxTotalFileSize(field string, bytes int64) *Form {
+	var total int64
+	for _, file := range f.GetAllFiles(field) {
+		total += file.Size
+	}
+	if total > bytes {
+		f.Errors[field] = fmt.Sprintf("The files in this field cannot exceed %d bytes in total", bytes)
+	}
+	return f
+}
+
+// FileValidation describe las restricciones que ValidateFile aplica a cada
+// archivo de un campo.
+type FileValidation struct {
+	// Tamaño máximo de cada archivo en bytes (0 = sin límite)
+	MaxSize int64
+	// Tipos MIME permitidos ("image/png", o "image/*" para cualquier imagen).
+	// El tipo se detecta a partir del contenido, no del Content-Type enviado
+	AllowedTypes []string
+	// Extensiones permitidas, como ".jpg", sin distinguir mayúsculas
+	AllowedExtensions []string
+}
+
+// ValidateFile valida el tamaño, el tipo real y la extensión de los archivos
+// de un campo. El primer archivo que no cumple se registra en Errors.
+func (f *Form) ValidateFile(field string, opts FileValidation) *Form {
+	for _, file := range f.GetAllFiles(field) {
+		if opts.MaxSize > 0 && file.Size > opts.MaxSize {
+			f.Errors[field] = fmt.Sprintf("File %s cannot be larger than %d bytes", file.Filename, opts.MaxSize)
+			return f
+		}
+
+		if len(opts.AllowedExtensions) > 0 {
+			ext := filepath.Ext(file.Filename)
+			if !slices.ContainsFunc(opts.AllowedExtensions, func(allowed string) bool { return strings.EqualFold(allowed, ext) }) {
+				f.Errors[field] = fmt.Sprintf("File %s must have one of these extensions: %s", file.Filename, strings.Join(opts.AllowedExtensions, ", "))
+				return f
+			}
+		}
+
+		if len(opts.AllowedTypes) > 0 {
+			sniffed, err := file.sniffContentType()
+			if err != nil {
+				f.Errors[field] = fmt.Sprintf("File %s could not be read", file.Filename)
+				return f
+			}
+			detected, _, _ := strings.Cut(sniffed, ";")
+			if !slices.ContainsFunc(opts.AllowedTypes, func(allowed string) bool { return mimeTypeMatches(allowed, detected) }) {
+				f.Errors[field] = fmt.Sprintf("File %s has a type that is not allowed (%s)", file.Filename, detected)
+				return f
+			}
+		}
+	}
+	return f
+}
+
+// mimeTypeMatches indica si mediaType coincide con pattern, que puede ser un
+// tipo completo o "tipo/*".
+func mimeTypeMatches(pattern, mediaType string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mediaType, prefix+"/")
+	}
+	return strings.EqualFold(pattern, mediaType)
+}
+
 // CustomValidation aplica una validación personalizada.
 func (f *Form) CustomValidation(field string, fn func(string) bool, message string) *Form {
 	value := f.Get(field)
@@ -226,39 +560,128 @@ func (f *Form) AddError(field, message string) *Form {
 	return f
 }
 
-// SaveFile guarda un archivo subido en una ubicación específica.
+// SaveFile guarda el primer archivo de fieldName en targetDir con su nombre
+// saneado por SanitizeFilename, sobrescribiendo un archivo con el mismo nombre.
 func (f *Form) SaveFile(fieldName, targetDir string) (string, error) {
 	file := f.GetFile(fieldName)
 	if file == nil {
 		return "", fmt.Errorf("no file uploaded for field %s", fieldName)
 	}
 
-	if targetDir == "" {
-		targetDir = os.TempDir()
+	if err := ensureUploadDir(&targetDir); err != nil {
+		return "", err
+	}
+
+	return saveFormFile(file, targetDir, false)
+}
+
+// SaveAll guarda todos los archivos de fieldName en targetDir y devuelve sus
+// rutas. Los nombres se sanean y, si ya existe un archivo con el mismo nombre,
+// se añade un sufijo (foto_1.jpg, foto_2.jpg...) en lugar de sobrescribirlo.
+func (f *Form) SaveAll(fieldName, targetDir string) ([]string, error) {
+	files := f.GetAllFiles(fieldName)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file uploaded for field %s", fieldName)
+	}
+
+	if err := ensureUploadDir(&targetDir); err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		path, err := saveFormFile(file, targetDir, true)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// ensureUploadDir crea el directorio de destino, que por defecto es el
+// directorio temporal del sistema.
+func ensureUploadDir(targetDir *string) error {
+	if *targetDir == "" {
+		*targetDir = os.TempDir()
 	}
 
 	// Crear directorio si no existe
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
+	if err := os.MkdirAll(*targetDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
 	}
+	return nil
+}
 
+// saveFormFile copia file a targetDir. Con unique nunca reemplaza un archivo
+// existente: O_EXCL garantiza que dos peticiones no escriban el mismo.
+func saveFormFile(file *FormFile, targetDir string, unique bool) (string, error) {
 	// Generar nombre de archivo único si es necesario
-	fileName := file.Filename
+	fileName := SanitizeFilename(file.Filename)
 	if fileName == "" {
-		fileName = fmt.Sprintf("upload_%d_%s", time.Now().UnixNano(), strconv.Itoa(int(time.Now().Unix())))
+		fileName = fmt.Sprintf("upload_%d", time.Now().UnixNano())
 	}
 
-	// Crear ruta completa
-	filePath := filepath.Join(targetDir, fileName)
+	// Copiar el contenido sin cargarlo entero en memoria
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
 
-	// Escribir archivo
-	if err := os.WriteFile(filePath, file.Content, 0644); err != nil {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if unique {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+
+	ext := filepath.Ext(fileName)
+	base := strings.TrimSuffix(fileName, ext)
+	filePath := filepath.Join(targetDir, fileName)
+	dst, err := os.OpenFile(filePath, flags, 0644)
+	for i := 1; unique && errors.Is(err, os.ErrExist); i++ {
+		filePath = filepath.Join(targetDir, fmt.Sprintf("%s_%d%s", base, i, ext))
+		dst, err = os.OpenFile(filePath, flags, 0644)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := dst.Close(); err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
 	return filePath, nil
 }
 
+// SanitizeFilename reduce el nombre enviado por el cliente a un nombre de
+// archivo seguro: descarta los directorios (con / o \), los caracteres de
+// control y los puntos iniciales, de modo que no pueda salir del directorio
+// de destino ni crear archivos ocultos. Devuelve "" si no queda nada.
+func SanitizeFilename(name string) string {
+	// Los navegadores antiguos envían la ruta completa, también la de Windows
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if len(name) > 255 {
+		ext := filepath.Ext(name)
+		if len(ext) > 32 {
+			ext = ""
+		}
+		name = name[:255-len(ext)] + ext
+	}
+	return strings.ToValidUTF8(name, "")
+}
+
 // Bind completa un struct con datos del formulario usando reflection.
 func (f *Form) Bind(obj interface{}) error {
 	// Validate forms first
@@ -301,54 +724,105 @@ func (f *Form) Bind(obj interface{}) error {
 			continue
 		}
 
+		// Si el campo admite varios archivos
+		if typeField.Type == reflect.TypeOf([]*FormFile{}) {
+			if files := f.GetAllFiles(formKey); len(files) > 0 {
+				field.Set(reflect.ValueOf(files))
+			}
+			continue
+		}
+
+		// Los slices reciben todos los valores del campo (p. ej. checkboxes)
+		if field.Kind() == reflect.Slice {
+			values := f.GetAll(formKey)
+			if len(values) == 0 {
+				continue
+			}
+			slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+			for j, v := range values {
+				if err := setFormValue(slice.Index(j), typeField, formKey, v); err != nil {
+					return err
+				}
+			}
+			field.Set(slice)
+			continue
+		}
+
 		// Para valores normales
 		formVal := f.Get(formKey)
 		if formVal == "" {
 			continue
 		}
+		if err := setFormValue(field, typeField, formKey, formVal); err != nil {
+			return err
+		}
+	}
 
-		switch field.Kind() {
-		case reflect.String:
-			field.SetString(formVal)
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			intVal, err := strconv.ParseInt(formVal, 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid integer value for field %s: %w", formKey, err)
-			}
-			field.SetInt(intVal)
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			uintVal, err := strconv.ParseUint(formVal, 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid unsigned integer value for field %s: %w", formKey, err)
-			}
-			field.SetUint(uintVal)
-		case reflect.Float32, reflect.Float64:
-			floatVal, err := strconv.ParseFloat(formVal, 64)
-			if err != nil {
-				return fmt.Errorf("invalid float value for field %s: %w", formKey, err)
-			}
-			field.SetFloat(floatVal)
-		case reflect.Bool:
-			boolVal := false
-			if formVal == "on" || formVal == "true" || formVal == "1" || formVal == "yes" {
-				boolVal = true
-			}
-			field.SetBool(boolVal)
+	return nil
+}
+
+// setFormValue convierte formVal al tipo de field. Los time.Time se leen con
+// el layout del tag `timeFormat` (por defecto "2006-01-02", el de los
+// <input type="date">).
+func setFormValue(field reflect.Value, typeField reflect.StructField, formKey, formVal string) error {
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		layout := typeField.Tag.Get("timeFormat")
+		if layout == "" {
+			layout = "2006-01-02"
 		}
+		t, err := time.Parse(layout, formVal)
+		if err != nil {
+			return fmt.Errorf("invalid time value for field %s: %w", formKey, err)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
 	}
 
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(formVal)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intVal, err := strconv.ParseInt(formVal, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer value for field %s: %w", formKey, err)
+		}
+		field.SetInt(intVal)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintVal, err := strconv.ParseUint(formVal, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer value for field %s: %w", formKey, err)
+		}
+		field.SetUint(uintVal)
+	case reflect.Float32, reflect.Float64:
+		floatVal, err := strconv.ParseFloat(formVal, 64)
+		if err != nil {
+			return fmt.Errorf("invalid float value for field %s: %w", formKey, err)
+		}
+		field.SetFloat(floatVal)
+	case reflect.Bool:
+		boolVal := false
+		if formVal == "on" || formVal == "true" || formVal == "1" || formVal == "yes" {
+			boolVal = true
+		}
+		field.SetBool(boolVal)
+	}
 	return nil
 }
 
 // BindForm procesa un formulario, lo valida y enlaza a un struct.
-func BindForm[T any](h func(http.ResponseWriter, *http.Request, Params, *Form, T)) HandlerFunc {
+// Acepta FormOption para limitar el número de partes o el tamaño total del cuerpo.
+func BindForm[T any](h func(http.ResponseWriter, *http.Request, Params, *Form, T), opts ...FormOption) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request, p Params) {
 		var obj T
 
 		// Crear y procesar formulario
-		form, err := NewForm(r, 32<<20) // 32MB limit
+		form, err := NewForm(r, 32<<20, opts...) // 32MB limit
 		if err != nil {
-			http.Error(w, fmt.Sprintf("error processing form: %v", err), http.StatusBadRequest)
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrFormTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, fmt.Sprintf("error processing form: %v", err), status)
 			return
 		}
 
@@ -358,13 +832,14 @@ func BindForm[T any](h func(http.ResponseWriter, *http.Request, Params, *Form, T
 		}
 
 		// Validar struct usando tags validate
-		if errs := ValidateStruct(obj); len(errs) > 0 {
+		if errs := GetValidator(r).Validate(obj); len(errs) > 0 {
 			for _, e := range errs {
 				form.AddError(e.Field, e.Message)
 			}
 		}
 
 		// Llamar al handler con el formulario y el objeto enlazado
+		defer form.RemoveAll()
 		h(w, r, p, form, obj)
 	}
 }
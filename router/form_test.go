@@ -0,0 +1,405 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// multipartBody construye un cuerpo multipart con el número de campos indicado
+func multipartBody(t *testing.T, fields int) (*bytes.Buffer, string) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for i := 0; i < fields; i++ {
+		if err := mw.WriteField(fmt.Sprintf("field%d", i), "value"); err != nil {
+			t.Fatalf("Error writing field: %v", err)
+		}
+	}
+	mw.Close()
+	return body, mw.FormDataContentType()
+}
+
+// TestFormMaxParts verifica que se rechacen formularios con demasiadas partes
+func TestFormMaxParts(t *testing.T) {
+	type Input struct {
+		Field0 string `form:"field0"`
+	}
+
+	r := New()
+	r.Post("/upload", BindForm(func(w http.ResponseWriter, r *http.Request, p Params, form *Form, in Input) {
+		w.Write([]byte(in.Field0))
+	}, FormMaxParts(5)))
+
+	// Dentro del límite
+	body, contentType := multipartBody(t, 3)
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.String() != "value" {
+		t.Errorf("Expected 200 with bound value, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Excede el límite de partes
+	body, contentType = multipartBody(t, 50)
+	req = httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for too many parts, got %d", rr.Code)
+	}
+
+	// Los valores de texto tienen el mismo límite que en ParseMultipartForm
+	body = &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	mw.WriteField("field0", strings.Repeat("x", 1024+10<<20+1))
+	mw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if _, err := NewForm(req, 1024, FormMaxParts(5)); err != ErrFormTooLarge {
+		t.Errorf("Expected ErrFormTooLarge for an oversized value, got %v", err)
+	}
+}
+
+// TestFormMaxTotalBytes verifica que se rechacen cuerpos que superan el tamaño total
+func TestFormMaxTotalBytes(t *testing.T) {
+	r := New()
+	r.Post("/upload", BindForm(func(w http.ResponseWriter, r *http.Request, p Params, form *Form, in struct{}) {
+		w.WriteHeader(http.StatusOK)
+	}, FormMaxTotalBytes(256)))
+
+	body, contentType := multipartBody(t, 50)
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 for oversized body, got %d", rr.Code)
+	}
+}
+
+// TestFormMultipleFiles verifica la validación agregada de varios archivos en un campo
+func TestFormMultipleFiles(t *testing.T) {
+	type Gallery struct {
+		Photos []*FormFile `form:"photos"`
+	}
+
+	var bound Gallery
+	var formErrors map[string]string
+
+	r := New()
+	r.Post("/gallery", BindForm(func(w http.ResponseWriter, r *http.Request, p Params, form *Form, in Gallery) {
+		form.MaxFiles("photos", 2).MaxTotalFileSize("photos", 1024)
+		bound = in
+		formErrors = form.GetErrors()
+	}))
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for i := 0; i < 3; i++ {
+		part, err := mw.CreateFormFile("photos", fmt.Sprintf("photo%d.jpg", i))
+		if err != nil {
+			t.Fatalf("Error creating file part: %v", err)
+		}
+		part.Write(bytes.Repeat([]byte("x"), 100))
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/gallery", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(bound.Photos) != 3 {
+		t.Errorf("Expected 3 bound files, got %d", len(bound.Photos))
+	}
+	if _, ok := formErrors["photos"]; !ok {
+		t.Errorf("Expected max files error for 'photos', got %v", formErrors)
+	}
+
+	// El tamaño total se valida sobre todos los archivos
+	form := &Form{Errors: map[string]string{}, Files: map[string][]*FormFile{
+		"docs": {{Size: 600}, {Size: 600}},
+	}}
+	if form.MaxFiles("docs", 2).HasErrors() {
+		t.Errorf("Expected 2 files to pass a max of 2")
+	}
+	if !form.MaxTotalFileSize("docs", 1000).HasErrors() {
+		t.Errorf("Expected total size 1200 to exceed 1000")
+	}
+}
+
+// TestFormValidateFile verifica el tamaño, el tipo detectado y la extensión de los archivos
+func TestFormValidateFile(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...)
+	images := FileValidation{MaxSize: 1024, AllowedTypes: []string{"image/*"}, AllowedExtensions: []string{".png", ".jpg"}}
+
+	tests := []struct {
+		name  string
+		file  *FormFile
+		valid bool
+	}{
+		{"valid png", &FormFile{Filename: "avatar.PNG", Size: int64(len(png)), Content: png}, true},
+		{"too large", &FormFile{Filename: "big.png", Size: 2048, Content: png}, false},
+		{"wrong extension", &FormFile{Filename: "avatar.gif", Size: int64(len(png)), Content: png}, false},
+		// El Content-Type enviado por el cliente no cuenta, solo el contenido
+		{"script disguised as image", &FormFile{
+			Filename: "evil.png",
+			Size:     30,
+			Header:   map[string][]string{"Content-Type": {"image/png"}},
+			Content:  []byte("<script>alert(1)</script>"),
+		}, false},
+	}
+	for _, tt := range tests {
+		form := &Form{Errors: map[string]string{}, Files: map[string][]*FormFile{"avatar": {tt.file}}}
+		form.ValidateFile("avatar", images)
+		if form.Valid() != tt.valid {
+			t.Errorf("%s: expected valid=%v, got errors %v", tt.name, tt.valid, form.Errors)
+		}
+	}
+
+	// Los tipos exactos también se aceptan y un campo sin archivos no es un error
+	form := &Form{Errors: map[string]string{}, Files: map[string][]*FormFile{"doc": {{Filename: "a.txt", Content: []byte("hello")}}}}
+	if !form.ValidateFile("doc", FileValidation{AllowedTypes: []string{"text/plain"}}).ValidateFile("missing", images).Valid() {
+		t.Errorf("Expected text/plain file to be valid, got %v", form.Errors)
+	}
+}
+
+// TestFormStreamFiles verifica que los archivos grandes se lean desde disco y no desde Content
+func TestFormStreamFiles(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789"), 1000)
+	newRequest := func() *http.Request {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		for name, content := range map[string][]byte{"big.bin": big, "small.txt": []byte("hello")} {
+			part, err := mw.CreateFormFile("files", name)
+			if err != nil {
+				t.Fatalf("Error creating file part: %v", err)
+			}
+			part.Write(content)
+		}
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req
+	}
+
+	// ParseMultipartForm y lectura parte a parte (con MaxParts), con umbral
+	// propio o por superar maxMemory
+	for name, tc := range map[string]struct {
+		maxMemory int64
+		opts      []FormOption
+	}{
+		"parsed":          {0, []FormOption{FormStreamFiles(1024)}},
+		"streamed":        {0, []FormOption{FormStreamFiles(1024), FormMaxParts(10)}},
+		"streamed memory": {1024, []FormOption{FormMaxParts(10)}},
+	} {
+		req := newRequest()
+		form, err := NewForm(req, tc.maxMemory, tc.opts...)
+		if err != nil {
+			t.Fatalf("%s: error parsing form: %v", name, err)
+		}
+		if req.MultipartForm != nil {
+			defer req.MultipartForm.RemoveAll()
+		}
+
+		files := map[string]*FormFile{}
+		for _, f := range form.GetAllFiles("files") {
+			files[f.Filename] = f
+		}
+		if f := files["small.txt"]; f == nil || string(f.Content) != "hello" {
+			t.Errorf("%s: expected small file in memory, got %+v", name, f)
+		}
+		largeFile := files["big.bin"]
+		if largeFile == nil || largeFile.Content != nil || largeFile.Size != int64(len(big)) {
+			t.Fatalf("%s: expected big file on disk with size %d, got %+v", name, len(big), largeFile)
+		}
+
+		rc, err := largeFile.Open()
+		if err != nil {
+			t.Fatalf("%s: error opening file: %v", name, err)
+		}
+		got, _ := io.ReadAll(rc)
+		rc.Close()
+		if !bytes.Equal(got, big) {
+			t.Errorf("%s: expected %d bytes from Open, got %d", name, len(big), len(got))
+		}
+
+		// SaveFile copia el archivo desde disco
+		form.Files["files"] = []*FormFile{largeFile}
+		path, err := form.SaveFile("files", t.TempDir())
+		if err != nil {
+			t.Fatalf("%s: error saving file: %v", name, err)
+		}
+		if saved, _ := os.ReadFile(path); !bytes.Equal(saved, big) {
+			t.Errorf("%s: expected saved file to match, got %d bytes", name, len(saved))
+		}
+
+		if err := form.RemoveAll(); err != nil {
+			t.Errorf("%s: error removing temp files: %v", name, err)
+		}
+		if largeFile.tempPath != "" {
+			if _, err := os.Stat(largeFile.tempPath); !os.IsNotExist(err) {
+				t.Errorf("%s: expected temp file to be removed, got %v", name, err)
+			}
+		}
+	}
+}
+
+// TestFormBindSlicesAndTime verifica el enlace de campos con varios valores y fechas
+func TestFormBindSlicesAndTime(t *testing.T) {
+	type Signup struct {
+		Interests []string  `form:"interests"`
+		Days      []int     `form:"days"`
+		Birthday  time.Time `form:"dob"`
+		Meeting   time.Time `form:"meeting" timeFormat:"2006-01-02T15:04"`
+		Missing   []string  `form:"missing"`
+	}
+
+	form := &Form{Errors: map[string]string{}, Values: map[string][]string{
+		"interests": {"go", "music"},
+		"days":      {"1", "3", "5"},
+		"dob":       {"1990-04-12"},
+		"meeting":   {"2024-05-01T09:30"},
+	}}
+	var in Signup
+	if err := form.Bind(&in); err != nil {
+		t.Fatalf("Error binding form: %v", err)
+	}
+	if !reflect.DeepEqual(in.Interests, []string{"go", "music"}) || !reflect.DeepEqual(in.Days, []int{1, 3, 5}) {
+		t.Errorf("Expected slices to be bound, got %v and %v", in.Interests, in.Days)
+	}
+	if !in.Birthday.Equal(time.Date(1990, 4, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected birthday 1990-04-12, got %v", in.Birthday)
+	}
+	if !in.Meeting.Equal(time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected meeting 2024-05-01 09:30, got %v", in.Meeting)
+	}
+	if in.Missing != nil {
+		t.Errorf("Expected missing slice to stay nil, got %v", in.Missing)
+	}
+
+	// Un valor inválido en un slice o una fecha devuelve error
+	for key, value := range map[string]string{"days": "x", "dob": "12/04/1990"} {
+		form := &Form{Errors: map[string]string{}, Values: map[string][]string{key: {value}}}
+		if err := form.Bind(&Signup{}); err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("Expected error for %s=%s, got %v", key, value, err)
+		}
+	}
+}
+
+// TestFormSaveAll verifica que SaveAll guarde cada archivo con un nombre saneado y único
+func TestFormSaveAll(t *testing.T) {
+	for name, want := range map[string]string{
+		"photo.jpg":            "photo.jpg",
+		"../../etc/passwd":     "passwd",
+		`C:\Users\ana\cat.png`: "cat.png",
+		".htaccess":            "htaccess",
+		"..":                   "",
+		"bad\x00name\n.txt":    "badname.txt",
+		"dir/":                 "",
+		"  spaced name?.pdf ":  "spaced name.pdf",
+	} {
+		if got := SanitizeFilename(name); got != want {
+			t.Errorf("Expected SanitizeFilename(%q) = %q, got %q", name, want, got)
+		}
+	}
+
+	dir := t.TempDir()
+	form := &Form{Files: map[string][]*FormFile{"photos": {
+		{Filename: "cat.png", Content: []byte("one")},
+		{Filename: "../cat.png", Content: []byte("two")},
+		{Filename: "", Content: []byte("three")},
+	}}}
+
+	paths, err := form.SaveAll("photos", dir)
+	if err != nil {
+		t.Fatalf("Error saving files: %v", err)
+	}
+	if len(paths) != 3 {
+		t.Fatalf("Expected 3 paths, got %v", paths)
+	}
+	if filepath.Base(paths[0]) != "cat.png" || filepath.Base(paths[1]) != "cat_1.png" {
+		t.Errorf("Expected cat.png and cat_1.png, got %v", paths)
+	}
+	for i, want := range []string{"one", "two", "three"} {
+		if filepath.Dir(paths[i]) != dir {
+			t.Errorf("Expected %s inside %s", paths[i], dir)
+		}
+		if got, _ := os.ReadFile(paths[i]); string(got) != want {
+			t.Errorf("Expected '%s' in %s, got '%s'", want, paths[i], got)
+		}
+	}
+
+	// Una segunda subida no sobrescribe las anteriores
+	paths, err = form.SaveAll("photos", dir)
+	if err != nil || filepath.Base(paths[0]) != "cat_2.png" {
+		t.Errorf("Expected cat_2.png on the second upload, got %v (%v)", paths, err)
+	}
+
+	if _, err := form.SaveAll("missing", dir); err == nil {
+		t.Errorf("Expected error for a field without files")
+	}
+}
+
+// TestPostMultipart verifica que TestClient.PostMultipart envíe campos y
+// archivos que NewForm, la validación de archivos y SaveFile procesan
+func TestPostMultipart(t *testing.T) {
+	type Profile struct {
+		Name   string    `form:"name" validate:"required"`
+		Avatar *FormFile `form:"avatar"`
+	}
+
+	dir := t.TempDir()
+	r := New()
+	r.Post("/profile", BindForm(func(w http.ResponseWriter, r *http.Request, p Params, form *Form, in Profile) {
+		form.ValidateFile("avatar", FileValidation{AllowedTypes: []string{"image/png"}, AllowedExtensions: []string{".png"}})
+		if form.HasErrors() {
+			JSON(w, http.StatusUnprocessableEntity, form.GetErrors())
+			return
+		}
+		path, err := form.SaveFile("avatar", dir)
+		if err != nil {
+			Error(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Write([]byte(in.Name + "|" + in.Avatar.Filename + "|" + filepath.Base(path) + "|" + in.Avatar.Header["Content-Type"][0]))
+	}))
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...)
+	client := NewTestClient(r)
+	resp := client.PostMultipart("/profile",
+		map[string]string{"name": "Ana"},
+		map[string]FileUpload{"avatar": {Filename: `my "avatar".png`, Content: png, ContentType: "image/png"}},
+	)
+	if !resp.IsOK() {
+		t.Fatalf("Expected status 200, got %d: %s", resp.StatusCode, resp.Text())
+	}
+	// Las comillas del nombre viajan escapadas y SaveFile las quita al sanear
+	if got := resp.Text(); got != `Ana|my "avatar".png|my avatar.png|image/png` {
+		t.Errorf("Expected bound name, filename, saved file and part type, got '%s'", got)
+	}
+	saved, err := os.ReadFile(filepath.Join(dir, "my avatar.png"))
+	if err != nil || !bytes.Equal(saved, png) {
+		t.Errorf("Expected saved file with the uploaded content, got %v (%v)", saved, err)
+	}
+
+	// Un archivo que no es PNG no pasa la validación
+	resp = client.PostMultipart("/profile",
+		map[string]string{"name": "Ana"},
+		map[string]FileUpload{"avatar": {Filename: "avatar.png", Content: []byte("plain text")}},
+	)
+	if resp.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(resp.Text(), "avatar") {
+		t.Errorf("Expected 422 with an avatar error, got %d: %s", resp.StatusCode, resp.Text())
+	}
+}
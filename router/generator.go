@@ -1,21 +1,41 @@
 package router
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go/format"
+	"go/token"
 	"io"
+	"io/fs"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
+	"unicode"
 )
 
+// DefaultImportPath es la ruta de importación del paquete router que usa
+// el código generado.
+const DefaultImportPath = "github.com/sazardev/mora-router/router"
+
 // RouteGenerator genera código para controladores y rutas.
 type RouteGenerator struct {
 	Router        *MoraRouter
 	TemplatePath  string
 	OutputPath    string
 	ResourcesPath string
+	// ImportPath es la ruta con la que el código generado importa el router.
+	ImportPath string
+	// DryRun hace que Scaffold devuelva los archivos sin escribirlos.
+	DryRun bool
+	// Force permite a Scaffold sobrescribir archivos existentes.
+	Force bool
 }
 
 // NewRouteGenerator crea un nuevo generador de rutas.
@@ -25,7 +45,87 @@ func NewRouteGenerator(r *MoraRouter) *RouteGenerator {
 		TemplatePath:  "templates",
 		OutputPath:    "generated",
 		ResourcesPath: "resources",
+		ImportPath:    DefaultImportPath,
+	}
+}
+
+// ScaffoldFile es un archivo producido por Scaffold.
+type ScaffoldFile struct {
+	Path    string
+	Content string
+}
+
+// Scaffold genera el controlador, el modelo y las pruebas de un recurso y
+// los escribe en disco: el controlador y sus pruebas en
+// OutputPath/controllers y el modelo en ResourcesPath/models. Con DryRun
+// no escribe nada; sin Force falla si algún archivo ya existe.
+func (g *RouteGenerator) Scaffold(name string, fields map[string]string) ([]ScaffoldFile, error) {
+	if name == "" {
+		return nil, errors.New("scaffold: el nombre del recurso es obligatorio")
+	}
+	base := strings.ToLower(name)
+
+	controller, err := g.GenerateController(name)
+	if err != nil {
+		return nil, err
+	}
+	model, err := g.GenerateModel(name, fields)
+	if err != nil {
+		return nil, err
+	}
+	tests, err := g.GenerateTests(name, []string{
+		"GET /" + name,
+		"GET /" + name + "/1",
+		"POST /" + name,
+		"PUT /" + name + "/1",
+		"DELETE /" + name + "/1",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := []ScaffoldFile{
+		{Path: filepath.Join(g.OutputPath, "controllers", base+"_controller.go"), Content: controller},
+		{Path: filepath.Join(g.OutputPath, "controllers", base+"_controller_test.go"), Content: tests},
+		{Path: filepath.Join(g.ResourcesPath, "models", base+".go"), Content: model},
+	}
+	for i, file := range files {
+		formatted, err := format.Source([]byte(file.Content))
+		if err != nil {
+			return nil, fmt.Errorf("scaffold: código generado inválido en %s: %w", file.Path, err)
+		}
+		files[i].Content = string(formatted)
+	}
+
+	if g.DryRun {
+		return files, nil
+	}
+	if !g.Force {
+		for _, file := range files {
+			if _, err := os.Stat(file.Path); err == nil {
+				return nil, fmt.Errorf("scaffold: %s ya existe", file.Path)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+		}
 	}
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(file.Path, []byte(file.Content), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// importPath devuelve la ruta de importación del router para las plantillas.
+func (g *RouteGenerator) importPath() string {
+	if g.ImportPath == "" {
+		return DefaultImportPath
+	}
+	return g.ImportPath
 }
 
 // GenerateController genera código para un controlador.
@@ -35,7 +135,7 @@ func (g *RouteGenerator) GenerateController(name string) (string, error) {
 import (
 	"net/http"
 
-	"mora-router/router"
+	"{{.ImportPath}}"
 )
 
 // {{.Name}}Controller implementa un controlador RESTful para {{.Resource}}.
@@ -78,11 +178,7 @@ func (c {{.Name}}Controller) Update(w http.ResponseWriter, r *http.Request, p ro
 
 // Delete elimina un {{.Resource}} por ID.
 func (c {{.Name}}Controller) Delete(w http.ResponseWriter, r *http.Request, p router.Params) {
-	id := p["id"]
-	router.JSON(w, http.StatusOK, map[string]interface{}{
-		"message": "{{.Resource}} eliminado",
-		"id":      id,
-	})
+	w.WriteHeader(http.StatusNoContent)
 }
 `
 	tpl, err := template.New("controller").Parse(controllerTpl)
@@ -91,11 +187,13 @@ func (c {{.Name}}Controller) Delete(w http.ResponseWriter, r *http.Request, p ro
 	}
 
 	data := struct {
-		Name     string
-		Resource string
+		Name       string
+		Resource   string
+		ImportPath string
 	}{
-		Name:     strings.Title(name),
-		Resource: name,
+		Name:       strings.Title(name),
+		Resource:   name,
+		ImportPath: g.importPath(),
 	}
 
 	var output strings.Builder
@@ -143,6 +241,8 @@ type {{.Name}} struct {
 			JSONName: strings.ToLower(fieldName),
 		})
 	}
+	// Orden estable para que regenerar no produzca diffs
+	slices.SortFunc(fieldsList, func(a, b Field) int { return strings.Compare(a.Name, b.Name) })
 
 	data := struct {
 		Name   string
@@ -162,34 +262,31 @@ type {{.Name}} struct {
 
 // GenerateTests genera código para pruebas de API.
 func (g *RouteGenerator) GenerateTests(name string, endpoints []string) (string, error) {
-	const testTpl = `package tests
+	const testTpl = `package controllers
 
 import (
 	"net/http"
 	"testing"
 
-	"mora-router/router"
+	"{{.ImportPath}}"
 )
 
 func Test{{.Name}}API(t *testing.T) {
 	r := router.New()
 	r.Resource("/{{.Resource}}", {{.Name}}Controller{})
-	
+
 	client := router.NewTestClient(r)
-	
 {{range .Endpoints}}
-	// Test {{.Method}} {{.Path}}
-	resp := client.{{.Method}}("{{.Path}}", nil)
-	if !resp.Is{{.ExpectedStatus}}() {
-		t.Errorf("Expected {{.ExpectedStatusCode}} status, got %d", resp.Status())
-	}
-{{end}}
-}
+	t.Run("{{.Method}} {{.Path}}", func(t *testing.T) {
+		client.{{.Call}}.AssertStatus(t, http.{{.ExpectedStatus}})
+	})
+{{end}}}
 `
 
 	type Endpoint struct {
 		Method             string
 		Path               string
+		Call               string
 		ExpectedStatus     string
 		ExpectedStatusCode int
 	}
@@ -199,7 +296,7 @@ func Test{{.Name}}API(t *testing.T) {
 		parts := strings.Split(endpoint, " ")
 		method := "GET"
 		path := endpoint
-		expectedStatus := "OK"
+		expectedStatus := "StatusOK"
 		expectedStatusCode := 200
 
 		if len(parts) > 1 {
@@ -208,17 +305,27 @@ func Test{{.Name}}API(t *testing.T) {
 
 			switch method {
 			case "POST":
-				expectedStatus = "Created"
+				expectedStatus = "StatusCreated"
 				expectedStatusCode = 201
 			case "DELETE":
-				expectedStatus = "NoContent"
+				expectedStatus = "StatusNoContent"
 				expectedStatusCode = 204
 			}
 		}
 
+		// Get, Delete y Options no llevan cuerpo en TestClient
+		call := strings.Title(strings.ToLower(method)) + fmt.Sprintf("(%q", path)
+		switch method {
+		case "GET", "DELETE", "OPTIONS":
+			call += ")"
+		default:
+			call += ", nil)"
+		}
+
 		endpointsList = append(endpointsList, Endpoint{
 			Method:             method,
 			Path:               path,
+			Call:               call,
 			ExpectedStatus:     expectedStatus,
 			ExpectedStatusCode: expectedStatusCode,
 		})
@@ -230,13 +337,15 @@ func Test{{.Name}}API(t *testing.T) {
 	}
 
 	data := struct {
-		Name      string
-		Resource  string
-		Endpoints []Endpoint
+		Name       string
+		Resource   string
+		ImportPath string
+		Endpoints  []Endpoint
 	}{
-		Name:      strings.Title(name),
-		Resource:  name,
-		Endpoints: endpointsList,
+		Name:       strings.Title(name),
+		Resource:   name,
+		ImportPath: g.importPath(),
+		Endpoints:  endpointsList,
 	}
 
 	var output strings.Builder
@@ -247,6 +356,275 @@ func Test{{.Name}}API(t *testing.T) {
 	return output.String(), nil
 }
 
+// clientHeader es la parte fija del cliente generado por GenerateClient.
+const clientHeader = `// Code generated by mora-router; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"{{if .UsesURL}}
+	"net/url"{{end}}{{if .UsesWildcard}}
+	"strings"{{end}}
+)
+
+// Client llama a las rutas con nombre del servicio.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// Header se añade a todas las peticiones.
+	Header http.Header
+}
+
+// NewClient crea un cliente para el servicio en baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: baseURL, HTTPClient: http.DefaultClient, Header: make(http.Header)}
+}
+
+// Error es la respuesta de una petición con estado 4xx o 5xx.
+type Error struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Body)
+}
+
+// do envía la petición codificando body como JSON y decodifica la respuesta
+// en out si no es nil.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(resp.Body)
+		return &Error{StatusCode: resp.StatusCode, Body: data}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+{{if .UsesWildcard}}
+// escapePath escapa cada segmento de p conservando las barras que los separan.
+func escapePath(p string) string {
+	segs := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segs, "/")
+}
+{{end}}{{range .Methods}}
+// {{.Func}} llama a {{.Method}} {{.Pattern}} (ruta "{{.Name}}").
+func (c *Client) {{.Func}}(ctx context.Context, {{range .Params}}{{.}} string, {{end}}{{if .HasBody}}body, {{end}}out interface{}) error {
+	return c.do(ctx, "{{.Method}}", {{.Path}}, {{if .HasBody}}body{{else}}nil{{end}}, out)
+}
+{{end}}`
+
+// nameMethods asocia el sufijo de los nombres de Resource con su método.
+var nameMethods = map[string]string{
+	"index":  http.MethodGet,
+	"show":   http.MethodGet,
+	"create": http.MethodPost,
+	"update": http.MethodPut,
+	"delete": http.MethodDelete,
+}
+
+// GenerateClient genera un cliente Go con un método por cada ruta con
+// nombre del router. Cada método recibe los parámetros de ruta en orden,
+// un body JSON en POST, PUT y PATCH, y decodifica la respuesta en out. Un
+// comodín *name recibe el resto del path, con sus barras.
+func (g *RouteGenerator) GenerateClient(pkgName string) (string, error) {
+	if g.Router == nil {
+		return "", errors.New("generate client: el generador no tiene router")
+	}
+	if !token.IsIdentifier(pkgName) {
+		return "", fmt.Errorf("generate client: nombre de paquete inválido %q", pkgName)
+	}
+
+	g.Router.mu.RLock()
+	named := maps.Clone(g.Router.namedRoutes)
+	g.Router.mu.RUnlock()
+
+	// Métodos registrados por patrón para deducir el verbo de cada nombre
+	methods := make(map[string][]string)
+	for _, rt := range g.Router.routesSnapshot() {
+		if rt.method != http.MethodHead && rt.method != http.MethodOptions {
+			methods[rt.pattern] = append(methods[rt.pattern], rt.method)
+		}
+	}
+
+	type clientMethod struct {
+		Name, Func, Method, Pattern, Path string
+		Params                            []string
+		HasBody, Wildcard                 bool
+	}
+	var list []clientMethod
+	seen := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(named)) {
+		pattern := named[name]
+		fn := goIdentifier(name, true)
+		if other, ok := seen[fn]; ok {
+			return "", fmt.Errorf("generate client: las rutas %q y %q generan el mismo método %s", other, name, fn)
+		}
+		seen[fn] = name
+
+		// Parámetros en el mismo orden que URL, que es quien construye el path
+		var params, placeholders []string
+		var wildcard string
+		used := map[string]bool{"c": true, "ctx": true, "body": true, "out": true}
+		for _, raw := range splitPath(pattern) {
+			isWildcard := strings.HasPrefix(raw, "*")
+			if !strings.HasPrefix(raw, ":") && !isWildcard {
+				continue
+			}
+			param := goIdentifier(parseSegment(raw).name, false)
+			for used[param] || token.IsKeyword(param) {
+				param += "Param"
+			}
+			used[param] = true
+			if isWildcard {
+				// URL deja el comodín tal cual; siempre es el último segmento
+				wildcard = raw
+				params = append(params, param)
+				break
+			}
+			params = append(params, param)
+			placeholders = append(placeholders, fmt.Sprintf("\x00%d\x00", len(placeholders)))
+		}
+		built, err := g.Router.URL(name, placeholders...)
+		if err != nil {
+			return "", err
+		}
+		built = strings.TrimSuffix(built, wildcard)
+		var parts []string
+		for i, placeholder := range placeholders {
+			before, after, _ := strings.Cut(built, placeholder)
+			if before != "" {
+				parts = append(parts, fmt.Sprintf("%q", before))
+			}
+			parts = append(parts, "url.PathEscape("+params[i]+")")
+			built = after
+		}
+		if built != "" || len(parts) == 0 {
+			parts = append(parts, fmt.Sprintf("%q", built))
+		}
+		if wildcard != "" {
+			parts = append(parts, "escapePath("+params[len(params)-1]+")")
+		}
+
+		method := routeMethod(name, methods[pattern])
+		list = append(list, clientMethod{
+			Name:     name,
+			Func:     fn,
+			Method:   method,
+			Pattern:  pattern,
+			Path:     strings.Join(parts, " + "),
+			Params:   params,
+			HasBody:  method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch,
+			Wildcard: wildcard != "",
+		})
+	}
+
+	tpl, err := template.New("client").Parse(clientHeader)
+	if err != nil {
+		return "", err
+	}
+	var output bytes.Buffer
+	usesURL := slices.ContainsFunc(list, func(m clientMethod) bool { return len(m.Params) > 0 })
+	usesWildcard := slices.ContainsFunc(list, func(m clientMethod) bool { return m.Wildcard })
+	err = tpl.Execute(&output, struct {
+		Package               string
+		UsesURL, UsesWildcard bool
+		Methods               []clientMethod
+	}{pkgName, usesURL, usesWildcard, list})
+	if err != nil {
+		return "", err
+	}
+	formatted, err := format.Source(output.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("generate client: código generado inválido: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// routeMethod elige el método HTTP de una ruta con nombre: el que indica el
+// sufijo de Resource, el único registrado para el patrón, GET si existe, o
+// el primero en orden alfabético.
+func routeMethod(name string, registered []string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		if method, ok := nameMethods[name[i+1:]]; ok && (len(registered) == 0 || slices.Contains(registered, method)) {
+			return method
+		}
+	}
+	switch {
+	case len(registered) == 0:
+		return http.MethodGet
+	case len(registered) == 1, !slices.Contains(registered, http.MethodGet):
+		return slices.Min(registered)
+	default:
+		return http.MethodGet
+	}
+}
+
+// goIdentifier convierte un nombre como "users.show" o "user_id" en un
+// identificador Go, exportado (UsersShow) o no (userID).
+func goIdentifier(name string, exported bool) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for i, word := range words {
+		if strings.EqualFold(word, "id") && i > 0 {
+			b.WriteString("ID")
+			continue
+		}
+		if i == 0 && !exported {
+			b.WriteString(strings.ToLower(word[:1]) + word[1:])
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	id := b.String()
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		if exported {
+			return "Route" + id
+		}
+		return "p" + id
+	}
+	return id
+}
+
 // MockResponseWriter es un ResponseWriter para pruebas.
 type MockResponseWriter struct {
 	headers http.Header
@@ -295,9 +673,9 @@ func NewRouteDebugger(r *MoraRouter) *RouteDebugger {
 // PrintRoutes imprime información sobre todas las rutas registradas.
 func (d *RouteDebugger) PrintRoutes() {
 	fmt.Println("=== MoraRouter Registered Routes ===")
-	fmt.Printf("Total routes: %d\n", len(d.router.routes))
+	fmt.Printf("Total routes: %d\n", len(d.router.routesSnapshot()))
 
-	for i, rt := range d.router.routes {
+	for i, rt := range d.router.routesSnapshot() {
 		fmt.Printf("%d. %s %s\n", i+1, rt.method, rt.pattern)
 
 		fmt.Print("   Parameters: ")
@@ -332,7 +710,7 @@ func (d *RouteDebugger) TraceRoute(method, path string) {
 	fmt.Println("\nMatching routes:")
 	found := false
 
-	for i, rt := range d.router.routes {
+	for i, rt := range d.router.routesSnapshot() {
 		params := make(Params)
 		if matchSegments(rt.segments, pathSegs, params) {
 			fmt.Printf("%d. %s %s\n", i+1, rt.method, rt.pattern)
@@ -0,0 +1,164 @@
+package router
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestScaffold verifica que Scaffold escribe controlador, modelo y pruebas
+func TestScaffold(t *testing.T) {
+	dir := t.TempDir()
+	gen := NewRouteGenerator(New())
+	gen.OutputPath = filepath.Join(dir, "app")
+	gen.ResourcesPath = filepath.Join(dir, "res")
+	gen.ImportPath = "example.com/vendor/router"
+
+	// En modo dry-run no se escribe nada
+	gen.DryRun = true
+	files, err := gen.Scaffold("user", map[string]string{"name": "string", "age": "int"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, got %d", len(files))
+	}
+	if _, err := os.Stat(gen.OutputPath); !os.IsNotExist(err) {
+		t.Errorf("Expected dry run to write nothing, got %v", err)
+	}
+
+	gen.DryRun = false
+	files, err = gen.Scaffold("user", map[string]string{"name": "string", "age": "int"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "app", "controllers", "user_controller.go"),
+		filepath.Join(dir, "app", "controllers", "user_controller_test.go"),
+		filepath.Join(dir, "res", "models", "user.go"),
+	}
+	for i, path := range expected {
+		if files[i].Path != path {
+			t.Errorf("Expected file %s, got %s", path, files[i].Path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Expected %s to be written: %v", path, err)
+		}
+		if string(data) != files[i].Content {
+			t.Errorf("Expected %s on disk to match returned content", path)
+		}
+	}
+
+	controller, _ := os.ReadFile(expected[0])
+	if !strings.Contains(string(controller), `"example.com/vendor/router"`) {
+		t.Errorf("Expected controller to use the configured import path, got:\n%s", controller)
+	}
+	tests, _ := os.ReadFile(expected[1])
+	if !strings.Contains(string(tests), `client.Delete("/user/1").AssertStatus(t, http.StatusNoContent)`) {
+		t.Errorf("Expected generated tests to call TestClient methods, got:\n%s", tests)
+	}
+	model, _ := os.ReadFile(expected[2])
+	if strings.Index(string(model), "Age") > strings.Index(string(model), "Name") {
+		t.Errorf("Expected model fields sorted, got:\n%s", model)
+	}
+
+	// Sin Force no se sobrescriben archivos existentes
+	if _, err := gen.Scaffold("user", nil); err == nil || !strings.Contains(err.Error(), "ya existe") {
+		t.Errorf("Expected error for existing files, got %v", err)
+	}
+	gen.Force = true
+	if _, err := gen.Scaffold("user", nil); err != nil {
+		t.Errorf("Expected Force to overwrite, got %v", err)
+	}
+}
+
+// TestGeneratorImportPath verifica la ruta de importación del código generado
+func TestGeneratorImportPath(t *testing.T) {
+	// Por defecto se usa la ruta real del módulo, también con un generador vacío
+	for _, gen := range []*RouteGenerator{NewRouteGenerator(New()), {}} {
+		controller, _ := gen.GenerateController("post")
+		tests, _ := gen.GenerateTests("post", []string{"GET /post"})
+		for _, code := range []string{controller, tests} {
+			if !strings.Contains(code, `"`+DefaultImportPath+`"`) {
+				t.Errorf("Expected import %q, got:\n%s", DefaultImportPath, code)
+			}
+			if strings.Contains(code, `"mora-router/router"`) {
+				t.Errorf("Expected no legacy import path, got:\n%s", code)
+			}
+		}
+	}
+
+	gen := NewRouteGenerator(New())
+	gen.ImportPath = "example.com/fork/router"
+	controller, _ := gen.GenerateController("post")
+	if !strings.Contains(controller, `"example.com/fork/router"`) {
+		t.Errorf("Expected custom import path, got:\n%s", controller)
+	}
+}
+
+// TestGenerateClient verifica el cliente generado a partir de las rutas con nombre
+func TestGenerateClient(t *testing.T) {
+	r := New()
+	r.Resource("/users", DefaultController{})
+	r.Post("/orgs/:org_id/repos/:name(\\w+)/star", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	r.Name("orgs.star", "/orgs/:org_id/repos/:name(\\w+)/star")
+
+	code, err := NewRouteGenerator(r).GenerateClient("api")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"package api",
+		`"net/url"`,
+		"func (c *Client) UsersIndex(ctx context.Context, out interface{}) error",
+		`c.do(ctx, "GET", "/users/"+url.PathEscape(id), nil, out)`,
+		`c.do(ctx, "PUT", "/users/"+url.PathEscape(id), body, out)`,
+		`c.do(ctx, "DELETE", "/users/"+url.PathEscape(id), nil, out)`,
+		"func (c *Client) OrgsStar(ctx context.Context, orgID string, name string, body, out interface{}) error",
+		`"/orgs/"+url.PathEscape(orgID)+"/repos/"+url.PathEscape(name)+"/star"`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Expected generated client to contain %q, got:\n%s", want, code)
+		}
+	}
+
+	// Un router sin parámetros de ruta no importa net/url
+	r2 := New()
+	r2.Get("/health", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	r2.Name("health", "/health")
+	code, err = NewRouteGenerator(r2).GenerateClient("api")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(code, `"net/url"`) || !strings.Contains(code, "func (c *Client) Health(") {
+		t.Errorf("Expected Health method without net/url import, got:\n%s", code)
+	}
+
+	// Un comodín recibe el resto del path y escapa cada segmento por separado
+	r3 := New()
+	r3.Get("/files/*path", func(w http.ResponseWriter, r *http.Request, p Params) {}).Name("files")
+	code, err = NewRouteGenerator(r3).GenerateClient("api")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`"strings"`,
+		"func escapePath(p string) string",
+		"func (c *Client) Files(ctx context.Context, path string, out interface{}) error",
+		`c.do(ctx, "GET", "/files/"+escapePath(path), nil, out)`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Expected generated client to contain %q, got:\n%s", want, code)
+		}
+	}
+
+	if _, err := NewRouteGenerator(r).GenerateClient("not a package"); err == nil {
+		t.Error("Expected error for invalid package name")
+	}
+}
@@ -1,11 +1,14 @@
 package router
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -19,8 +22,25 @@ type HotReloader struct {
 	lastMod   time.Time
 	callbacks []func()
 	stop      chan struct{}
+	// nombres de la última carga, que se sustituyen en la siguiente
+	names map[string]string
+	// decodificador fijado con SetDecoder; si es nil se elige por extensión
+	decoder ConfigDecoder
+	// watcher de notificaciones del sistema de archivos de StartWatch
+	watcher fileWatcher
 }
 
+// fileWatcher avisa por Events cada vez que cambia el archivo vigilado. El
+// canal se cierra si el watcher deja de funcionar.
+type fileWatcher interface {
+	Events() <-chan struct{}
+	Close() error
+}
+
+// ConfigDecoder decodifica el contenido del archivo de rutas en v, con la
+// misma firma que json.Unmarshal.
+type ConfigDecoder func(data []byte, v any) error
+
 // NewHotReloader crea un nuevo recargador para el router.
 func NewHotReloader(r *MoraRouter, filePath string, interval time.Duration) *HotReloader {
 	if interval == 0 {
@@ -41,9 +61,41 @@ func (hr *HotReloader) Start() {
 	go hr.watchFile()
 }
 
-// Stop detiene el proceso de vigilancia.
+// StartWatch es como Start, pero recarga en cuanto el sistema de archivos
+// notifica un cambio en lugar de esperar al siguiente intervalo. Si las
+// notificaciones no están disponibles en la plataforma, o fallan después,
+// vigila el archivo por intervalos como Start.
+func (hr *HotReloader) StartWatch() {
+	watcher, err := newFileWatcher(hr.filePath)
+	if err != nil {
+		fmt.Printf("[MORA][HotReload] Notificaciones no disponibles, se usará sondeo: %v\n", err)
+		hr.Start()
+		return
+	}
+	hr.mu.Lock()
+	hr.watcher = watcher
+	hr.mu.Unlock()
+	go hr.watchEvents(watcher)
+}
+
+// Stop detiene el proceso de vigilancia y cierra el watcher de StartWatch.
 func (hr *HotReloader) Stop() {
 	close(hr.stop)
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	if hr.watcher != nil {
+		hr.watcher.Close()
+		hr.watcher = nil
+	}
+}
+
+// SetDecoder fija el decodificador del archivo de rutas, por ejemplo el
+// Unmarshal de una librería YAML. Sin él los archivos se leen como JSON y los
+// YAML (.yaml, .yml o sin '{' inicial) no se cargan.
+func (hr *HotReloader) SetDecoder(decoder ConfigDecoder) {
+	hr.mu.Lock()
+	hr.decoder = decoder
+	hr.mu.Unlock()
 }
 
 // OnReload registra una función callback que se ejecutará cuando se detecte un cambio.
@@ -68,6 +120,29 @@ func (hr *HotReloader) watchFile() {
 	}
 }
 
+// watchEvents carga el archivo y lo recarga con cada notificación; si el
+// watcher deja de funcionar, sigue vigilando por intervalos.
+func (hr *HotReloader) watchEvents(watcher fileWatcher) {
+	hr.checkFile()
+	for {
+		select {
+		case _, ok := <-watcher.Events():
+			if !ok {
+				select {
+				case <-hr.stop:
+				default:
+					fmt.Printf("[MORA][HotReload] Notificaciones interrumpidas, se usará sondeo\n")
+					hr.watchFile()
+				}
+				return
+			}
+			hr.checkFile()
+		case <-hr.stop:
+			return
+		}
+	}
+}
+
 // checkFile verifica si el archivo ha cambiado y ejecuta la recarga.
 func (hr *HotReloader) checkFile() {
 	hr.mu.Lock()
@@ -79,8 +154,9 @@ func (hr *HotReloader) checkFile() {
 		return
 	}
 
+	// cualquier cambio de fecha cuenta, también al renombrar encima un archivo más antiguo
 	modTime := fi.ModTime()
-	if !modTime.After(hr.lastMod) {
+	if modTime.Equal(hr.lastMod) {
 		// No ha cambiado
 		return
 	}
@@ -103,21 +179,23 @@ func (hr *HotReloader) checkFile() {
 }
 
 // RouteDefinition define una ruta en el formato JSON/YAML de configuración.
+// Las etiquetas yaml repiten los nombres de las json para los decodificadores
+// de SetDecoder, que suelen ignorar las json.
 type RouteDefinition struct {
-	Method      string            `json:"method"`
-	Pattern     string            `json:"pattern"`
-	HandlerFile string            `json:"handler_file"`
-	HandlerFunc string            `json:"handler_func"`
-	Middleware  []string          `json:"middleware,omitempty"`
-	Name        string            `json:"name,omitempty"`
-	Group       string            `json:"group,omitempty"`
-	Params      map[string]string `json:"params,omitempty"`
+	Method      string            `json:"method" yaml:"method"`
+	Pattern     string            `json:"pattern" yaml:"pattern"`
+	HandlerFile string            `json:"handler_file" yaml:"handler_file"`
+	HandlerFunc string            `json:"handler_func" yaml:"handler_func"`
+	Middleware  []string          `json:"middleware,omitempty" yaml:"middleware,omitempty"`
+	Name        string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Group       string            `json:"group,omitempty" yaml:"group,omitempty"`
+	Params      map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
 }
 
 // RouteCollection es una colección de definiciones de rutas.
 type RouteCollection struct {
-	Routes []RouteDefinition `json:"routes"`
-	Groups map[string]string `json:"groups,omitempty"`
+	Routes []RouteDefinition `json:"routes" yaml:"routes"`
+	Groups map[string]string `json:"groups,omitempty" yaml:"groups,omitempty"`
 }
 
 // loadRoutes carga las rutas desde el archivo de configuración.
@@ -133,19 +211,27 @@ func (hr *HotReloader) loadRoutes() error {
 		return fmt.Errorf("error leyendo archivo: %w", err)
 	}
 
+	decode, err := hr.configDecoder(data)
+	if err != nil {
+		return err
+	}
 	var routes RouteCollection
-	if err := json.Unmarshal(data, &routes); err != nil {
-		return fmt.Errorf("error parseando JSON: %w", err)
+	if err := decode(data, &routes); err != nil {
+		return fmt.Errorf("error parseando configuración: %w", err)
 	}
 
-	// Limpiar rutas anteriores
-	// Nota: Esto requeriría cambios en MoraRouter para permitir remover rutas
-	// hr.router.clearRoutes()
+	// Registrar en un router auxiliar con los mismos middlewares; las rutas
+	// servidas no cambian hasta sustituirlas todas de una vez
+	staging := hr.router.clone()
+	staging.routes = nil
+	staging.namedRoutes = make(map[string]string)
+	staging.routeNames = make(map[string]string)
+	staging.mu = &sync.RWMutex{}
 
 	// Crear grupos
 	groups := make(map[string]*RouteGroup)
 	for name, prefix := range routes.Groups {
-		groups[name] = hr.router.Group(prefix)
+		groups[name] = staging.Group(prefix)
 	}
 
 	// Registrar rutas
@@ -165,7 +251,7 @@ func (hr *HotReloader) loadRoutes() error {
 		if len(route.Middleware) > 0 {
 			mws := make([]Middleware, 0, len(route.Middleware))
 			for _, name := range route.Middleware {
-				if mw, ok := hr.router.middlewareRegistry[name]; ok {
+				if mw, ok := hr.router.namedMiddleware(name); ok {
 					mws = append(mws, mw)
 				}
 			}
@@ -189,18 +275,48 @@ func (hr *HotReloader) loadRoutes() error {
 				}
 			}
 		} else {
-			hr.router.Handle(route.Method, route.Pattern, handler)
+			staging.Handle(route.Method, route.Pattern, handler)
 		}
 
 		// Nombrar ruta si se especifica
 		if route.Name != "" {
-			hr.router.Name(route.Name, route.Pattern)
+			staging.nameRoute(route.Name, route.Pattern, route.Method)
 		}
 	}
 
+	// Sustituir las rutas de la carga anterior por las nuevas; se reconocen
+	// por loadedBy, así que una ruta registrada en código con el mismo
+	// método y patrón se conserva
+	for i := range staging.routes {
+		staging.routes[i].loadedBy = hr
+	}
+	hr.router.replaceRoutes(func(rt route) bool {
+		return rt.loadedBy == hr
+	}, staging.routes, hr.names, staging.namedRoutes, staging.routeNames)
+	hr.names = staging.namedRoutes
+
 	return nil
 }
 
+// configDecoder elige el decodificador: el de SetDecoder o, sin él, JSON. Los
+// archivos .yaml y .yml, y los de otras extensiones que no empiezan por '{',
+// son YAML y necesitan un decodificador fijado con SetDecoder.
+func (hr *HotReloader) configDecoder(data []byte) (ConfigDecoder, error) {
+	if hr.decoder != nil {
+		return hr.decoder, nil
+	}
+	switch strings.ToLower(filepath.Ext(hr.filePath)) {
+	case ".json":
+		return json.Unmarshal, nil
+	case ".yaml", ".yml":
+	default:
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			return json.Unmarshal, nil
+		}
+	}
+	return nil, fmt.Errorf("%s es YAML: fija un decodificador YAML con SetDecoder", hr.filePath)
+}
+
 // ReloadRoutes fuerza una recarga inmediata de las rutas.
 func (hr *HotReloader) ReloadRoutes() error {
 	hr.mu.Lock()
@@ -215,6 +331,15 @@ func CompleteHotReload(router *MoraRouter, filePath string, interval time.Durati
 	return hr
 }
 
+// WithHotReloadWatch habilita la recarga de rutas con notificaciones del
+// sistema de archivos, que aplican los cambios al instante. Donde no hay
+// notificaciones vigila el archivo cada 5 segundos, como WithHotReload.
+func WithHotReloadWatch(filePath string) Option {
+	return func(r *MoraRouter) {
+		NewHotReloader(r, filePath, 0).StartWatch()
+	}
+}
+
 // WithHotReload ahora devuelve el reloader para que pueda ser controlado.
 func WithHotReloadComplete(filePath string, interval time.Duration) Option {
 	return func(r *MoraRouter) {
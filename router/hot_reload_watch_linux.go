@@ -0,0 +1,73 @@
+//go:build linux
+
+package router
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyWatcher vigila con inotify el directorio del archivo, de modo que
+// también detecta los editores que guardan escribiendo otro archivo y
+// renombrándolo encima.
+type inotifyWatcher struct {
+	file   *os.File
+	name   string
+	events chan struct{}
+}
+
+func newFileWatcher(path string) (fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	dir, name := filepath.Split(filepath.Clean(path))
+	if dir == "" {
+		dir = "."
+	}
+	// IN_CLOSE_WRITE evita leer el archivo a medio escribir
+	if _, err := syscall.InotifyAddWatch(fd, dir, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("inotify_add_watch", err)
+	}
+	w := &inotifyWatcher{
+		// al ser no bloqueante, las lecturas pasan por el poller de Go y
+		// Close las desbloquea
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		name:   name,
+		events: make(chan struct{}, 1),
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan struct{} { return w.events }
+
+func (w *inotifyWatcher) Close() error { return w.file.Close() }
+
+func (w *inotifyWatcher) read() {
+	defer close(w.events)
+	buf := make([]byte, 16*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			start := offset + syscall.SizeofInotifyEvent
+			offset = start + int(event.Len)
+			if strings.TrimRight(string(buf[start:offset]), "\x00") != w.name {
+				continue
+			}
+			// varios eventos seguidos se resuelven con una sola recarga
+			select {
+			case w.events <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package router
+
+import "errors"
+
+func newFileWatcher(path string) (fileWatcher, error) {
+	return nil, errors.New("notificaciones del sistema de archivos no soportadas en esta plataforma")
+}
@@ -0,0 +1,175 @@
+package router
+
+import (
+	"bytes"
+	"math"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fastAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type fastStatus int
+
+// fastPtrStatus solo tiene MarshalJSON con receptor puntero, que encoding/json
+// usa únicamente con valores direccionables
+type fastPtrStatus int
+
+func (s *fastPtrStatus) MarshalJSON() ([]byte, error) {
+	return []byte(`"ptr"`), nil
+}
+
+func (s fastStatus) MarshalJSON() ([]byte, error) {
+	return []byte(`"status-` + string(rune('0'+s)) + `"`), nil
+}
+
+// fastUser es un struct de tamaño medio con los casos que JSONFast debe reproducir
+type fastUser struct {
+	ID      int64             `json:"id"`
+	Name    string            `json:"name"`
+	Email   string            `json:"email,omitempty"`
+	Bio     string            `json:"bio"`
+	Age     uint8             `json:"age"`
+	Score   float64           `json:"score"`
+	Ratio   float32           `json:"ratio"`
+	Tiny    float64           `json:"tiny"`
+	Active  bool              `json:"active"`
+	Admin   bool              `json:"admin,omitempty"`
+	Status  fastStatus        `json:"status"`
+	Created time.Time         `json:"created"`
+	Tags    []string          `json:"tags"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Address fastAddress       `json:"address"`
+	Manager *fastAddress      `json:"manager"`
+	Extra   interface{}       `json:"extra,omitempty"`
+	Ignored string            `json:"-"`
+	Dash    string            `json:"-,"`
+	NoTag   int
+	private string
+}
+
+type ptrFastAddress struct {
+	Status fastPtrStatus `json:"status"`
+}
+
+func sampleFastUser() fastUser {
+	return fastUser{
+		ID:      42,
+		Name:    "Ana <admin> & \"co\"\n\t\b\f\x01 ünïcode \u2028\u2029",
+		Bio:     "Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+		Age:     37,
+		Score:   1234.5678,
+		Ratio:   0.1,
+		Tiny:    1e-7,
+		Active:  true,
+		Status:  3,
+		Created: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC),
+		Tags:    []string{"go", "json", "<fast>"},
+		Meta:    map[string]string{"b": "2", "a": "1"},
+		Address: fastAddress{Street: "Main St 1", City: "Madrid"},
+		Dash:    "dash",
+		NoTag:   7,
+		private: "hidden",
+	}
+}
+
+// TestJSONFastMatchesJSON verifica que JSONFast produzca exactamente la misma salida que JSON
+func TestJSONFastMatchesJSON(t *testing.T) {
+	RegisterJSONType[fastUser]()
+	if _, ok := jsonEncoders.Load(reflect.TypeOf(fastUser{})); !ok {
+		t.Fatalf("Expected fastUser to be registered")
+	}
+
+	empty := fastUser{}
+	large := sampleFastUser()
+	large.Score, large.Tiny = 1e21, -2.5e-10
+	for _, v := range []fastUser{sampleFastUser(), empty, large} {
+		want, got := httptest.NewRecorder(), httptest.NewRecorder()
+		JSON(want, 201, v)
+		JSONFast(got, 201, v)
+		if !bytes.Equal(got.Body.Bytes(), want.Body.Bytes()) {
+			t.Errorf("Expected output:\n%s\ngot:\n%s", want.Body, got.Body)
+		}
+		if got.Code != 201 || got.Header().Get("Content-Type") != want.Header().Get("Content-Type") {
+			t.Errorf("Expected status 201 and '%s', got %d '%s'", want.Header().Get("Content-Type"), got.Code, got.Header().Get("Content-Type"))
+		}
+	}
+
+	// NaN, el UTF-8 inválido y un año fuera de rango se comportan igual que JSON
+	nan, invalid, year := sampleFastUser(), sampleFastUser(), sampleFastUser()
+	nan.Score = math.NaN()
+	invalid.Name = "bad \xff"
+	year.Created = time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, v := range []fastUser{nan, invalid, year} {
+		want, got := httptest.NewRecorder(), httptest.NewRecorder()
+		JSON(want, 200, v)
+		JSONFast(got, 200, v)
+		if !bytes.Equal(got.Body.Bytes(), want.Body.Bytes()) {
+			t.Errorf("Expected '%s', got '%s'", want.Body, got.Body)
+		}
+	}
+
+	// Los MarshalJSON con receptor puntero se usan solo tras un puntero o en un
+	// slice, no en los valores de un map
+	type ptrMarshalers struct {
+		Direct  fastPtrStatus            `json:"direct"`
+		Pointer *fastPtrStatus           `json:"pointer"`
+		List    []fastPtrStatus          `json:"list"`
+		Nested  *ptrFastAddress          `json:"nested"`
+		Map     map[string]fastPtrStatus `json:"map"`
+	}
+	RegisterJSONType[ptrMarshalers]()
+	status := fastPtrStatus(1)
+	pm := ptrMarshalers{Direct: 1, Pointer: &status, List: []fastPtrStatus{2}, Nested: &ptrFastAddress{Status: 3}, Map: map[string]fastPtrStatus{"<b>": 4, "a": 5}}
+	want, got := httptest.NewRecorder(), httptest.NewRecorder()
+	JSON(want, 200, pm)
+	JSONFast(got, 200, pm)
+	if !bytes.Equal(got.Body.Bytes(), want.Body.Bytes()) {
+		t.Errorf("Expected '%s' for pointer receivers, got '%s'", want.Body, got.Body)
+	}
+
+	// Los tipos no registrados y los que tienen campos embebidos también coinciden
+	type embedded struct {
+		fastAddress
+		Zip    string `json:"zip"`
+		BadTag string `json:"a\\b"`
+	}
+	for _, register := range []bool{false, true} {
+		if register {
+			RegisterJSONType[embedded]()
+		}
+		v := embedded{fastAddress{"A", "B"}, "28001", "bad"}
+		want, got := httptest.NewRecorder(), httptest.NewRecorder()
+		JSON(want, 200, v)
+		JSONFast(got, 200, v)
+		if !bytes.Equal(got.Body.Bytes(), want.Body.Bytes()) {
+			t.Errorf("Expected '%s' for an unsupported type, got '%s'", want.Body, got.Body)
+		}
+	}
+}
+
+func BenchmarkJSON(b *testing.B) {
+	v := sampleFastUser()
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Body.Reset()
+		JSON(w, 200, v)
+	}
+}
+
+func BenchmarkJSONFast(b *testing.B) {
+	RegisterJSONType[fastUser]()
+	v := sampleFastUser()
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Body.Reset()
+		JSONFast(w, 200, v)
+	}
+}
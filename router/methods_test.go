@@ -2,6 +2,7 @@ package router
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -78,6 +79,38 @@ func TestWildcardRoutes(t *testing.T) {
 	}
 }
 
+// TestWildcardOptions verifica la cabecera Allow de OPTIONS sobre rutas comodín
+func TestWildcardOptions(t *testing.T) {
+	r := New()
+	handler := func(w http.ResponseWriter, r *http.Request, p Params) {}
+	r.Get("/files/*path", handler)
+	r.Put("/files/*path", handler)
+	r.Get("/files/:id", handler)
+	r.Delete("/files/:id", handler)
+
+	tests := map[string]string{
+		"/files/docs/report.pdf": "GET,PUT",
+		"/files":                 "GET,PUT",
+		// el comodín y el parámetro coinciden, sin repetir GET
+		"/files/report.pdf": "GET,PUT,DELETE",
+	}
+	for path, want := range tests {
+		resp := NewTestClient(r).exec(httptest.NewRequest(http.MethodOptions, path, nil))
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("Expected status 204 for OPTIONS %s, got %d", path, resp.StatusCode)
+		}
+		if allow := resp.Header.Get("Allow"); allow != want {
+			t.Errorf("Expected Allow '%s' for %s, got '%s'", want, path, allow)
+		}
+	}
+
+	// El 405 usa la misma lista
+	resp := NewTestClient(r).Post("/files/report.pdf", nil)
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET,PUT,DELETE" {
+		t.Errorf("Expected 405 with Allow 'GET,PUT,DELETE', got %d '%s'", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+}
+
 // TestRouteGroups verifica el manejo de grupos de rutas
 func TestRouteGroups(t *testing.T) {
 	r := New()
@@ -187,6 +220,64 @@ func TestMiddlewareOrder(t *testing.T) {
 	}
 }
 
+// TestGroupMiddlewareIsolation verifica que los middlewares de un grupo solo
+// envuelvan sus rutas, que los globales añadidos después de crear el grupo no
+// le afecten y que sus rutas se sirvan desde el router principal
+func TestGroupMiddlewareIsolation(t *testing.T) {
+	r := New()
+	var trace []string
+	mark := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request, p Params) {
+				trace = append(trace, name)
+				next(w, r, p)
+			}
+		}
+	}
+	ok := func(w http.ResponseWriter, r *http.Request, p Params) { w.Write([]byte("ok")) }
+
+	a := r.Group("/a")
+	a.Use(mark("A"))
+	b := r.Group("/b").Use(mark("B"))
+	r.Use(mark("global")) // añadido después de crear los grupos
+	a.Get("/x", ok)
+	b.Get("/x", ok)
+	r.Get("/plain", ok)
+
+	// Un subgrupo hereda los middlewares del padre sin modificarlo
+	nested := a.Group("/n").Use(mark("N"))
+	nested.Get("/x", ok)
+	a.Get("/y", ok)
+
+	// With solo afecta a la ruta que registra
+	a.With(mark("once")).Get("/z", ok)
+	a.Get("/w", ok)
+
+	// Un grupo creado después de Use sí usa el middleware global
+	r.Group("/c").Use(mark("C")).Get("/x", ok)
+
+	want := map[string]string{
+		"/a/x":   "A",
+		"/b/x":   "B",
+		"/plain": "global",
+		"/a/n/x": "A,N",
+		"/a/y":   "A",
+		"/a/z":   "A,once",
+		"/a/w":   "A",
+		"/c/x":   "global,C",
+	}
+	client := NewTestClient(r)
+	for path, expected := range want {
+		trace = nil
+		if resp := client.Get(path); resp.Text() != "ok" {
+			t.Errorf("Expected 'ok' for %s from the main router, got %d '%s'", path, resp.StatusCode, resp.Text())
+		}
+		if got := strings.Join(trace, ","); got != expected {
+			t.Errorf("Expected middlewares '%s' for %s, got '%s'", expected, path, got)
+		}
+	}
+}
+
 // TestContentNegotiation verifica la negociación de contenido básica
 func TestContentNegotiation(t *testing.T) {
 	r := New()
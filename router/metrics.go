@@ -0,0 +1,454 @@
+package router
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets son los límites superiores de los buckets del histograma de
+// latencias por ruta, los mismos que usa Prometheus por defecto.
+var latencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// latencyHistogram cuenta las latencias de una ruta por bucket; el último
+// elemento de counts es el bucket +Inf.
+type latencyHistogram struct {
+	method  string
+	pattern string
+	counts  []uint64
+	count   uint64
+	sum     time.Duration
+}
+
+// MetricsCollector guarda las métricas de un router: totales, peticiones en
+// curso y rechazadas, contadores e histogramas por ruta, y las métricas
+// propias creadas con Counter y Gauge. Cada router tiene el suyo, compartido
+// con sus grupos y clones.
+type MetricsCollector struct {
+	mu            sync.Mutex
+	totalRequests uint64
+	totalLatency  time.Duration
+	histograms    map[string]*latencyHistogram
+	counters      map[routeStatusKey]uint64
+	custom        map[string]*Metric
+
+	// peticiones en curso y rechazadas por WithMaxConcurrentRequests
+	inFlight atomic.Int64
+	rejected atomic.Int64
+}
+
+func newMetricsCollector() *MetricsCollector {
+	return &MetricsCollector{
+		histograms: make(map[string]*latencyHistogram),
+		counters:   make(map[routeStatusKey]uint64),
+		custom:     make(map[string]*Metric),
+	}
+}
+
+// Metrics devuelve el colector de métricas del router, para registrar
+// métricas propias que se publican en /metrics junto a las de WithMetrics.
+func (r *MoraRouter) Metrics() *MetricsCollector {
+	return r.metrics
+}
+
+// ResetMetrics pone a cero todas las métricas del router. Pensado para tests.
+func (r *MoraRouter) ResetMetrics() {
+	r.metrics.Reset()
+}
+
+// Reset pone a cero todas las métricas. Las métricas propias siguen
+// registradas, sin valores.
+func (m *MetricsCollector) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.totalRequests = 0
+	m.totalLatency = 0
+	clear(m.histograms)
+	clear(m.counters)
+	for _, metric := range m.custom {
+		metric.mu.Lock()
+		clear(metric.values)
+		metric.mu.Unlock()
+	}
+	m.rejected.Store(0)
+}
+
+// middleware mide cada petición: latencia, código de estado y peticiones en curso.
+func (m *MetricsCollector) middleware(next HandlerFunc) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, p Params) {
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next(sw, r, p)
+		dur := time.Since(start)
+		pattern, _ := r.Context().Value(patternKey).(string)
+		m.observe(r.Method, pattern, sw.status, dur)
+	}
+}
+
+// observe registra una petición en los totales y, si se conoce la ruta, en
+// su histograma y su contador.
+func (m *MetricsCollector) observe(method, pattern string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.totalRequests++
+	m.totalLatency += d
+	if pattern == "" {
+		return
+	}
+	m.observeRouteLatency(method, pattern, d)
+	m.counters[routeStatusKey{method, pattern, status}]++
+}
+
+// observeRouteLatency registra la duración de una petición a la ruta. Debe
+// llamarse con m.mu tomado.
+func (m *MetricsCollector) observeRouteLatency(method, pattern string, d time.Duration) {
+	key := method + " " + pattern
+	h := m.histograms[key]
+	if h == nil {
+		h = &latencyHistogram{method: method, pattern: pattern, counts: make([]uint64, len(latencyBuckets)+1)}
+		m.histograms[key] = h
+	}
+	i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+	h.counts[i]++
+	h.count++
+	h.sum += d
+}
+
+// ServeHTTP publica las métricas en el formato de texto de Prometheus.
+func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
+
+// WritePrometheus escribe todas las métricas en el formato de texto de Prometheus.
+func (m *MetricsCollector) WritePrometheus(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	avg := time.Duration(0)
+	if m.totalRequests > 0 {
+		avg = m.totalLatency / time.Duration(m.totalRequests)
+	}
+	fmt.Fprintf(w, "# HELP http_handler_latency_seconds_average average latency in seconds\n")
+	fmt.Fprintf(w, "# TYPE http_handler_latency_seconds_average gauge\n")
+	fmt.Fprintf(w, "http_handler_latency_seconds_average %f\n", avg.Seconds())
+	fmt.Fprintf(w, "# HELP http_handler_requests_total total handled requests\n")
+	fmt.Fprintf(w, "# TYPE http_handler_requests_total counter\n")
+	fmt.Fprintf(w, "http_handler_requests_total %d\n", m.totalRequests)
+	fmt.Fprintf(w, "# HELP http_requests_in_flight requests currently being handled\n")
+	fmt.Fprintf(w, "# TYPE http_requests_in_flight gauge\n")
+	fmt.Fprintf(w, "http_requests_in_flight %d\n", m.inFlight.Load())
+	fmt.Fprintf(w, "# HELP http_requests_rejected_total requests rejected by the concurrency limit\n")
+	fmt.Fprintf(w, "# TYPE http_requests_rejected_total counter\n")
+	fmt.Fprintf(w, "http_requests_rejected_total %d\n", m.rejected.Load())
+	m.writeRouteCounters(w)
+	m.writeRouteHistograms(w)
+	m.writeCustom(w)
+}
+
+// routeStatusKey identifica un contador de http_requests_total.
+type routeStatusKey struct {
+	method  string
+	pattern string
+	status  int
+}
+
+// writeRouteCounters escribe los contadores por ruta, método y estado en
+// formato Prometheus. Debe llamarse con m.mu tomado.
+func (m *MetricsCollector) writeRouteCounters(w io.Writer) {
+	keys := make([]routeStatusKey, 0, len(m.counters))
+	for key := range m.counters {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.pattern != b.pattern {
+			return a.pattern < b.pattern
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+
+	fmt.Fprintf(w, "# HELP http_requests_total requests per route, method and status\n")
+	fmt.Fprintf(w, "# TYPE http_requests_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(w, "http_requests_total{method=\"%s\",route=\"%s\",status=\"%d\"} %d\n",
+			escapeLabel(key.method), escapeLabel(key.pattern), key.status, m.counters[key])
+	}
+}
+
+// Metric es una métrica propia, contador o gauge, con una serie por cada
+// combinación de etiquetas.
+type Metric struct {
+	name   string
+	help   string
+	kind   string
+	mu     sync.Mutex
+	values map[string]float64 // etiquetas ya formateadas -> valor
+}
+
+// Counter devuelve el contador name, creándolo si no existe. Por convención
+// de Prometheus su nombre debería acabar en _total.
+func (m *MetricsCollector) Counter(name, help string) *Metric {
+	return m.metric(name, help, "counter")
+}
+
+// Gauge devuelve el gauge name, creándolo si no existe.
+func (m *MetricsCollector) Gauge(name, help string) *Metric {
+	return m.metric(name, help, "gauge")
+}
+
+func (m *MetricsCollector) metric(name, help, kind string) *Metric {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if metric, ok := m.custom[name]; ok {
+		return metric
+	}
+	metric := &Metric{name: name, help: help, kind: kind, values: make(map[string]float64)}
+	m.custom[name] = metric
+	return metric
+}
+
+// Inc suma 1 a la serie con las etiquetas dadas como pares clave, valor.
+func (mt *Metric) Inc(labels ...string) {
+	mt.Add(1, labels...)
+}
+
+// Add suma delta a la serie con las etiquetas dadas como pares clave, valor.
+func (mt *Metric) Add(delta float64, labels ...string) {
+	key := formatLabels(labels)
+	mt.mu.Lock()
+	mt.values[key] += delta
+	mt.mu.Unlock()
+}
+
+// Set fija el valor de la serie con las etiquetas dadas; pensado para gauges.
+func (mt *Metric) Set(value float64, labels ...string) {
+	key := formatLabels(labels)
+	mt.mu.Lock()
+	mt.values[key] = value
+	mt.mu.Unlock()
+}
+
+// formatLabels convierte pares clave, valor en `k="v",...`; una clave sin
+// valor se descarta.
+func formatLabels(labels []string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(labels); i += 2 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, labels[i], escapeLabel(labels[i+1]))
+	}
+	return b.String()
+}
+
+// writeCustom escribe las métricas propias ordenadas por nombre y etiquetas.
+// Debe llamarse con m.mu tomado.
+func (m *MetricsCollector) writeCustom(w io.Writer) {
+	names := make([]string, 0, len(m.custom))
+	for name := range m.custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		metric := m.custom[name]
+		metric.mu.Lock()
+		fmt.Fprintf(w, "# HELP %s %s\n", name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, metric.kind)
+		keys := make([]string, 0, len(metric.values))
+		for key := range metric.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := strconv.FormatFloat(metric.values[key], 'g', -1, 64)
+			if key == "" {
+				fmt.Fprintf(w, "%s %s\n", name, value)
+			} else {
+				fmt.Fprintf(w, "%s{%s} %s\n", name, key, value)
+			}
+		}
+		metric.mu.Unlock()
+	}
+}
+
+// labelEscaper escapa los valores de etiqueta según el formato de exposición
+// de Prometheus, que solo admite \\, \" y \n.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+// statusWriter guarda el código de estado de la respuesta sin almacenar el
+// cuerpo. Conserva Flush y Hijack para no romper streaming ni WebSocket.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		// Flush envía las cabeceras con un 200 implícito si aún no se habían enviado
+		if !w.wroteHeader {
+			w.status = http.StatusOK
+			w.wroteHeader = true
+		}
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		// una conexión secuestrada (WebSocket) cuenta como 101 y ya no
+		// admite más escrituras
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("hijack no soportado")
+}
+
+// Unwrap permite a http.ResponseController llegar al ResponseWriter original.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// quantile estima el percentil q (entre 0 y 1) interpolando dentro del bucket
+// que lo contiene, como histogram_quantile de Prometheus. Si cae en el bucket
+// +Inf devuelve el último límite conocido.
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := q * float64(h.count)
+	var cumulative uint64
+	for i, n := range h.counts {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		if i == len(latencyBuckets) {
+			return latencyBuckets[len(latencyBuckets)-1]
+		}
+		lower := time.Duration(0)
+		if i > 0 {
+			lower = latencyBuckets[i-1]
+		}
+		fraction := (rank - float64(cumulative)) / float64(n)
+		return lower + time.Duration(fraction*float64(latencyBuckets[i]-lower))
+	}
+	return latencyBuckets[len(latencyBuckets)-1]
+}
+
+// sortedHistograms devuelve los histogramas ordenados por ruta y método.
+// Debe llamarse con m.mu tomado.
+func (m *MetricsCollector) sortedHistograms() []*latencyHistogram {
+	hists := make([]*latencyHistogram, 0, len(m.histograms))
+	for _, h := range m.histograms {
+		hists = append(hists, h)
+	}
+	sort.Slice(hists, func(i, j int) bool {
+		if hists[i].pattern == hists[j].pattern {
+			return hists[i].method < hists[j].method
+		}
+		return hists[i].pattern < hists[j].pattern
+	})
+	return hists
+}
+
+// writeRouteHistograms escribe los histogramas por ruta en formato Prometheus.
+// Debe llamarse con m.mu tomado.
+func (m *MetricsCollector) writeRouteHistograms(w io.Writer) {
+	fmt.Fprintf(w, "# HELP http_request_duration_seconds request latency per route\n")
+	fmt.Fprintf(w, "# TYPE http_request_duration_seconds histogram\n")
+	for _, h := range m.sortedHistograms() {
+		labels := fmt.Sprintf(`method="%s",route="%s"`, escapeLabel(h.method), escapeLabel(h.pattern))
+		var cumulative uint64
+		for i, n := range h.counts {
+			cumulative += n
+			le := "+Inf"
+			if i < len(latencyBuckets) {
+				le = strconv.FormatFloat(latencyBuckets[i].Seconds(), 'g', -1, 64)
+			}
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=%q} %d\n", labels, le, cumulative)
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{%s} %f\n", labels, h.sum.Seconds())
+		fmt.Fprintf(w, "http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+}
+
+// RouteTiming resume las latencias de una ruta en milisegundos.
+type RouteTiming struct {
+	Method  string  `json:"method"`
+	Pattern string  `json:"pattern"`
+	Count   uint64  `json:"count"`
+	P50     float64 `json:"p50_ms"`
+	P90     float64 `json:"p90_ms"`
+	P99     float64 `json:"p99_ms"`
+}
+
+// timingsHandler devuelve los percentiles p50/p90/p99 de cada ruta calculados
+// a partir de los histogramas de WithMetrics.
+func (m *MetricsCollector) timingsHandler(w http.ResponseWriter, req *http.Request, p Params) {
+	m.mu.Lock()
+	hists := m.sortedHistograms()
+	timings := make([]RouteTiming, 0, len(hists))
+	for _, h := range hists {
+		timings = append(timings, RouteTiming{
+			Method:  h.method,
+			Pattern: h.pattern,
+			Count:   h.count,
+			P50:     milliseconds(h.quantile(0.50)),
+			P90:     milliseconds(h.quantile(0.90)),
+			P99:     milliseconds(h.quantile(0.99)),
+		})
+	}
+	m.mu.Unlock()
+
+	JSON(w, http.StatusOK, timings)
+}
+
+// milliseconds convierte una duración a milisegundos con decimales.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,181 @@
+package router
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRouteTimings verifica los percentiles por ruta de /_mora/timings
+func TestRouteTimings(t *testing.T) {
+	r := New(WithMetrics(), WithDebugAuth("admin", "s3cret"))
+	r.Get("/timings-test/:id", func(w http.ResponseWriter, r *http.Request, p Params) {})
+
+	// La petición real queda registrada con el patrón de la ruta
+	if resp := NewTestClient(r).Get("/timings-test/1"); !resp.IsOK() {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	// Latencias conocidas: 50 en (0,5ms], 40 en (25ms,50ms] y 10 en (500ms,1s]
+	for i := 0; i < 49; i++ {
+		r.Metrics().observe("GET", "/timings-test/:id", http.StatusOK, 3*time.Millisecond)
+	}
+	for i := 0; i < 40; i++ {
+		r.Metrics().observe("GET", "/timings-test/:id", http.StatusOK, 30*time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		r.Metrics().observe("GET", "/timings-test/:id", http.StatusOK, 700*time.Millisecond)
+	}
+
+	// Sin credenciales el endpoint está protegido
+	if resp := NewTestClient(r).Get("/_mora/timings"); !resp.IsUnauthorized() {
+		t.Fatalf("Expected status 401 without debug auth, got %d", resp.StatusCode)
+	}
+
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:s3cret"))
+	resp := NewTestClient(r).WithHeader("Authorization", auth).Get("/_mora/timings")
+	if !resp.IsOK() {
+		t.Fatalf("Expected status 200 with debug auth, got %d", resp.StatusCode)
+	}
+	var timings []RouteTiming
+	if err := resp.DecodeJSON(&timings); err != nil {
+		t.Fatalf("Error decoding timings: %v", err)
+	}
+
+	var timing *RouteTiming
+	for i := range timings {
+		if timings[i].Method == "GET" && timings[i].Pattern == "/timings-test/:id" {
+			timing = &timings[i]
+		}
+	}
+	if timing == nil {
+		t.Fatalf("Expected timings for /timings-test/:id, got %+v", timings)
+	}
+	if timing.Count != 100 {
+		t.Errorf("Expected 100 observations, got %d", timing.Count)
+	}
+	if timing.P50 <= 0 || timing.P50 > 5 {
+		t.Errorf("Expected p50 in (0,5ms], got %v", timing.P50)
+	}
+	if timing.P90 <= 25 || timing.P90 > 50 {
+		t.Errorf("Expected p90 in (25ms,50ms], got %v", timing.P90)
+	}
+	if timing.P99 <= 500 || timing.P99 > 1000 {
+		t.Errorf("Expected p99 in (500ms,1s], got %v", timing.P99)
+	}
+
+	// El histograma también se expone en /metrics
+	metrics := NewTestClient(r).Get("/metrics").Text()
+	if !strings.Contains(metrics, `http_request_duration_seconds_bucket{method="GET",route="/timings-test/:id",le="0.005"} 50`) {
+		t.Errorf("Expected route histogram in /metrics, got:\n%s", metrics)
+	}
+}
+
+// TestPrometheusMetrics verifica los contadores por ruta, método y estado y el
+// formato de exposición de /metrics
+func TestPrometheusMetrics(t *testing.T) {
+	r := New(WithMetrics())
+	r.Get("/prom-test/:id", func(w http.ResponseWriter, r *http.Request, p Params) {
+		if p["id"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	r.Post("/prom-test/:id", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	client := NewTestClient(r)
+	client.Get("/prom-test/1")
+	client.Get("/prom-test/2")
+	client.Get("/prom-test/missing")
+	client.Post("/prom-test/3", nil)
+
+	resp := client.Get("/metrics")
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected Prometheus text Content-Type, got '%s'", ct)
+	}
+	metrics := resp.Text()
+	for _, line := range []string{
+		"# TYPE http_requests_total counter",
+		`http_requests_total{method="GET",route="/prom-test/:id",status="200"} 2`,
+		`http_requests_total{method="GET",route="/prom-test/:id",status="404"} 1`,
+		`http_requests_total{method="POST",route="/prom-test/:id",status="201"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+		`http_request_duration_seconds_bucket{method="GET",route="/prom-test/:id",le="+Inf"} 3`,
+		`http_request_duration_seconds_count{method="GET",route="/prom-test/:id"} 3`,
+		"# TYPE http_handler_requests_total counter",
+		"# TYPE http_requests_in_flight gauge",
+	} {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("Expected line %q in /metrics, got:\n%s", line, metrics)
+		}
+	}
+	if !strings.Contains(metrics, `http_request_duration_seconds_sum{method="GET",route="/prom-test/:id"} `) {
+		t.Errorf("Expected histogram sum in /metrics, got:\n%s", metrics)
+	}
+
+	// Los valores de etiqueta se escapan como exige Prometheus, sin \u
+	if got := escapeLabel("a\"b\\c\nñ"); got != `a\"b\\c\nñ` {
+		t.Errorf("Expected escaped label, got %s", got)
+	}
+}
+
+// TestMetricsPerRouter verifica que cada router tenga sus propias métricas,
+// que ResetMetrics las ponga a cero y que las métricas propias se publiquen
+func TestMetricsPerRouter(t *testing.T) {
+	first := New(WithMetrics())
+	second := New(WithMetrics())
+	handler := func(w http.ResponseWriter, r *http.Request, p Params) {}
+	first.Get("/shared", handler)
+	second.Get("/shared", handler)
+	// Las rutas de los grupos cuentan en el colector de su router
+	first.Group("/api").Get("/grouped", handler)
+
+	NewTestClient(first).Get("/shared")
+	NewTestClient(first).Get("/api/grouped")
+
+	series := `http_requests_total{method="GET",route="/shared",status="200"} 1`
+	if metrics := NewTestClient(first).Get("/metrics").Text(); !strings.Contains(metrics, series) ||
+		!strings.Contains(metrics, `route="/api/grouped"`) {
+		t.Errorf("Expected first router metrics to count its requests, got:\n%s", metrics)
+	}
+	if metrics := NewTestClient(second).Get("/metrics").Text(); strings.Contains(metrics, `route="/shared"`) {
+		t.Errorf("Expected second router metrics to be independent, got:\n%s", metrics)
+	}
+
+	jobs := first.Metrics().Counter("jobs_processed_total", "processed background jobs")
+	jobs.Inc("queue", "emails")
+	jobs.Add(2, "queue", "emails")
+	jobs.Inc("queue", `re"ports`)
+	first.Metrics().Gauge("queue_depth", "pending jobs").Set(7)
+	if first.Metrics().Counter("jobs_processed_total", "") != jobs {
+		t.Error("Expected Counter to return the registered metric")
+	}
+
+	metrics := NewTestClient(first).Get("/metrics").Text()
+	for _, line := range []string{
+		"# HELP jobs_processed_total processed background jobs",
+		"# TYPE jobs_processed_total counter",
+		`jobs_processed_total{queue="emails"} 3`,
+		`jobs_processed_total{queue="re\"ports"} 1`,
+		"# TYPE queue_depth gauge",
+		"queue_depth 7",
+	} {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("Expected line %q in /metrics, got:\n%s", line, metrics)
+		}
+	}
+
+	first.ResetMetrics()
+	metrics = NewTestClient(first).Get("/metrics").Text()
+	if strings.Contains(metrics, `route="/shared"`) || strings.Contains(metrics, `queue="emails"`) {
+		t.Errorf("Expected metrics to be reset, got:\n%s", metrics)
+	}
+	if !strings.Contains(metrics, "# TYPE jobs_processed_total counter") {
+		t.Errorf("Expected custom metric to stay registered after reset, got:\n%s", metrics)
+	}
+}
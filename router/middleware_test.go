@@ -1,7 +1,19 @@
 package router
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -26,6 +38,121 @@ func TestRecoveryMiddleware(t *testing.T) {
 	}
 }
 
+// TestRecoveryOutermost verifica que el recovery capture panics de middlewares registrados en cualquier orden
+func TestRecoveryOutermost(t *testing.T) {
+	panicky := func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			panic("middleware panic")
+		}
+	}
+	handler := func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte("unreachable"))
+	}
+
+	// Con WithRecovery los middlewares registrados después quedan dentro del recovery
+	r := New(WithRecovery())
+	r.Use(panicky)
+	r.Get("/after", handler)
+	if resp := NewTestClient(r).Get("/after"); !resp.IsServerError() {
+		t.Errorf("Expected server error for a middleware added after recovery, got %d", resp.StatusCode)
+	}
+
+	// WithRecoveryOutermost envuelve también a los registrados antes
+	r = New(func(r *MoraRouter) { r.Use(panicky) }, WithRecoveryOutermost())
+	r.Get("/before", handler)
+	r.Use(panicky)
+	r.Get("/both", handler)
+	for _, path := range []string{"/before", "/both"} {
+		if resp := NewTestClient(r).Get(path); !resp.IsServerError() {
+			t.Errorf("Expected server error for %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+// TestRecoveryJSON verifica la respuesta JSON para APIs y el hook OnPanic
+func TestRecoveryJSON(t *testing.T) {
+	var reported error
+	var stack []byte
+	r := New(WithRecovery(RecoveryOptions{
+		OnPanic: func(err error, s []byte) { reported, stack = err, s },
+	}))
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request, p Params) {
+		panic("boom")
+	})
+
+	t.Setenv("MORA_ENV", "")
+	resp := NewTestClient(r).WithHeader("Accept", "application/json").WithHeader("X-Request-ID", "req-7").Get("/panic")
+	resp.AssertStatus(t, http.StatusInternalServerError).
+		AssertHeader(t, "Content-Type", "application/json; charset=utf-8").
+		AssertJSON(t, "", map[string]string{"error": "internal server error", "request_id": "req-7"})
+	if reported == nil || reported.Error() != "boom" || !bytes.Contains(stack, []byte("TestRecoveryJSON")) {
+		t.Errorf("Expected OnPanic with error and stack, got %v", reported)
+	}
+
+	// Los navegadores siguen recibiendo texto plano
+	resp = NewTestClient(r).WithHeader("Accept", "text/html,*/*;q=0.8").Get("/panic")
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected plain text for browsers, got %q", ct)
+	}
+
+	// En desarrollo el JSON incluye el panic y el stack trace
+	t.Setenv("MORA_ENV", "development")
+	resp = NewTestClient(r).WithHeader("Content-Type", "application/json").Get("/panic")
+	resp.AssertJSON(t, "panic", "boom").AssertBodyContains(t, "TestRecoveryJSON")
+}
+
+// TestRecoveryAfterWrite verifica que el recovery no reescriba una respuesta
+// ya enviada y que en desarrollo el Content-Type llegue antes del estado
+func TestRecoveryAfterWrite(t *testing.T) {
+	panicked := false
+	r := New(WithRecovery(RecoveryOptions{OnPanic: func(error, []byte) { panicked = true }}))
+	r.Get("/partial", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("partial"))
+		panic("late")
+	})
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request, p Params) {
+		panic("early")
+	})
+	r.Get("/flushed", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.(http.Flusher).Flush()
+		panic("after flush")
+	})
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	resp := NewTestClient(r).Get("/partial")
+	if resp.StatusCode != http.StatusAccepted || resp.Text() != "partial" || !panicked {
+		t.Errorf("Expected untouched 202 'partial' and OnPanic called, got %d %q", resp.StatusCode, resp.Text())
+	}
+
+	// Flush ya envió un 200 implícito, así que no se responde con un 500
+	if resp := NewTestClient(r).Get("/flushed"); resp.StatusCode != http.StatusOK || resp.Text() != "" {
+		t.Errorf("Expected untouched 200 after Flush, got %d %q", resp.StatusCode, resp.Text())
+	}
+
+	// Con un servidor real net/http avisaría de un WriteHeader superfluo
+	server := httptest.NewUnstartedServer(r)
+	server.Config.ErrorLog = log.New(&logs, "", 0)
+	server.Start()
+	defer server.Close()
+	if res, err := http.Get(server.URL + "/partial"); err == nil {
+		res.Body.Close()
+	}
+	if strings.Contains(logs.String(), "superfluous") {
+		t.Errorf("Expected no superfluous WriteHeader warning, got logs:\n%s", logs.String())
+	}
+
+	t.Setenv("MORA_ENV", "development")
+	resp = NewTestClient(r).Get("/panic")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Expected text/plain in development, got %q", ct)
+	}
+	resp.AssertStatus(t, http.StatusInternalServerError).AssertBodyContains(t, "early")
+}
+
 // TestLoggingMiddleware verifica que el middleware de logging funcione correctamente
 func TestLoggingMiddleware(t *testing.T) {
 	// El logging es difícil de probar directamente, así que solo verificamos
@@ -48,6 +175,141 @@ func TestLoggingMiddleware(t *testing.T) {
 	}
 }
 
+// TestStructuredLogging verifica los campos que WithStructuredLogging registra con slog
+func TestStructuredLogging(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	r := New(WithStructuredLogging(logger), WithTrustedProxies("10.0.0.1"))
+	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Header().Set("X-Request-ID", "req-42")
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest("GET", "/users/7", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Error decoding log entry %q: %v", logs.String(), err)
+	}
+	expected := map[string]any{
+		"level":      "WARN",
+		"msg":        "request",
+		"method":     "GET",
+		"path":       "/users/7",
+		"route":      "/users/:id",
+		"status":     float64(404),
+		"request_id": "req-42",
+		"remote_ip":  "203.0.113.9",
+	}
+	for key, want := range expected {
+		if entry[key] != want {
+			t.Errorf("Expected %s=%v, got %v", key, want, entry[key])
+		}
+	}
+	if _, ok := entry["duration"].(float64); !ok {
+		t.Errorf("Expected numeric duration, got %v", entry["duration"])
+	}
+
+	// Sin proxy de confianza se registra la IP de RemoteAddr
+	logs.Reset()
+	req = httptest.NewRequest("GET", "/users/7", nil)
+	req.RemoteAddr = "192.0.2.5:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	entry = nil
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Error decoding log entry %q: %v", logs.String(), err)
+	}
+	if entry["remote_ip"] != "192.0.2.5" {
+		t.Errorf("Expected remote_ip 192.0.2.5, got %v", entry["remote_ip"])
+	}
+
+	// El registro no impide los WebSocket ni el streaming SSE
+	checkStreamingRoutes(t, New(WithStructuredLogging(slog.New(slog.DiscardHandler))), "/ws-logging", http.MethodGet)
+}
+
+// checkStreamingRoutes verifica que un WebSocket en wsPath y un SSE registrado
+// con sseMethod sigan funcionando con los middlewares de r. Los hubs WebSocket
+// se comparten por ruta, así que cada test usa su propio wsPath
+func checkStreamingRoutes(t *testing.T, r *MoraRouter, wsPath, sseMethod string) {
+	t.Helper()
+	hub := NewSSEHub()
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+
+	r.WebSocket(wsPath, func(conn *WebSocketConnection, msg []byte) {
+		conn.SendText(string(msg))
+	})
+	r.Handle(sseMethod, "/events", hub.Handler())
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, reader := dialWebSocket(t, server, wsPath)
+	defer conn.Close()
+	writeClientFrame(t, conn, 0x1, []byte("echo"))
+	if opcode, payload := readServerFrame(t, reader); opcode != 0x1 || string(payload) != "echo" {
+		t.Errorf("Expected the WebSocket echo, got opcode %x payload '%s'", opcode, payload)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, sseMethod, server.URL+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error connecting to the SSE route: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an SSE stream, got %d '%s'", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	waitForClients(t, hub, 1)
+	hub.Broadcast("tick", "1")
+	if got := readSSEEvent(t, bufio.NewReader(resp.Body)); got != "event: tick\ndata: 1" {
+		t.Errorf("Expected the SSE event, got %q", got)
+	}
+}
+
+// TestSlowRequestLog verifica que solo se registren las peticiones lentas
+func TestSlowRequestLog(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	r := New(WithSlowRequestLog(20 * time.Millisecond))
+	r.Get("/fast/:id", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte("fast"))
+	})
+	r.Get("/slow/:id", func(w http.ResponseWriter, r *http.Request, p Params) {
+		time.Sleep(40 * time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	client := NewTestClient(r)
+	if resp := client.Get("/fast/1"); resp.Text() != "fast" {
+		t.Fatalf("Expected 'fast', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected no log for the fast request, got %q", logs.String())
+	}
+
+	client.Get("/slow/42")
+	entry := logs.String()
+	for _, want := range []string{"[Mora][WARN] slow request GET /slow/42", "route=/slow/:id", "status=202", "params=map[id:42]"} {
+		if !strings.Contains(entry, want) {
+			t.Errorf("Expected slow log to contain %q, got %q", want, entry)
+		}
+	}
+	if strings.Contains(entry, "/fast/") {
+		t.Errorf("Expected only the slow request to be logged, got %q", entry)
+	}
+
+	// El aviso no impide los WebSocket ni el streaming SSE
+	checkStreamingRoutes(t, New(WithSlowRequestLog(time.Hour)), "/ws-slow", http.MethodGet)
+}
+
 // TestCORSMiddleware verifica que el middleware CORS agregue los encabezados correctos
 func TestCORSMiddleware(t *testing.T) {
 	r := New(WithCORS("*"))
@@ -72,31 +334,235 @@ func TestCORSMiddleware(t *testing.T) {
 		t.Errorf("Expected Access-Control-Allow-Origin header to be '*', got '%s'", allowOrigin)
 	}
 
-	// Nota: La prueba del preflight OPTIONS se omite hasta que implementemos
-	// completamente el soporte para preflight en el middleware CORS
+	// El preflight lo responde el manejo automático de OPTIONS
+	resp = client.
+		WithHeader("Access-Control-Request-Method", "GET").
+		Options("/cors-test")
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected 204 preflight with origin '*', got %d %q", resp.StatusCode, resp.Header.Get("Access-Control-Allow-Origin"))
+	}
+	if methods := resp.Header.Get("Access-Control-Allow-Methods"); methods != "GET" {
+		t.Errorf("Expected the route's methods in the preflight, got %q", methods)
+	}
+}
+
+// TestCORSConfig verifica la lista de orígenes, las credenciales y el preflight
+func TestCORSConfig(t *testing.T) {
+	r := New(WithCORSConfig(CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"X-Total-Count"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+	r.Get("/items", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	r.Post("/items", func(w http.ResponseWriter, r *http.Request, p Params) {})
+
+	// Un origen permitido se devuelve explícito junto con las credenciales
+	resp := NewTestClient(r).WithHeader("Origin", "https://app.example.com").Get("/items")
+	resp.AssertHeader(t, "Access-Control-Allow-Origin", "https://app.example.com").
+		AssertHeader(t, "Access-Control-Allow-Credentials", "true").
+		AssertHeader(t, "Access-Control-Expose-Headers", "X-Total-Count").
+		AssertHeader(t, "Vary", "Origin")
+
+	// Un origen desconocido no recibe cabeceras CORS
+	resp = NewTestClient(r).WithHeader("Origin", "https://evil.example.com").Get("/items")
+	if resp.HasHeader("Access-Control-Allow-Origin") || resp.HasHeader("Access-Control-Allow-Credentials") {
+		t.Errorf("Expected no CORS headers for an unknown origin, got %v", resp.Header)
+	}
+
+	resp = NewTestClient(r).
+		WithHeader("Origin", "https://app.example.com").
+		WithHeader("Access-Control-Request-Method", "POST").
+		WithHeader("Access-Control-Request-Headers", "content-type").
+		Options("/items")
+	resp.AssertStatus(t, http.StatusNoContent).
+		AssertHeader(t, "Access-Control-Allow-Origin", "https://app.example.com").
+		AssertHeader(t, "Access-Control-Allow-Methods", "GET, POST").
+		AssertHeader(t, "Access-Control-Allow-Headers", "Content-Type, Authorization").
+		AssertHeader(t, "Access-Control-Max-Age", "600")
+
+	// Los métodos configurados se limitan a los que tiene la ruta
+	r2 := New(WithCORSConfig(CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"get", "POST", "DELETE"},
+	}))
+	r2.Get("/reports", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	r2.Put("/reports", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	resp = NewTestClient(r2).
+		WithHeader("Origin", "https://app.example.com").
+		WithHeader("Access-Control-Request-Method", "GET").
+		Options("/reports")
+	resp.AssertHeader(t, "Access-Control-Allow-Methods", "GET").
+		AssertHeader(t, "Allow", "GET,PUT")
+
+	// "*" con credenciales es un error de configuración
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic for wildcard origin with credentials")
+		}
+	}()
+	WithCORSConfig(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+// TestCacheRouteTTL verifica el TTL por ruta de Route.Cache y la exclusión con Route.NoCache
+func TestCacheRouteTTL(t *testing.T) {
+	r := New(WithCache(time.Minute))
+	calls := map[string]int{}
+	handler := func(w http.ResponseWriter, req *http.Request, p Params) {
+		calls[req.URL.Path]++
+		w.Write([]byte("ok"))
+	}
+	r.Get("/route-ttl/config", handler).Cache(time.Hour)
+	r.Get("/route-ttl/feed", handler).Cache(time.Millisecond)
+	r.Get("/route-ttl/live", handler).NoCache()
+	r.Get("/route-ttl/default", handler)
+
+	client := NewTestClient(r)
+	for _, path := range []string{"/route-ttl/config", "/route-ttl/feed", "/route-ttl/live", "/route-ttl/default"} {
+		client.Get(path)
+	}
+	time.Sleep(5 * time.Millisecond)
+	for _, path := range []string{"/route-ttl/config", "/route-ttl/feed", "/route-ttl/live", "/route-ttl/default"} {
+		client.Get(path).AssertStatus(t, http.StatusOK).AssertBodyContains(t, "ok")
+	}
+
+	want := map[string]int{"/route-ttl/config": 1, "/route-ttl/feed": 2, "/route-ttl/live": 2, "/route-ttl/default": 1}
+	for path, n := range want {
+		if calls[path] != n {
+			t.Errorf("Expected %s to run %d times, got %d", path, n, calls[path])
+		}
+	}
+
+	// La entrada de /config usa el TTL de la ruta y /live no se guarda
+	cacheMu.Lock()
+	config, configOK := cacheStore["GET:/route-ttl/config"]
+	_, liveOK := cacheStore["GET:/route-ttl/live"]
+	cacheMu.Unlock()
+	if !configOK || time.Until(config.expire) < 59*time.Minute {
+		t.Errorf("Expected /config to be cached for an hour, got %v", time.Until(config.expire))
+	}
+	if liveOK {
+		t.Error("Expected /live not to be stored in the cache")
+	}
+	if resp := client.Get("/route-ttl/live"); resp.HasHeader("ETag") {
+		t.Errorf("Expected no ETag on a NoCache route, got %q", resp.Header.Get("ETag"))
+	}
+}
+
+// TestCacheConditional verifica que la caché responda 304 a If-None-Match sin ejecutar el handler
+func TestCacheConditional(t *testing.T) {
+	r := New(WithCache(time.Minute))
+	calls := 0
+	r.Get("/cache-etag", func(w http.ResponseWriter, r *http.Request, p Params) {
+		calls++
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("cached body"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/cache-etag", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "cached body" {
+		t.Fatalf("Expected 200 'cached body', got %d '%s'", w.Code, w.Body.String())
+	}
+	if etag == "" {
+		t.Fatal("Expected ETag header on cached response")
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain" {
+		t.Errorf("Expected Content-Type 'text/plain', got '%s'", ct)
+	}
+
+	req := httptest.NewRequest("GET", "/cache-etag", nil)
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected empty body, got '%s'", w.Body.String())
+	}
+	if w.Header().Get("ETag") != etag {
+		t.Errorf("Expected ETag %s, got %s", etag, w.Header().Get("ETag"))
+	}
+	if calls != 1 {
+		t.Errorf("Expected handler to run once, got %d", calls)
+	}
+
+	// Un ETag distinto recibe la respuesta completa desde la caché
+	req = httptest.NewRequest("GET", "/cache-etag", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "cached body" {
+		t.Errorf("Expected 200 'cached body', got %d '%s'", w.Code, w.Body.String())
+	}
+	if calls != 1 {
+		t.Errorf("Expected handler to run once, got %d", calls)
+	}
+}
+
+// TestAcceptEnforcement verifica que se responda 406 cuando Accept no admite los tipos soportados
+func TestAcceptEnforcement(t *testing.T) {
+	r := New(WithAcceptEnforcement("application/json"))
+	r.Get("/strict", func(w http.ResponseWriter, r *http.Request, p Params) {
+		JSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	cases := []struct {
+		accept string
+		status int
+	}{
+		{"application/pdf", http.StatusNotAcceptable},
+		{"application/json", http.StatusOK},
+		{"", http.StatusOK},
+		{"*/*", http.StatusOK},
+		{"application/*;q=0.5", http.StatusOK},
+		{"text/html, application/json;q=0.1", http.StatusOK},
+		{"application/json;q=0, */*", http.StatusNotAcceptable},
+		{"text/*, application/xml", http.StatusNotAcceptable},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/strict", nil)
+		if c.accept != "" {
+			req.Header.Set("Accept", c.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.status {
+			t.Errorf("Expected status %d for Accept '%s', got %d", c.status, c.accept, w.Code)
+		}
+	}
 }
 
-// timeoutHandler es un middleware que simula un tiempo de espera
+// timeoutHandler es un middleware que simula un tiempo de espera. El
+// handler escribe en su propio buffer, que se descarta si se agota el tiempo,
+// para no tocar w desde la goroutine después de responder.
 func timeoutHandler(timeout time.Duration) Middleware {
 	return func(next HandlerFunc) HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request, p Params) {
 			// Simular procesamiento con temporizador
-			done := make(chan bool)
+			buf := httptest.NewRecorder()
+			done := make(chan struct{}, 1)
 
 			go func() {
-				next(w, r, p)
-				done <- true
+				next(buf, r, p)
+				done <- struct{}{}
 			}()
 
 			select {
 			case <-done:
 				// La solicitud se completó dentro del límite de tiempo
-				return
+				for k, v := range buf.Header() {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(buf.Code)
+				w.Write(buf.Body.Bytes())
 			case <-time.After(timeout):
 				// Se agotó el tiempo
 				w.WriteHeader(http.StatusRequestTimeout)
 				w.Write([]byte("Request timed out"))
-				return
 			}
 		}
 	}
@@ -194,3 +660,187 @@ func TestAuthMiddleware(t *testing.T) {
 		t.Errorf("Expected 'Protected content', got '%s'", resp.Text())
 	}
 }
+
+// TestMaxConcurrentRequests verifica que el exceso de peticiones simultáneas reciba 503
+func TestMaxConcurrentRequests(t *testing.T) {
+	const limit = 2
+	r := New(WithMaxConcurrentRequests(limit))
+
+	release := make(chan struct{})
+	started := make(chan struct{}, limit)
+	r.Get("/slow", func(w http.ResponseWriter, r *http.Request, p Params) {
+		started <- struct{}{}
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	client := NewTestClient(r)
+
+	// Ocupar todos los huecos con peticiones lentas
+	results := make(chan int, limit)
+	for i := 0; i < limit; i++ {
+		go func() {
+			results <- client.Get("/slow").StatusCode
+		}()
+	}
+	for i := 0; i < limit; i++ {
+		<-started
+	}
+
+	// Las peticiones adicionales se rechazan
+	for i := 0; i < 3; i++ {
+		resp := client.Get("/slow")
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503 over the limit, got %d", resp.StatusCode)
+		}
+		if resp.Header.Get("Retry-After") == "" {
+			t.Errorf("Expected Retry-After header on rejected request")
+		}
+	}
+
+	close(release)
+	for i := 0; i < limit; i++ {
+		if status := <-results; status != http.StatusOK {
+			t.Errorf("Expected in-flight request to succeed, got %d", status)
+		}
+	}
+
+	// 0 o un valor negativo no limitan
+	for _, n := range []int{0, -1} {
+		unlimited := New(WithMaxConcurrentRequests(n))
+		unlimited.Get("/ok", func(w http.ResponseWriter, r *http.Request, p Params) {})
+		if resp := NewTestClient(unlimited).Get("/ok"); !resp.IsOK() {
+			t.Errorf("Expected status 200 with limit %d, got %d", n, resp.StatusCode)
+		}
+	}
+}
+
+// TestAuditMiddleware verifica que solo las peticiones que modifican estado generen eventos
+func TestAuditMiddleware(t *testing.T) {
+	var events []AuditEvent
+	r := New(WithAudit(func(ev AuditEvent) {
+		events = append(events, ev)
+	}), WithJWT("secret"))
+
+	r.Get("/items/:id", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte("item"))
+	})
+	r.Post("/items", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// Token HS256 firmado con la misma clave
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice"}`))
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(header + "." + payload))
+	token := header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	client := NewTestClient(r).WithAuth(token)
+
+	// Un GET no genera evento
+	if resp := client.Get("/items/1"); !resp.IsOK() {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if len(events) != 0 {
+		t.Fatalf("Expected no audit events for GET, got %d", len(events))
+	}
+
+	// Un POST genera un evento con identidad, patrón y estado
+	before := time.Now()
+	if resp := client.Post("/items", nil); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 audit event for POST, got %d", len(events))
+	}
+	ev := events[0]
+	if ev.Identity != "alice" || ev.Method != http.MethodPost || ev.Pattern != "/items" || ev.Status != http.StatusCreated {
+		t.Errorf("Unexpected audit event: %+v", ev)
+	}
+	if ev.Timestamp.Before(before) {
+		t.Errorf("Expected timestamp after the request started, got %v", ev.Timestamp)
+	}
+
+	// Una respuesta POST en streaming sigue pudiendo hacer Flush
+	checkStreamingRoutes(t, New(WithAudit(func(AuditEvent) {})), "/ws-audit", http.MethodPost)
+}
+
+// TestForRouteNames verifica que el middleware solo se ejecute en rutas con el prefijo de nombre
+func TestForRouteNames(t *testing.T) {
+	r := New()
+
+	r.Use(ForRouteNames("admin.", func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			w.Header().Set("X-Admin", RouteName(r))
+			next(w, r, p)
+		}
+	}))
+
+	r.Get("/admin/users", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte("users"))
+	})
+	r.Get("/", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte("home"))
+	})
+	r.Get("/about", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte("about"))
+	})
+	// Los nombres se asignan después de registrar las rutas
+	r.Name("admin.users.index", "/admin/users")
+	r.Name("public.home", "/")
+
+	client := NewTestClient(r)
+
+	resp := client.Get("/admin/users")
+	if got := resp.Header.Get("X-Admin"); got != "admin.users.index" {
+		t.Errorf("Expected middleware to run for admin.users.index, got header '%s'", got)
+	}
+
+	for _, path := range []string{"/", "/about"} {
+		resp := client.Get(path)
+		if !resp.IsOK() {
+			t.Errorf("Expected status 200 for %s, got %d", path, resp.StatusCode)
+		}
+		if got := resp.Header.Get("X-Admin"); got != "" {
+			t.Errorf("Expected middleware to be skipped for %s, got header '%s'", path, got)
+		}
+	}
+
+	// Cada método de un mismo patrón conserva su nombre
+	r.Get("/posts", func(w http.ResponseWriter, r *http.Request, p Params) {}).Name("public.posts.index")
+	r.Post("/posts", func(w http.ResponseWriter, r *http.Request, p Params) {}).Name("admin.posts.create")
+	if got := client.Get("/posts").Header.Get("X-Admin"); got != "" {
+		t.Errorf("Expected middleware to be skipped for GET /posts, got header '%s'", got)
+	}
+	if got := client.Post("/posts", nil).Header.Get("X-Admin"); got != "admin.posts.create" {
+		t.Errorf("Expected middleware to run for admin.posts.create, got header '%s'", got)
+	}
+}
+
+// TestServerHeader verifica la cabecera Server personalizada y su supresión
+func TestServerHeader(t *testing.T) {
+	r := New(WithServerHeader("mora-test/1.0"))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte("ok"))
+	})
+	if got := NewTestClient(r).Get("/").Header.Get("Server"); got != "mora-test/1.0" {
+		t.Errorf("Expected Server 'mora-test/1.0', got '%s'", got)
+	}
+
+	// La supresión elimina también la cabecera puesta por otros middlewares o el handler
+	r = New(WithoutServerHeader(), WithServerHeader("mora-test/1.0"))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte("ok"))
+	})
+	r.Get("/proxied", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Header().Set("Server", "nginx")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	for _, path := range []string{"/", "/proxied"} {
+		resp := NewTestClient(r).Get(path)
+		if _, ok := resp.Header["Server"]; ok {
+			t.Errorf("Expected no Server header for %s, got '%s'", path, resp.Header.Get("Server"))
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package router
+
+import (
+	_ "embed"
+	"encoding"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// operationDoc describe el cuerpo de la petición, las respuestas y los
+// ejemplos de una ruta para la especificación OpenAPI.
+type operationDoc struct {
+	requestType    reflect.Type
+	requestContent string               // application/json o application/xml
+	responses      map[int]reflect.Type // código -> tipo del cuerpo
+	info           RouteDoc
+}
+
+// RouteDoc son los cuerpos de ejemplo que Route.Document añade a la
+// especificación OpenAPI; el resumen y la descripción se fijan con Route.Doc.
+type RouteDoc struct {
+	// Example es un cuerpo de petición de ejemplo; aparece en examples del
+	// requestBody junto al esquema de Route.Accepts.
+	Example any
+	// ResponseExamples son cuerpos de respuesta de ejemplo por código HTTP.
+	ResponseExamples map[int]any
+}
+
+// SwaggerOptions fija los metadatos de la especificación de WithSwagger. Los
+// campos vacíos conservan los valores por defecto.
+type SwaggerOptions struct {
+	// Título de la API; por defecto "API generada con MoraRouter"
+	Title string
+	// Descripción de la API
+	Description string
+	// Versión de la API; por defecto 1.0.0
+	Version string
+	// URLs base de la API, p. ej. una por entorno
+	Servers []OpenAPIServer
+}
+
+// OpenAPIServer es una entrada del bloque servers de la especificación.
+type OpenAPIServer struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// SwaggerUIOptions configura la página de WithSwaggerUIAdvanced.
+type SwaggerUIOptions struct {
+	// Ruta donde se sirve la página, p. ej. /docs
+	Path string
+	// URL de la especificación; por defecto /openapi.json, la de WithSwagger
+	SpecURL string
+	// Título de la página hasta que carga la especificación
+	Title string
+}
+
+//go:embed swagger_ui.html
+var swaggerUIPage string
+
+var swaggerUITemplate = template.Must(template.New("swagger-ui").Parse(swaggerUIPage))
+
+// WithSwaggerUI sirve en path una página para explorar y probar la API
+// descrita en /openapi.json (ver WithSwagger). La página lleva sus estilos y
+// scripts incrustados, así que no necesita acceso a internet.
+func WithSwaggerUI(path string) Option {
+	return WithSwaggerUIAdvanced(SwaggerUIOptions{Path: path})
+}
+
+// WithSwaggerUIAdvanced es WithSwaggerUI con una URL de especificación y un
+// título propios.
+func WithSwaggerUIAdvanced(opts SwaggerUIOptions) Option {
+	if opts.SpecURL == "" {
+		opts.SpecURL = "/openapi.json"
+	}
+	if opts.Title == "" {
+		opts.Title = "API Reference"
+	}
+	return func(r *MoraRouter) {
+		r.Get(opts.Path, func(w http.ResponseWriter, req *http.Request, p Params) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := swaggerUITemplate.Execute(w, opts); err != nil {
+				http.Error(w, "Error rendering Swagger UI", http.StatusInternalServerError)
+			}
+		})
+	}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	schemaNameChars   = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// implementsMarshaler indica si t o *t codifican su propio JSON o texto.
+func implementsMarshaler(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(jsonMarshalerType) || pt.Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || pt.Implements(textMarshalerType)
+}
+
+// schemaBuilder genera esquemas OpenAPI a partir de tipos Go. Los structs con
+// nombre se añaden a components.schemas y se referencian con $ref.
+type schemaBuilder struct {
+	schemas map[string]interface{}
+	names   map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		schemas: make(map[string]interface{}),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schema devuelve el esquema de t.
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType || implementsMarshaler(t):
+		// el formato lo decide su propio MarshalJSON
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		// []byte se codifica en base64
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + b.componentName(t)}
+	}
+	// interfaces y demás: cualquier valor
+	return map[string]interface{}{}
+}
+
+// componentName registra el struct t en components.schemas y devuelve su
+// nombre. Se reserva antes de generar el esquema para admitir tipos recursivos.
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := schemaNameChars.ReplaceAllString(t.Name(), "_")
+	if _, taken := b.schemas[name]; taken {
+		// mismo nombre en otro paquete
+		pkg := t.PkgPath()
+		base := schemaNameChars.ReplaceAllString(pkg[strings.LastIndex(pkg, "/")+1:], "_") + "." + name
+		name = base
+		for i := 2; b.schemas[name] != nil; i++ {
+			name = base + strconv.Itoa(i)
+		}
+	}
+	b.names[t] = name
+	b.schemas[name] = map[string]interface{}{}
+	b.schemas[name] = b.structSchema(t)
+	return name
+}
+
+// structSchema genera el esquema de un struct con los nombres de sus tags
+// json. Las reglas de validate se traducen a required, format y límites.
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	b.addFields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// los campos de un struct embebido sin nombre se aplanan, como en encoding/json
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(ft, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := b.schema(field.Type)
+		if strings.Contains(opts, "string") {
+			schema = map[string]interface{}{"type": "string"}
+		}
+		if applyValidateRules(schema, field.Type, field.Tag.Get(ValidationTagName)) {
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+}
+
+// applyValidateRules añade a schema las restricciones de un tag validate y
+// devuelve si el campo es obligatorio. Las reglas tras dive se ignoran.
+func applyValidateRules(schema map[string]interface{}, t reflect.Type, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// un $ref no admite propiedades junto a él
+	_, isRef := schema["$ref"]
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, value, _ := strings.Cut(rule, "=")
+		if name == "dive" {
+			break
+		}
+		if name == "required" {
+			required = true
+			continue
+		}
+		if isRef {
+			continue
+		}
+		switch name {
+		case "email":
+			schema["format"] = "email"
+		case "url":
+			schema["format"] = "uri"
+		case "uuid":
+			schema["format"] = "uuid"
+		case "regex":
+			schema["pattern"] = value
+		case "in":
+			schema["enum"] = strings.Split(value, "|")
+		case "min", "max", "len":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			var keys []string
+			switch t.Kind() {
+			case reflect.String:
+				keys = []string{"minLength", "maxLength"}
+			case reflect.Slice, reflect.Array:
+				keys = []string{"minItems", "maxItems"}
+			case reflect.Map:
+				keys = []string{"minProperties", "maxProperties"}
+			default:
+				keys = []string{"minimum", "maximum"}
+			}
+			switch name {
+			case "min":
+				schema[keys[0]] = n
+			case "max":
+				schema[keys[1]] = n
+			default:
+				schema[keys[0]], schema[keys[1]] = n, n
+			}
+		case "gt", "gte", "lt", "lte":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			switch name {
+			case "gt":
+				schema["minimum"], schema["exclusiveMinimum"] = n, true
+			case "gte":
+				schema["minimum"] = n
+			case "lt":
+				schema["maximum"], schema["exclusiveMaximum"] = n, true
+			case "lte":
+				schema["maximum"] = n
+			}
+		}
+	}
+	return required
+}
+
+// operationBody completa operation con el requestBody, las respuestas y los
+// ejemplos documentados de doc.
+func (b *schemaBuilder) operationBody(operation map[string]interface{}, doc operationDoc) {
+	if doc.requestType != nil || doc.info.Example != nil {
+		contentType := doc.requestContent
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				contentType: b.mediaType(doc.requestType, doc.info.Example),
+			},
+		}
+	}
+
+	statuses := make(map[int]bool)
+	for status := range doc.responses {
+		statuses[status] = true
+	}
+	for status := range doc.info.ResponseExamples {
+		statuses[status] = true
+	}
+	if len(statuses) > 0 {
+		responses := make(map[string]interface{})
+		for status := range statuses {
+			responses[strconv.Itoa(status)] = map[string]interface{}{
+				"description": http.StatusText(status),
+				"content": map[string]interface{}{
+					"application/json": b.mediaType(doc.responses[status], doc.info.ResponseExamples[status]),
+				},
+			}
+		}
+		operation["responses"] = responses
+	}
+}
+
+// mediaType genera un Media Type Object con el esquema de t y el ejemplo,
+// si los hay.
+func (b *schemaBuilder) mediaType(t reflect.Type, example any) map[string]interface{} {
+	media := make(map[string]interface{})
+	if t != nil {
+		media["schema"] = b.schema(t)
+	}
+	if example != nil {
+		media["examples"] = map[string]interface{}{
+			"default": map[string]interface{}{"value": example},
+		}
+	}
+	return media
+}
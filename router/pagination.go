@@ -0,0 +1,98 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultPerPage es el tamaño de página si la petición no indica per_page.
+var DefaultPerPage = 20
+
+// MaxPerPage limita el per_page que puede pedir un cliente.
+var MaxPerPage = 100
+
+// PageMeta describe la página servida por un endpoint de listado.
+type PageMeta struct {
+	Page       int
+	PerPage    int
+	Total      int
+	TotalPages int
+
+	// URL de la petición, base para las cabeceras Link
+	url *url.URL
+}
+
+// Paginate lee ?page= y ?per_page= (por defecto 1 y DefaultPerPage, con
+// per_page limitado a MaxPerPage) y devuelve el rango a servir de total
+// elementos. offset y limit ya están acotados a total, así que
+// items[offset:offset+limit] es siempre válido.
+func Paginate(r *http.Request, total int) (offset, limit int, meta PageMeta) {
+	query := r.URL.Query()
+
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(query.Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = DefaultPerPage
+	}
+	if perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+
+	meta = PageMeta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+		url:        r.URL,
+	}
+
+	// Se compara antes de multiplicar: un page enorme desbordaría (page-1)*perPage
+	offset = total
+	if page-1 <= total/perPage {
+		offset = (page - 1) * perPage
+	}
+	limit = min(perPage, total-offset)
+	return offset, limit, meta
+}
+
+// JSONPaginated responde con los elementos y los datos de paginación
+// (total, page, per_page) y añade cabeceras Link con rel="next" y rel="prev"
+// (RFC 5988) cuando existen esas páginas.
+func JSONPaginated(w http.ResponseWriter, status int, items interface{}, meta PageMeta) {
+	var links []string
+	if meta.Page < meta.TotalPages {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, meta.pageURL(meta.Page+1)))
+	}
+	if meta.Page > 1 && meta.TotalPages > 0 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, meta.pageURL(min(meta.Page-1, meta.TotalPages))))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	JSON(w, status, map[string]interface{}{
+		"items":    items,
+		"total":    meta.Total,
+		"page":     meta.Page,
+		"per_page": meta.PerPage,
+	})
+}
+
+// pageURL devuelve la URL de la petición apuntando a otra página.
+func (m PageMeta) pageURL(page int) string {
+	u := url.URL{}
+	if m.url != nil {
+		u = *m.url
+	}
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(m.PerPage))
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
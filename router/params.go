@@ -0,0 +1,357 @@
+package router
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Has indica si el parámetro existe, aunque su valor sea vacío.
+func (p Params) Has(key string) bool {
+	_, ok := p[key]
+	return ok
+}
+
+// Get devuelve el valor del parámetro o "" si no existe.
+func (p Params) Get(key string) string {
+	return p[key]
+}
+
+// GetDefault devuelve el valor del parámetro o fallback si no existe.
+func (p Params) GetDefault(key, fallback string) string {
+	if v, ok := p[key]; ok {
+		return v
+	}
+	return fallback
+}
+
+// Set asigna un parámetro. El router guarda el mismo mapa en el contexto de
+// la petición, así que un middleware que llama a Set antes de next hace el
+// valor visible tanto en el argumento p del handler como en Param(r, key).
+func (p Params) Set(key, value string) {
+	p[key] = value
+}
+
+// Clone devuelve una copia independiente de los parámetros.
+func (p Params) Clone() Params {
+	clone := make(Params, len(p))
+	for k, v := range p {
+		clone[k] = v
+	}
+	return clone
+}
+
+// SetParams devuelve una copia de r cuyo contexto lleva p. Un middleware que
+// reemplaza los parámetros en lugar de modificarlos debe pasar ambos a next,
+// next(w, SetParams(r, p2), p2), para que Param y el argumento coincidan.
+func SetParams(r *http.Request, p Params) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), paramsKey, p))
+}
+
+// ParamsFromContext devuelve los parámetros de ruta que ServeHTTP guarda en
+// el contexto de cada petición, o nil si ctx no los lleva. Sirve a código que
+// solo recibe el contexto, como servicios llamados desde el handler.
+func ParamsFromContext(ctx context.Context) Params {
+	p, _ := ctx.Value(paramsKey).(Params)
+	return p
+}
+
+// ParamInt convierte a int el parámetro de ruta name de la petición.
+func ParamInt(r *http.Request, name string) (int, error) {
+	return ParamsFromContext(r.Context()).Int(name)
+}
+
+// ParamInt64 convierte a int64 el parámetro de ruta name de la petición.
+func ParamInt64(r *http.Request, name string) (int64, error) {
+	return ParamsFromContext(r.Context()).Int64(name)
+}
+
+// ParamBool convierte a bool el parámetro de ruta name de la petición.
+func ParamBool(r *http.Request, name string) (bool, error) {
+	return ParamsFromContext(r.Context()).Bool(name)
+}
+
+// Int convierte el parámetro a int.
+func (p Params) Int(key string) (int, error) {
+	v, ok := p[key]
+	if !ok {
+		return 0, fmt.Errorf("parámetro no encontrado: %s", key)
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("parámetro %s no es un entero: %w", key, err)
+	}
+	return n, nil
+}
+
+// IntDefault convierte el parámetro a int o devuelve fallback si falta o es inválido.
+func (p Params) IntDefault(key string, fallback int) int {
+	n, err := p.Int(key)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// Int64 convierte el parámetro a int64.
+func (p Params) Int64(key string) (int64, error) {
+	v, ok := p[key]
+	if !ok {
+		return 0, fmt.Errorf("parámetro no encontrado: %s", key)
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parámetro %s no es un entero: %w", key, err)
+	}
+	return n, nil
+}
+
+// Float64 convierte el parámetro a float64.
+func (p Params) Float64(key string) (float64, error) {
+	v, ok := p[key]
+	if !ok {
+		return 0, fmt.Errorf("parámetro no encontrado: %s", key)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parámetro %s no es un número: %w", key, err)
+	}
+	return f, nil
+}
+
+// Bool convierte el parámetro a bool usando strconv.ParseBool.
+func (p Params) Bool(key string) (bool, error) {
+	v, ok := p[key]
+	if !ok {
+		return false, fmt.Errorf("parámetro no encontrado: %s", key)
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("parámetro %s no es un booleano: %w", key, err)
+	}
+	return b, nil
+}
+
+// Bind copia los parámetros en los campos del struct apuntado por obj. El
+// nombre se toma del tag `param:"id"` o, si falta, del nombre del campo en
+// minúsculas; `param:"-"` ignora el campo. Además de cadenas, números y
+// booleanos admite UUID en campos [16]byte (como uuid.UUID).
+func (p Params) Bind(obj any) error {
+	val := reflect.ValueOf(obj)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind requiere un puntero a struct")
+	}
+	errs := bindTagged(val.Elem(), "param", true, func(key string) (string, bool) {
+		v, ok := p[key]
+		return v, ok
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("parámetro %s: %s", errs[0].Field, errs[0].Message)
+	}
+	return nil
+}
+
+// bindTagged asigna a los campos de val los valores que devuelve get para la
+// clave de su tag; con defaultName, los campos sin tag usan su nombre en
+// minúsculas. Devuelve un error por cada valor que no se pudo convertir.
+func bindTagged(val reflect.Value, tag string, defaultName bool, get func(key string) (string, bool)) ValidationErrors {
+	var errs ValidationErrors
+	typ := val.Type()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		key := typ.Field(i).Tag.Get(tag)
+		if key == "-" || (key == "" && !defaultName) {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(typ.Field(i).Name)
+		}
+		v, ok := get(key)
+		if !ok {
+			continue
+		}
+		if err := setParamField(field, v); err != nil {
+			errs = append(errs, ValidationError{Field: key, Message: err.Error(), Rule: "type", Value: v})
+		}
+	}
+	return errs
+}
+
+// setParamField convierte v al tipo del campo y lo asigna.
+func setParamField(field reflect.Value, v string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(v, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("no es un entero: %w", err)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(v, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("no es un entero sin signo: %w", err)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(v, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("no es un número: %w", err)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("no es un booleano: %w", err)
+		}
+		field.SetBool(b)
+	case reflect.Array:
+		if field.Len() != 16 || field.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("tipo no soportado: %s", field.Type())
+		}
+		id, err := parseUUID(v)
+		if err != nil {
+			return err
+		}
+		reflect.Copy(field, reflect.ValueOf(id[:]))
+	default:
+		return fmt.Errorf("tipo no soportado: %s", field.Type())
+	}
+	return nil
+}
+
+// parseUUID interpreta un UUID en forma canónica xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.
+func parseUUID(s string) ([16]byte, error) {
+	var id [16]byte
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return id, fmt.Errorf("no es un UUID: %q", s)
+	}
+	raw := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(id[:], []byte(raw)); err != nil {
+		return id, fmt.Errorf("no es un UUID: %q", s)
+	}
+	return id, nil
+}
+
+// BindParams completa un struct T con los parámetros de ruta (ver Params.Bind)
+// antes de llamar al handler y valida tags `validate`.
+func BindParams[T any](h func(http.ResponseWriter, *http.Request, Params, T)) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, p Params) {
+		var obj T
+		if err := p.Bind(&obj); err != nil {
+			http.Error(w, fmt.Sprintf("invalid path parameter: %v", err), http.StatusBadRequest)
+			return
+		}
+		if errs := GetValidator(r).Validate(obj); len(errs) > 0 {
+			handleValidationErrors(w, r, errs)
+			return
+		}
+		h(w, r, p, obj)
+	}
+}
+
+// BindSource identifica una de las partes de la petición que lee BindRequest.
+type BindSource string
+
+const (
+	BindFromBody  BindSource = "body"  // cuerpo JSON, tags `json`
+	BindFromPath  BindSource = "path"  // parámetros de ruta, tags `param`
+	BindFromQuery BindSource = "query" // query string, tags `query`
+)
+
+// BindRequest completa un único struct T con el cuerpo JSON y los parámetros
+// de ruta y de query antes de llamar al handler. Solo se enlazan los campos
+// con tag `param` o `query`. Si una fuente aporta un campo que también llega
+// por otra, gana la primera de precedence (por defecto cuerpo, ruta, query).
+// Los errores de conversión y validación se responden juntos con 400 y un
+// JSON {"errors": [...]}.
+func BindRequest[T any](h func(http.ResponseWriter, *http.Request, Params, T), precedence ...BindSource) HandlerFunc {
+	if len(precedence) == 0 {
+		precedence = []BindSource{BindFromBody, BindFromPath, BindFromQuery}
+	}
+	return func(w http.ResponseWriter, r *http.Request, p Params) {
+		var obj T
+		val := reflect.ValueOf(&obj).Elem()
+		if val.Kind() != reflect.Struct {
+			http.Error(w, "BindRequest requiere un struct", http.StatusInternalServerError)
+			return
+		}
+
+		// aplicar de menor a mayor precedencia para que gane la primera
+		var errs ValidationErrors
+		query := r.URL.Query()
+		for i := len(precedence) - 1; i >= 0; i-- {
+			switch precedence[i] {
+			case BindFromBody:
+				if r.Body == nil || r.Body == http.NoBody {
+					continue
+				}
+				if err := json.NewDecoder(r.Body).Decode(&obj); err != nil && err != io.EOF {
+					errs = append(errs, ValidationError{Field: "body", Message: fmt.Sprintf("invalid JSON: %v", err), Rule: "json"})
+				}
+			case BindFromPath:
+				errs = append(errs, bindTagged(val, "param", false, func(key string) (string, bool) {
+					v, ok := p[key]
+					return v, ok
+				})...)
+			case BindFromQuery:
+				errs = append(errs, bindTagged(val, "query", false, func(key string) (string, bool) {
+					if !query.Has(key) {
+						return "", false
+					}
+					return query.Get(key), true
+				})...)
+			}
+		}
+
+		if len(errs) == 0 {
+			errs = GetValidator(r).Validate(obj)
+		}
+		if len(errs) > 0 {
+			handleValidationErrors(w, r, errs)
+			return
+		}
+		h(w, r, p, obj)
+	}
+}
+
+// BindHeaders completa un struct T con las cabeceras de la petición antes de
+// llamar al handler. Solo se enlazan los campos con tag `header:"X-Tenant-ID"`
+// y se validan los tags `validate`, así que `required` exige la cabecera. Los
+// errores se responden con 400 y un JSON {"errors": [...]}.
+func BindHeaders[T any](h func(http.ResponseWriter, *http.Request, Params, T)) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, p Params) {
+		var obj T
+		val := reflect.ValueOf(&obj).Elem()
+		if val.Kind() != reflect.Struct {
+			http.Error(w, "BindHeaders requiere un struct", http.StatusInternalServerError)
+			return
+		}
+
+		errs := bindTagged(val, "header", false, func(key string) (string, bool) {
+			values := r.Header.Values(key)
+			if len(values) == 0 {
+				return "", false
+			}
+			return values[0], true
+		})
+		if len(errs) == 0 {
+			errs = GetValidator(r).Validate(obj)
+		}
+		if len(errs) > 0 {
+			handleValidationErrors(w, r, errs)
+			return
+		}
+		h(w, r, p, obj)
+	}
+}
@@ -1,7 +1,11 @@
 package router
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -169,6 +173,44 @@ func TestParamFromContext(t *testing.T) {
 	}
 }
 
+// TestTypedParamsFromContext verifica ParamsFromContext y los accesores
+// tipados que solo necesitan la petición
+func TestTypedParamsFromContext(t *testing.T) {
+	// lookup simula un servicio que solo recibe el contexto
+	lookup := func(ctx context.Context) string {
+		p := ParamsFromContext(ctx)
+		return p["org"] + "/" + p["id"]
+	}
+
+	r := New()
+	r.Get("/orgs/:org/items/:id/:big/:flag", func(w http.ResponseWriter, req *http.Request, p Params) {
+		id, err := ParamInt(req, "id")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		big, _ := ParamInt64(req, "big")
+		flag, _ := ParamBool(req, "flag")
+		fmt.Fprintf(w, "%s %d %d %t", lookup(req.Context()), id, big, flag)
+	})
+
+	resp := NewTestClient(r).Get("/orgs/acme/items/7/9000000000/true")
+	if expected := "acme/7 7 9000000000 true"; resp.Text() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, resp.Text())
+	}
+
+	// Fuera del router no hay parámetros y los accesores devuelven error
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if p := ParamsFromContext(req.Context()); p != nil {
+		t.Errorf("Expected nil params, got %v", p)
+	}
+	if _, err := ParamInt(req, "id"); err == nil {
+		t.Error("Expected error for a missing parameter")
+	}
+	if Param(req, "id") != "" {
+		t.Error("Expected empty Param without route params")
+	}
+}
+
 // TestAlternativeParamSyntax verifica la sintaxis alternativa para parámetros
 func TestAlternativeParamSyntax(t *testing.T) {
 	r := New()
@@ -197,3 +239,248 @@ func TestAlternativeParamSyntax(t *testing.T) {
 		t.Errorf("Expected status 404, got %d", resp.StatusCode)
 	}
 }
+
+// TestParamsAccessors verifica los accesores con valores por defecto y tipados
+func TestParamsAccessors(t *testing.T) {
+	p := Params{"id": "42", "empty": "", "ratio": "1.5", "active": "true"}
+
+	if !p.Has("id") || !p.Has("empty") {
+		t.Errorf("Expected Has to report existing keys")
+	}
+	if p.Has("missing") {
+		t.Errorf("Expected Has to be false for missing key")
+	}
+
+	if got := p.GetDefault("id", "0"); got != "42" {
+		t.Errorf("Expected '42', got '%s'", got)
+	}
+	if got := p.GetDefault("empty", "fallback"); got != "" {
+		t.Errorf("Expected present empty value, got '%s'", got)
+	}
+	if got := p.GetDefault("missing", "fallback"); got != "fallback" {
+		t.Errorf("Expected 'fallback', got '%s'", got)
+	}
+	if got := p.Get("missing"); got != "" {
+		t.Errorf("Expected empty string for missing key, got '%s'", got)
+	}
+
+	if n, err := p.Int("id"); err != nil || n != 42 {
+		t.Errorf("Expected 42, got %d (%v)", n, err)
+	}
+	if _, err := p.Int("missing"); err == nil {
+		t.Errorf("Expected error for missing int param")
+	}
+	if n := p.IntDefault("ratio", 7); n != 7 {
+		t.Errorf("Expected fallback 7 for non-integer param, got %d", n)
+	}
+	if f, err := p.Float64("ratio"); err != nil || f != 1.5 {
+		t.Errorf("Expected 1.5, got %f (%v)", f, err)
+	}
+	if b, err := p.Bool("active"); err != nil || !b {
+		t.Errorf("Expected true, got %v (%v)", b, err)
+	}
+
+	// Params sigue siendo intercambiable con map[string]string
+	var m map[string]string = p
+	p = m
+	if p.Get("id") != "42" {
+		t.Errorf("Expected conversion to keep values")
+	}
+}
+
+// TestStrictParamValidation verifica la respuesta 400 para valores que no cumplen la regex
+func TestStrictParamValidation(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte(p["id"]))
+	}
+
+	// Por defecto se mantiene el 404
+	r := New()
+	r.Get("/items/:id(\\d+)", handler)
+	if resp := NewTestClient(r).Get("/items/abc"); !resp.IsNotFound() {
+		t.Errorf("Expected status 404 by default, got %d", resp.StatusCode)
+	}
+
+	strict := New(WithStrictParamValidation())
+	strict.Get("/items/:id(\\d+)", handler)
+	client := NewTestClient(strict)
+
+	resp := client.Get("/items/abc")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 in strict mode, got %d", resp.StatusCode)
+	}
+	if !strings.Contains(resp.Text(), `"id"`) {
+		t.Errorf("Expected message to name the parameter, got '%s'", resp.Text())
+	}
+
+	// Rutas con otra forma siguen devolviendo 404
+	if resp := client.Get("/items/abc/extra"); !resp.IsNotFound() {
+		t.Errorf("Expected status 404 for unrelated path, got %d", resp.StatusCode)
+	}
+	if resp := client.Get("/items/42"); !resp.IsOK() {
+		t.Errorf("Expected status 200 for valid ID, got %d", resp.StatusCode)
+	}
+}
+
+// TestBindParams verifica el enlace de parámetros de ruta a un struct tipado
+func TestBindParams(t *testing.T) {
+	type postParams struct {
+		UserID int      `param:"id"`
+		PostID [16]byte `param:"post_id"`
+	}
+
+	r := New()
+	r.Get("/users/:id/posts/:post_id", BindParams(func(w http.ResponseWriter, r *http.Request, p Params, in postParams) {
+		fmt.Fprintf(w, "%d:%x", in.UserID, in.PostID)
+	}))
+
+	client := NewTestClient(r)
+
+	resp := client.Get("/users/42/posts/123e4567-e89b-12d3-a456-426614174000")
+	if !resp.IsOK() {
+		t.Fatalf("Expected status 200, got %d: %s", resp.StatusCode, resp.Text())
+	}
+	if expected := "42:123e4567e89b12d3a456426614174000"; resp.Text() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, resp.Text())
+	}
+
+	// Valores que no se pueden convertir responden 400
+	for _, path := range []string{"/users/abc/posts/123e4567-e89b-12d3-a456-426614174000", "/users/42/posts/not-a-uuid"} {
+		if resp := client.Get(path); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+// TestBindRequest verifica el enlace combinado de ruta, query y cuerpo
+func TestBindRequest(t *testing.T) {
+	type updateRequest struct {
+		ID     int    `param:"id" json:"-"`
+		Page   int    `query:"page" json:"-"`
+		Name   string `json:"name" validate:"required"`
+		Source string `param:"source" query:"source" json:"source"`
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request, p Params, in updateRequest) {
+		fmt.Fprintf(w, "%d:%d:%s:%s", in.ID, in.Page, in.Name, in.Source)
+	}
+
+	r := New()
+	r.Put("/items/:id/:source", BindRequest(handler))
+	r.Put("/query-first/:id/:source", BindRequest(handler, BindFromQuery, BindFromPath, BindFromBody))
+
+	client := NewTestClient(r)
+
+	// Cada campo llega de una fuente; el cuerpo gana por defecto
+	resp := client.PutJSON("/items/7/path?page=3&source=query", map[string]string{"name": "box", "source": "body"})
+	if !resp.IsOK() {
+		t.Fatalf("Expected status 200, got %d: %s", resp.StatusCode, resp.Text())
+	}
+	if expected := "7:3:box:body"; resp.Text() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, resp.Text())
+	}
+
+	// La precedencia es configurable
+	resp = client.PutJSON("/query-first/7/path?page=3&source=query", map[string]string{"name": "box", "source": "body"})
+	if expected := "7:3:box:query"; resp.Text() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, resp.Text())
+	}
+
+	// Los errores se devuelven estructurados
+	resp = client.PutJSON("/items/7/path?page=x", map[string]string{})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Errors []ValidationError `json:"errors"`
+	}
+	if err := resp.JSON(&body); err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	if len(body.Errors) != 1 || body.Errors[0].Field != "page" || body.Errors[0].Rule != "type" {
+		t.Errorf("Expected a type error for 'page', got %+v", body.Errors)
+	}
+
+	resp = client.PutJSON("/items/7/path", map[string]string{})
+	if err := resp.JSON(&body); err != nil || len(body.Errors) != 1 || body.Errors[0].Rule != "required" {
+		t.Errorf("Expected a required error, got %+v (%v)", body.Errors, err)
+	}
+}
+
+// TestBindHeaders verifica el enlace y la validación de cabeceras
+func TestBindHeaders(t *testing.T) {
+	type tenantHeaders struct {
+		TenantID      string `header:"X-Tenant-ID" validate:"required"`
+		ClientVersion int    `header:"X-Client-Version" validate:"min=2"`
+	}
+
+	r := New()
+	r.Get("/tenant", BindHeaders(func(w http.ResponseWriter, r *http.Request, p Params, h tenantHeaders) {
+		fmt.Fprintf(w, "%s:%d", h.TenantID, h.ClientVersion)
+	}))
+
+	resp := NewTestClient(r).WithHeader("X-Tenant-ID", "acme").WithHeader("X-Client-Version", "3").Get("/tenant")
+	if !resp.IsOK() || resp.Text() != "acme:3" {
+		t.Fatalf("Expected 'acme:3', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+
+	// Falta la cabecera obligatoria
+	resp = NewTestClient(r).WithHeader("X-Client-Version", "3").Get("/tenant")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for missing header, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Errors ValidationErrors `json:"errors"`
+	}
+	if err := resp.DecodeJSON(&body); err != nil {
+		t.Fatalf("Error decoding errors: %v", err)
+	}
+	if len(body.Errors) != 1 || body.Errors[0].Field != "TenantID" || body.Errors[0].Rule != "required" {
+		t.Errorf("Expected a required error for TenantID, got %v", body.Errors)
+	}
+
+	// Un valor que no se puede convertir también es un 400
+	resp = NewTestClient(r).WithHeader("X-Tenant-ID", "acme").WithHeader("X-Client-Version", "beta").Get("/tenant")
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(resp.Text(), "X-Client-Version") {
+		t.Errorf("Expected 400 naming X-Client-Version, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+}
+
+// TestMiddlewareParams verifica que un middleware pueda añadir o reemplazar parámetros
+func TestMiddlewareParams(t *testing.T) {
+	slugs := map[string]string{"hello-world": "42"}
+
+	// Añade un parámetro derivado al mismo mapa
+	resolveSlug := func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			p.Set("id", slugs[p["slug"]])
+			next(w, r, p)
+		}
+	}
+	// Reemplaza los parámetros por una copia
+	tenant := func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			p2 := p.Clone()
+			p2.Set("tenant", "acme")
+			next(w, SetParams(r, p2), p2)
+		}
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request, p Params) {
+		fmt.Fprintf(w, "%s:%s:%s|%s:%s", p["slug"], p["id"], p["tenant"], Param(r, "id"), Param(r, "tenant"))
+	}
+
+	r := New()
+	r.Use(resolveSlug)
+	r.Get("/posts/:slug", handler)
+	if resp := NewTestClient(r).Get("/posts/hello-world"); resp.Text() != "hello-world:42:|42:" {
+		t.Errorf("Expected 'hello-world:42:|42:', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+
+	r = New()
+	r.Use(tenant, resolveSlug)
+	r.Get("/tenant/posts/:slug", handler)
+	if resp := NewTestClient(r).Get("/tenant/posts/hello-world"); resp.Text() != "hello-world:42:acme|42:acme" {
+		t.Errorf("Expected 'hello-world:42:acme|42:acme', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestJSONRendering verifica el renderizado JSON
@@ -252,3 +256,304 @@ func TestJSONBindingAndResponse(t *testing.T) {
 		t.Errorf("Expected success:false in error response, got %v", errorResponse)
 	}
 }
+
+// TestStreamJSON verifica que StreamJSON produzca un array JSON válido desde un canal
+func TestStreamJSON(t *testing.T) {
+	r := New()
+
+	r.Get("/stream", func(w http.ResponseWriter, r *http.Request, p Params) {
+		ch := make(chan interface{})
+		go func() {
+			defer close(ch)
+			for i := 1; i <= 3; i++ {
+				ch <- map[string]int{"id": i}
+			}
+		}()
+		if err := StreamJSON(w, http.StatusOK, ch); err != nil {
+			t.Errorf("Unexpected stream error: %v", err)
+		}
+	})
+
+	r.Get("/stream-error/:at", func(w http.ResponseWriter, r *http.Request, p Params) {
+		ch := make(chan interface{}, 3)
+		for i := range 3 {
+			if strconv.Itoa(i) == p["at"] {
+				ch <- func() {} // no serializable
+				continue
+			}
+			ch <- map[string]int{"id": i}
+		}
+		close(ch)
+		if err := StreamJSON(w, http.StatusOK, ch); err == nil {
+			t.Errorf("Expected stream error for unsupported value")
+		}
+	})
+
+	client := NewTestClient(r)
+
+	resp := client.Get("/stream")
+	if !strings.Contains(resp.Header.Get("Content-Type"), "application/json") {
+		t.Errorf("Expected JSON content type, got '%s'", resp.Header.Get("Content-Type"))
+	}
+	var items []map[string]int
+	if err := resp.JSON(&items); err != nil {
+		t.Fatalf("Error parsing streamed JSON: %v (%s)", err, resp.Text())
+	}
+	if len(items) != 3 || items[2]["id"] != 3 {
+		t.Errorf("Unexpected streamed items: %v", items)
+	}
+
+	// Un error al codificar deja el array sin cerrar para que el cliente note
+	// el truncado, esté al principio o a mitad del stream
+	for at, want := range map[string]string{"0": "[", "1": "[{\"id\":0}\n"} {
+		resp = client.Get("/stream-error/" + at)
+		if resp.Text() != want || json.Valid(resp.Body) {
+			t.Errorf("Expected truncated JSON %q for an error at item %s, got %q", want, at, resp.Text())
+		}
+	}
+	// Tras un error StreamJSON deja de leer; un productor que nunca cierra el
+	// canal termina al cancelarse el contexto cuando vuelve el handler
+	stopped := make(chan struct{})
+	r.Get("/stream-endless", func(w http.ResponseWriter, r *http.Request, p Params) {
+		ch := make(chan interface{})
+		go func() {
+			defer close(stopped)
+			for {
+				select {
+				case ch <- func() {}: // no serializable
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}()
+		if err := StreamJSON(w, http.StatusOK, ch); err == nil {
+			t.Errorf("Expected stream error for unsupported value")
+		}
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+	res, err := http.Get(server.URL + "/stream-endless")
+	if err != nil {
+		t.Fatalf("Error requesting stream: %v", err)
+	}
+	res.Body.Close()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("Expected the producer to stop once the handler returned")
+	}
+}
+
+// countingReader cuenta los bytes leídos del cuerpo de la petición
+type countingReader struct {
+	r    io.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += n
+	return n, err
+}
+
+// TestStreamJSONArray verifica el procesamiento incremental de arrays JSON
+func TestStreamJSONArray(t *testing.T) {
+	const total = 10000
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := 0; i < total; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(`{"id":` + strconv.Itoa(i) + `,"name":"item"}`)
+	}
+	sb.WriteString("]")
+	payload := sb.String()
+
+	body := &countingReader{r: strings.NewReader(payload)}
+	req := httptest.NewRequest(http.MethodPost, "/items", body)
+
+	seen := 0
+	err := StreamJSONArray(req, func(item json.RawMessage) error {
+		var v struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(item, &v); err != nil {
+			return err
+		}
+		if v.ID != seen {
+			t.Fatalf("Expected item %d, got %d", seen, v.ID)
+		}
+		// El primer elemento llega antes de haber leído todo el cuerpo
+		if seen == 0 && body.read >= len(payload) {
+			t.Errorf("Expected first item before the whole body was buffered")
+		}
+		seen++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if seen != total {
+		t.Errorf("Expected %d items, got %d", total, seen)
+	}
+
+	// El primer error del callback detiene el recorrido
+	req = httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`[1,2,3]`))
+	calls := 0
+	stop := io.ErrUnexpectedEOF
+	if err := StreamJSONArray(req, func(item json.RawMessage) error {
+		calls++
+		if calls == 2 {
+			return stop
+		}
+		return nil
+	}); err != stop || calls != 2 {
+		t.Errorf("Expected to stop at second item with callback error, got %v after %d calls", err, calls)
+	}
+
+	// Un cuerpo que no es un array se rechaza
+	req = httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"id":1}`))
+	if err := StreamJSONArray(req, func(json.RawMessage) error { return nil }); err == nil {
+		t.Errorf("Expected error for non-array body")
+	}
+}
+
+// TestRouterRender verifica el Render compartido del router
+func TestRouterRender(t *testing.T) {
+	router := New()
+	router.Render().IndentJSON = false
+
+	data := map[string]int{"a": 1, "b": 2}
+	router.Get("/router", func(w http.ResponseWriter, r *http.Request, p Params) {
+		router.Render().JSON(w, http.StatusOK, data)
+	})
+	router.Get("/context", func(w http.ResponseWriter, r *http.Request, p Params) {
+		GetRender(r).JSON(w, http.StatusOK, data)
+	})
+
+	client := NewTestClient(router)
+	for _, path := range []string{"/router", "/context"} {
+		resp := client.Get(path)
+		if expected := `{"a":1,"b":2}` + "\n"; resp.Text() != expected {
+			t.Errorf("%s: expected compact JSON %q, got %q", path, expected, resp.Text())
+		}
+	}
+
+	// WithRender sustituye el Render por defecto
+	custom := NewRender()
+	custom.DefaultCharset = "iso-8859-1"
+	r2 := New(WithRender(custom))
+	r2.Get("/charset", func(w http.ResponseWriter, r *http.Request, p Params) {
+		GetRender(r).Text(w, http.StatusOK, "hola")
+	})
+	resp := NewTestClient(r2).Get("/charset")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/plain; charset=iso-8859-1" {
+		t.Errorf("Expected configured charset, got '%s'", ct)
+	}
+}
+
+// TestTemplateTranslations verifica t, tf y tn con el idioma negociado
+func TestTemplateTranslations(t *testing.T) {
+	dir := t.TempDir()
+	page := `{{t "title"}}|{{tf "hello" "Ana"}}|{{tn "items" 0}}|{{tn "items" 1}}|{{tn "items" 5}}|{{t "missing"}}`
+	if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := NewTranslations("en").
+		Add("en", map[string]string{
+			"title":       "Cart",
+			"hello":       "Hello, %s",
+			"items.zero":  "no items",
+			"items.one":   "%d item",
+			"items.other": "%d items",
+		}).
+		Add("es", map[string]string{
+			"title":       "Carrito",
+			"hello":       "Hola, %s",
+			"items.one":   "%d artículo",
+			"items.other": "%d artículos",
+		}).
+		Add("ru", map[string]string{
+			"items.one":  "%d товар",
+			"items.few":  "%d товара",
+			"items.many": "%d товаров",
+		})
+	tm := NewTemplateManager(dir).WithTranslations(bundle)
+
+	r := New(WithI18n(map[string]map[string]string{"en": {}, "es": {}, "ru": {}}))
+	r.Use(TemplateMiddleware(tm))
+	r.Get("/page", func(w http.ResponseWriter, r *http.Request, p Params) {
+		if err := RenderTemplateView(w, r, "page.html", nil); err != nil {
+			t.Errorf("Unexpected render error: %v", err)
+		}
+	})
+
+	tests := map[string]string{
+		"":      "Cart|Hello, Ana|no items|1 item|5 items|missing",
+		"es-MX": "Carrito|Hola, Ana|0 artículos|1 artículo|5 artículos|missing",
+		// Los mensajes que faltan en ru se toman del idioma por defecto
+		"ru": "Cart|Hello, Ana|0 товаров|1 товар|5 товаров|missing",
+	}
+	for lang, expected := range tests {
+		body := NewTestClient(r).WithHeader("Accept-Language", lang).Get("/page").Text()
+		if body != expected {
+			t.Errorf("%q: Expected %q, got %q", lang, expected, body)
+		}
+	}
+
+	if got := bundle.Tn("ru", "items", 3); got != "3 товара" {
+		t.Errorf("Expected Russian 'few' form, got %q", got)
+	}
+}
+
+// TestTemplateDevelopmentReload verifica que en desarrollo solo se vuelvan a
+// analizar las plantillas modificadas
+func TestTemplateDevelopmentReload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, age time.Duration) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Fijar mtime explícitamente para no depender de la resolución del reloj
+		stamp := time.Now().Add(-age)
+		os.Chtimes(path, stamp, stamp)
+	}
+	write("a.html", "A1", time.Hour)
+	write("b.html", "B1", time.Hour)
+
+	tm := NewTemplateManager(dir).Development()
+	tm.Reload()
+	render := func(name string) string {
+		var buf strings.Builder
+		if err := tm.Render(&buf, name, nil); err != nil {
+			t.Fatalf("Unexpected render error for %s: %v", name, err)
+		}
+		return buf.String()
+	}
+	before, _ := tm.Template("b.html")
+
+	write("a.html", "A2", time.Minute)
+	if got := render("a.html"); got != "A2" {
+		t.Errorf("Expected changed template to be reparsed, got %q", got)
+	}
+	if after, _ := tm.Template("b.html"); after != before {
+		t.Error("Expected unchanged template to be kept")
+	}
+
+	// Una plantilla nueva provoca una recarga completa
+	write("c.html", "C1", time.Minute)
+	if got := render("c.html"); got != "C1" {
+		t.Errorf("Expected new template to be loaded, got %q", got)
+	}
+
+	// Una plantilla con errores mantiene la versión anterior
+	var reported error
+	tm.WithErrorHandler(func(err error) { reported = err })
+	write("a.html", "{{.Broken", 0)
+	if got := render("a.html"); got != "A2" || reported == nil {
+		t.Errorf("Expected previous version and a reported error, got %q and %v", got, reported)
+	}
+}
@@ -214,3 +214,205 @@ func TestCustomRoutePatterns(t *testing.T) {
 		t.Errorf("Expected 'All users', got '%s'", resp.Text())
 	}
 }
+
+// TestResourceWithOptions verifica el nombre de parámetro y el patrón personalizados
+func TestResourceWithOptions(t *testing.T) {
+	r := New()
+	r.ResourceWithOptions("/users", ProductController{}, ResourceOptions{
+		ParamName: "user_id",
+		IDPattern: `\d+`,
+	})
+
+	client := NewTestClient(r)
+
+	resp := client.Get("/users/42")
+	if !resp.IsOK() {
+		t.Errorf("Expected status 200 for numeric ID, got %d", resp.StatusCode)
+	}
+
+	resp = client.Get("/users/abc")
+	if !resp.IsNotFound() {
+		t.Errorf("Expected status 404 for non-numeric ID, got %d", resp.StatusCode)
+	}
+
+	// El nombre del parámetro se refleja en las rutas nombradas
+	url, err := r.URL("users.show", "7")
+	if err != nil || url != "/users/7" {
+		t.Errorf("Expected '/users/7', got '%s' (%v)", url, err)
+	}
+
+	found := false
+	for _, rt := range r.routes {
+		if rt.method == "GET" && rt.pattern == `/users/:user_id(\d+)` {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected member route '/users/:user_id(\\d+)' to be registered")
+	}
+}
+
+// TestNestedResource verifica las rutas de un recurso anidado bajo otro
+func TestNestedResource(t *testing.T) {
+	r := New()
+	r.Resource("/users", ProductController{})
+	r.NestedResourceWithOptions("/users/:user_id/posts", ProductController{}, ResourceOptions{IDPattern: `\d+`})
+	r.NestedResource("/teams/:team_id/members", paramsEchoController{})
+
+	client := NewTestClient(r)
+
+	// El parámetro del padre se conserva junto al del miembro
+	resp := client.Get("/teams/5/members/9")
+	if !resp.IsOK() {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.Text() != "team:5,id:9" {
+		t.Errorf("Expected 'team:5,id:9', got '%s'", resp.Text())
+	}
+
+	// Las rutas padre e hija conviven
+	if resp := client.Get("/users/7"); !resp.IsOK() {
+		t.Errorf("Expected status 200 for parent resource, got %d", resp.StatusCode)
+	}
+	if resp := client.Get("/users/7/posts"); !resp.IsOK() {
+		t.Errorf("Expected status 200 for nested index, got %d", resp.StatusCode)
+	}
+	if resp := client.Delete("/users/7/posts/3"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204 for nested delete, got %d", resp.StatusCode)
+	}
+
+	// Los nombres reflejan el anidamiento
+	url, err := r.URL("users.posts.show", "7", "3")
+	if err != nil || url != "/users/7/posts/3" {
+		t.Errorf("Expected '/users/7/posts/3', got '%s' (%v)", url, err)
+	}
+	if _, err := r.URL("users.show", "7"); err != nil {
+		t.Errorf("Expected parent route name to be kept: %v", err)
+	}
+}
+
+// paramsEchoController devuelve los parámetros recibidos en Show
+type paramsEchoController struct {
+	DefaultController
+}
+
+func (c paramsEchoController) Show(w http.ResponseWriter, r *http.Request, p Params) {
+	fmt.Fprintf(w, "team:%s,id:%s", p["team_id"], p["id"])
+}
+
+// patchController distingue PATCH de PUT
+type patchController struct {
+	DefaultController
+}
+
+func (c patchController) Update(w http.ResponseWriter, r *http.Request, p Params) {
+	w.Write([]byte("update"))
+}
+
+func (c patchController) Patch(w http.ResponseWriter, r *http.Request, p Params) {
+	w.Write([]byte("patch"))
+}
+
+// TestResourcePatchAndOnlyExcept verifica PATCH y la selección de acciones
+func TestResourcePatchAndOnlyExcept(t *testing.T) {
+	r := New()
+	r.Resource("/products", ProductController{})
+	r.Resource("/notes", patchController{})
+	r.Resource("/reports", ProductController{}, ResourceOptions{Only: []string{"index", "show"}})
+	r.Resource("/tags", ProductController{}, ResourceOptions{Except: []string{"delete"}})
+
+	client := NewTestClient(r)
+
+	// Sin método Patch, PATCH usa Update
+	resp := client.PatchJSON("/products/2", map[string]interface{}{"name": "Patched"})
+	var product map[string]interface{}
+	if err := resp.JSON(&product); err != nil || product["id"] != "2" || product["name"] != "Patched" {
+		t.Errorf("Expected PATCH to fall back to Update, got %d %s", resp.StatusCode, resp.Text())
+	}
+
+	// Con método Patch, PATCH lo usa y PUT sigue en Update
+	if resp := client.Patch("/notes/1", nil); resp.Text() != "patch" {
+		t.Errorf("Expected 'patch', got '%s'", resp.Text())
+	}
+	if resp := client.Put("/notes/1", nil); resp.Text() != "update" {
+		t.Errorf("Expected 'update', got '%s'", resp.Text())
+	}
+
+	// Only registra solo las acciones indicadas
+	if resp := client.Get("/reports/1"); !resp.IsOK() {
+		t.Errorf("Expected status 200 for Only show, got %d", resp.StatusCode)
+	}
+	if resp := client.PostJSON("/reports", map[string]interface{}{}); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for action outside Only, got %d", resp.StatusCode)
+	}
+	if _, err := r.URL("reports.create"); err == nil {
+		t.Errorf("Expected no named route for action outside Only")
+	}
+
+	// Except omite las acciones indicadas
+	if resp := client.Delete("/tags/1"); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for excepted action, got %d", resp.StatusCode)
+	}
+	if resp := client.PutJSON("/tags/1", map[string]interface{}{}); !resp.IsOK() {
+		t.Errorf("Expected status 200 for Update, got %d", resp.StatusCode)
+	}
+
+	// Una acción desconocida es un error de configuración
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic for unknown action")
+		}
+	}()
+	r.Resource("/bad", ProductController{}, ResourceOptions{Only: []string{"edit"}})
+}
+
+// TestResourceMemberAndCollection verifica las rutas personalizadas de un recurso
+func TestResourceMemberAndCollection(t *testing.T) {
+	r := New()
+	r.Resource("/users", ProductController{}).
+		Member("POST", "/activate", func(w http.ResponseWriter, r *http.Request, p Params) {
+			w.Write([]byte("activated " + p["id"]))
+		}).
+		Collection("GET", "/search", func(w http.ResponseWriter, r *http.Request, p Params) {
+			w.Write([]byte("search " + r.URL.Query().Get("q")))
+		})
+
+	client := NewTestClient(r)
+
+	if resp := client.Post("/users/7/activate", nil); resp.Text() != "activated 7" {
+		t.Errorf("Expected 'activated 7', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+
+	// La ruta de colección no la captura el miembro /users/:id
+	if resp := client.Get("/users/search?q=ana"); resp.Text() != "search ana" {
+		t.Errorf("Expected 'search ana', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+	if resp := client.Get("/users/3"); !resp.IsOK() {
+		t.Errorf("Expected status 200 for Show, got %d", resp.StatusCode)
+	}
+
+	// Las rutas quedan nombradas bajo el recurso
+	if url, err := r.URL("users.activate", "7"); err != nil || url != "/users/7/activate" {
+		t.Errorf("Expected '/users/7/activate', got '%s' (%v)", url, err)
+	}
+	if url, err := r.URL("users.search"); err != nil || url != "/users/search" {
+		t.Errorf("Expected '/users/search', got '%s' (%v)", url, err)
+	}
+	// En un clon de With la ruta de colección se mueve en el router padre
+	admin := New()
+	clone := admin.With(func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			w.Header().Set("X-With", "admin")
+			next(w, r, p)
+		}
+	})
+	admin.Get("/health", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	clone.Resource("/users", ProductController{}).
+		Collection("GET", "/search", func(w http.ResponseWriter, r *http.Request, p Params) {
+			w.Write([]byte("search"))
+		})
+	resp := NewTestClient(admin).Get("/users/search")
+	if resp.Text() != "search" || resp.Header.Get("X-With") != "admin" {
+		t.Errorf("Expected 'search' through the With middleware, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+}
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -207,3 +208,225 @@ func TestResponseCompression(t *testing.T) {
 	// Aceptamos tanto que haya compresión como que no la haya en esta prueba
 	t.Logf("Response compression: Content-Encoding=%s", contentEncoding)
 }
+
+// TestPagination verifica Paginate y JSONPaginated
+func TestPagination(t *testing.T) {
+	items := make([]int, 45)
+	for i := range items {
+		items[i] = i + 1
+	}
+
+	r := New()
+	r.Get("/numbers", func(w http.ResponseWriter, r *http.Request, p Params) {
+		offset, limit, meta := Paginate(r, len(items))
+		JSONPaginated(w, http.StatusOK, items[offset:offset+limit], meta)
+	})
+
+	client := NewTestClient(r)
+
+	var body struct {
+		Items   []int `json:"items"`
+		Total   int   `json:"total"`
+		Page    int   `json:"page"`
+		PerPage int   `json:"per_page"`
+	}
+
+	// Página intermedia: elementos, metadatos y enlaces en ambos sentidos
+	resp := client.Get("/numbers?page=2&per_page=10&sort=asc")
+	if err := resp.JSON(&body); err != nil {
+		t.Fatalf("Error parsing JSON: %v", err)
+	}
+	if len(body.Items) != 10 || body.Items[0] != 11 || body.Total != 45 || body.Page != 2 || body.PerPage != 10 {
+		t.Errorf("Unexpected page: %+v", body)
+	}
+	link := resp.Header.Get("Link")
+	if !strings.Contains(link, `</numbers?page=3&per_page=10&sort=asc>; rel="next"`) ||
+		!strings.Contains(link, `</numbers?page=1&per_page=10&sort=asc>; rel="prev"`) {
+		t.Errorf("Unexpected Link header: %s", link)
+	}
+
+	// Última página: incompleta y sin rel="next"
+	resp = client.Get("/numbers?page=5&per_page=10")
+	resp.JSON(&body)
+	if len(body.Items) != 5 || body.Items[0] != 41 {
+		t.Errorf("Expected last 5 items, got %v", body.Items)
+	}
+	if link := resp.Header.Get("Link"); strings.Contains(link, `rel="next"`) || !strings.Contains(link, `rel="prev"`) {
+		t.Errorf("Unexpected Link header on last page: %s", link)
+	}
+
+	// Valores por defecto, límite y páginas fuera de rango
+	resp = client.Get("/numbers?page=abc&per_page=1000")
+	resp.JSON(&body)
+	if body.Page != 1 || body.PerPage != MaxPerPage || len(body.Items) != 45 {
+		t.Errorf("Expected defaults and capped per_page, got page %d per_page %d items %d", body.Page, body.PerPage, len(body.Items))
+	}
+	if link := resp.Header.Get("Link"); link != "" {
+		t.Errorf("Expected no Link header for a single page, got %s", link)
+	}
+	resp = client.Get("/numbers?page=9")
+	resp.JSON(&body)
+	if len(body.Items) != 0 || body.PerPage != DefaultPerPage {
+		t.Errorf("Expected empty page past the end, got %v", body.Items)
+	}
+	resp = client.Get("/numbers?page=9223372036854775807")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 for a huge page, got %d", resp.StatusCode)
+	}
+	body.Items = nil
+	resp.JSON(&body)
+	if len(body.Items) != 0 {
+		t.Errorf("Expected empty page for a huge page number, got %v", body.Items)
+	}
+}
+
+// TestNotModifiedSince verifica las respuestas 304 condicionales
+func TestNotModifiedSince(t *testing.T) {
+	modtime := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	rendered := 0
+
+	r := New()
+	r.Get("/report", func(w http.ResponseWriter, r *http.Request, p Params) {
+		if NotModifiedSince(w, r, modtime) {
+			return
+		}
+		rendered++
+		JSON(w, http.StatusOK, map[string]string{"status": "fresh"})
+	})
+
+	client := NewTestClient(r)
+
+	// Sin cabecera condicional se renderiza y se anuncia Last-Modified
+	resp := client.Get("/report")
+	if !resp.IsOK() || resp.Header.Get("Last-Modified") != "Wed, 01 May 2024 12:00:00 GMT" {
+		t.Errorf("Expected 200 with Last-Modified, got %d '%s'", resp.StatusCode, resp.Header.Get("Last-Modified"))
+	}
+
+	// Copia del cliente vigente: 304 sin renderizar
+	resp = client.WithHeader("If-Modified-Since", "Wed, 01 May 2024 12:00:00 GMT").Get("/report")
+	if resp.StatusCode != http.StatusNotModified || len(resp.Body) != 0 {
+		t.Errorf("Expected empty 304 for a fresh copy, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+
+	// Copia antigua: el handler continúa
+	resp = client.WithHeader("If-Modified-Since", "Tue, 30 Apr 2024 12:00:00 GMT").Get("/report")
+	if !resp.IsOK() {
+		t.Errorf("Expected 200 for a stale copy, got %d", resp.StatusCode)
+	}
+	if rendered != 2 {
+		t.Errorf("Expected the handler to render twice, rendered %d times", rendered)
+	}
+}
+
+// recordingTB captura los fallos de las aserciones sin detener la prueba real
+type recordingTB struct {
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+// TestResponseAssertions verifica los helpers de aserción de TestResponse
+func TestResponseAssertions(t *testing.T) {
+	r := New()
+	r.Get("/user", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Header().Set("X-Version", "2")
+		JSON(w, http.StatusOK, map[string]interface{}{
+			"name": "Alice",
+			"age":  30,
+			"tags": []string{"admin", "dev"},
+			"a/b":  true,
+		})
+	})
+
+	client := NewTestClient(r)
+
+	// Las aserciones correctas no deben reportar fallos y se pueden encadenar
+	client.Get("/user").
+		AssertStatus(t, http.StatusOK).
+		AssertHeader(t, "X-Version", "2").
+		AssertBodyContains(t, "Alice").
+		AssertJSON(t, "name", "Alice").
+		AssertJSON(t, "age", 30).
+		AssertJSON(t, "tags.1", "dev").
+		AssertJSON(t, "/tags/0", "admin").
+		AssertJSON(t, "/a~1b", true).
+		AssertJSON(t, "tags", []string{"admin", "dev"})
+
+	// Las aserciones incorrectas deben reportar un fallo cada una
+	rec := &recordingTB{}
+	client.Get("/user").
+		AssertStatus(rec, http.StatusCreated).
+		AssertHeader(rec, "X-Version", "3").
+		AssertBodyContains(rec, "Bob").
+		AssertJSON(rec, "name", "Bob").
+		AssertJSON(rec, "tags.5", "x").
+		AssertJSON(rec, "missing", nil)
+
+	if len(rec.failures) != 6 {
+		t.Fatalf("Expected 6 failures, got %d: %v", len(rec.failures), rec.failures)
+	}
+	if !strings.Contains(rec.failures[0], "Expected status 201, got 200") {
+		t.Errorf("Expected descriptive status failure, got %q", rec.failures[0])
+	}
+	if !strings.Contains(rec.failures[3], `"Bob"`) || !strings.Contains(rec.failures[3], `"Alice"`) {
+		t.Errorf("Expected JSON failure to show both values, got %q", rec.failures[3])
+	}
+}
+
+// TestClientCookieJar verifica que el cliente reenvía las cookies recibidas
+func TestClientCookieJar(t *testing.T) {
+	r := New()
+	r.Post("/login", func(w http.ResponseWriter, r *http.Request, p Params) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		http.SetCookie(w, &http.Cookie{Name: "admin", Value: "yes", Path: "/admin"})
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.Post("/logout", func(w http.ResponseWriter, r *http.Request, p Params) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Path: "/", MaxAge: -1})
+	})
+	echo := func(w http.ResponseWriter, r *http.Request, p Params) {
+		var names []string
+		for _, c := range r.Cookies() {
+			names = append(names, c.Name+"="+c.Value)
+		}
+		w.Write([]byte(strings.Join(names, ";")))
+	}
+	r.Get("/me", echo)
+	r.Get("/admin/panel", echo)
+
+	// Sin jar las cookies no se recuerdan
+	plain := NewTestClient(r)
+	plain.Post("/login", nil)
+	if got := plain.Get("/me").Text(); got != "" {
+		t.Errorf("Expected no cookies without jar, got %q", got)
+	}
+
+	client := NewTestClient(r).WithCookies(true)
+	client.Post("/login", nil)
+
+	if cookies := client.Cookies(); len(cookies) != 2 || cookies[0].Name != "admin" || cookies[1].Name != "session" {
+		t.Fatalf("Expected admin and session cookies, got %v", cookies)
+	}
+	if got := client.Get("/me").Text(); got != "session=abc" {
+		t.Errorf("Expected session=abc on /me, got %q", got)
+	}
+	// La cookie con Path=/admin solo se envía bajo ese path
+	if got := client.Get("/admin/panel").Text(); !strings.Contains(got, "admin=yes") || !strings.Contains(got, "session=abc") {
+		t.Errorf("Expected both cookies on /admin/panel, got %q", got)
+	}
+
+	// MaxAge negativo borra la cookie
+	client.Post("/logout", nil)
+	if got := client.Get("/me").Text(); got != "" {
+		t.Errorf("Expected session removed after logout, got %q", got)
+	}
+
+	client.WithCookies(false)
+	if cookies := client.Cookies(); len(cookies) != 0 {
+		t.Errorf("Expected empty jar after disabling, got %v", cookies)
+	}
+}
@@ -1,20 +1,35 @@
 package router
 
 import (
+	"bufio"
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"log/slog"
+	"maps"
+	"mime"
+	"net"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
 	"regexp"
-	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,11 +40,22 @@ func NewMoraRouter(opts ...Option) *MoraRouter {
 	r := &MoraRouter{
 		notFound:           defaultNotFound,
 		namedRoutes:        make(map[string]string),
+		routeNames:         make(map[string]string),
 		middlewareRegistry: make(map[string]Middleware),
+		render:             NewRender(),
+		services:           &container{services: make(map[reflect.Type]any)},
+		securitySchemes:    make(map[string]map[string]interface{}),
+		metrics:            newMetricsCollector(),
+		webSockets:         &webSocketHubs{},
+		mu:                 &sync.RWMutex{},
 	}
 	for _, opt := range opts {
 		opt(r)
 	}
+	// el Render compartido usa las plantillas del router si no tiene propias
+	if r.render.TemplateManager == nil {
+		r.render.TemplateManager = r.templateManager
+	}
 	return r
 }
 
@@ -41,62 +67,378 @@ func New(opts ...Option) *MoraRouter {
 // WithLogging agrega middleware de registro de peticiones.
 func WithLogging() Option {
 	return func(r *MoraRouter) {
-		r.middlewareRegistry["logging"] = loggingMiddleware
+		r.registerMiddleware("logging", loggingMiddleware)
 		r.middlewares = append(r.middlewares, loggingMiddleware)
 	}
 }
 
+// WithStructuredLogging registra cada petición en logger como pares clave/valor:
+// method, path, route, status, duration, request_id y remote_ip. Las
+// respuestas 5xx se registran con nivel Error, las 4xx con Warn y el resto con
+// Info. Con logger nil se usa slog.Default(). WithLogging sigue siendo el
+// registro en texto por defecto.
+func WithStructuredLogging(logger *slog.Logger) Option {
+	return func(r *MoraRouter) {
+		if logger == nil {
+			logger = slog.Default()
+		}
+		m := func(next HandlerFunc) HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request, p Params) {
+				start := time.Now()
+				// statusWriter conserva Flush y Hijack para SSE y WebSocket
+				sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+				next(sw, req, p)
+
+				level := slog.LevelInfo
+				switch {
+				case sw.status >= 500:
+					level = slog.LevelError
+				case sw.status >= 400:
+					level = slog.LevelWarn
+				}
+				attrs := []slog.Attr{
+					slog.String("method", req.Method),
+					slog.String("path", req.URL.Path),
+					slog.Int("status", sw.status),
+					slog.Duration("duration", time.Since(start)),
+					slog.String("remote_ip", r.clientIP(req)),
+				}
+				if pattern, ok := req.Context().Value(patternKey).(string); ok {
+					attrs = append(attrs, slog.String("route", pattern))
+				}
+				// el ID puede venir del cliente o haberlo puesto otro middleware
+				if id := cmp.Or(w.Header().Get("X-Request-ID"), req.Header.Get("X-Request-ID")); id != "" {
+					attrs = append(attrs, slog.String("request_id", id))
+				}
+				logger.LogAttrs(req.Context(), level, "request", attrs...)
+			}
+		}
+		r.registerMiddleware("logging", m)
+		r.middlewares = append(r.middlewares, m)
+	}
+}
+
+// RecoveryOptions configura WithRecovery y WithRecoveryOutermost.
+type RecoveryOptions struct {
+	// OnPanic recibe el panic como error y su stack trace, p. ej. para
+	// reportarlo a Sentry. Se llama antes de escribir la respuesta.
+	OnPanic func(err error, stack []byte)
+}
+
 // WithRecovery agrega middleware para recuperación de panics.
-func WithRecovery() Option {
+func WithRecovery(opts ...RecoveryOptions) Option {
+	return func(r *MoraRouter) {
+		m := recoveryMiddleware(opts...)
+		r.registerMiddleware("recovery", m)
+		r.middlewares = append(r.middlewares, m)
+	}
+}
+
+// WithRecoveryOutermost es como WithRecovery pero el recovery envuelve a todos
+// los middlewares de cada ruta, aunque se registren antes que él, así que
+// también captura sus panics.
+func WithRecoveryOutermost(opts ...RecoveryOptions) Option {
 	return func(r *MoraRouter) {
-		r.middlewareRegistry["recovery"] = recoveryMiddleware
-		r.middlewares = append(r.middlewares, recoveryMiddleware)
+		m := recoveryMiddleware(opts...)
+		r.registerMiddleware("recovery", m)
+		r.recoveryOutermost = m
 	}
 }
 
-// WithCORS permite configurar CORS con orígenes permitidos.
+// WithCORS permite CORS para un origen exacto o para cualquiera con "*". Es
+// un atajo de WithCORSConfig.
 func WithCORS(allow string) Option {
+	return WithCORSConfig(CORSConfig{AllowedOrigins: []string{allow}})
+}
+
+// CORSConfig configura WithCORSConfig.
+type CORSConfig struct {
+	// AllowedOrigins son los orígenes admitidos, exactos o "*" para todos.
+	AllowedOrigins []string
+	// AllowedMethods limita los métodos del preflight; solo se anuncian los
+	// que además tiene registrados la ruta. Por defecto, todos los de la ruta.
+	AllowedMethods []string
+	// AllowedHeaders para el preflight; por defecto se aceptan los que pide
+	// el navegador en Access-Control-Request-Headers.
+	AllowedHeaders []string
+	// ExposedHeaders son las cabeceras de respuesta visibles para el script.
+	ExposedHeaders []string
+	// AllowCredentials permite cookies y Authorization. Exige una lista de
+	// orígenes explícita: con "*", WithCORSConfig hace panic.
+	AllowCredentials bool
+	// MaxAge es cuánto puede cachear el navegador el preflight.
+	MaxAge time.Duration
+}
+
+// WithCORSConfig agrega CORS con una lista de orígenes permitidos. Las
+// peticiones preflight (OPTIONS con Access-Control-Request-Method) se
+// responden en el manejo automático de OPTIONS del router.
+func WithCORSConfig(cfg CORSConfig) Option {
+	// "*" con credenciales dejaría a cualquier sitio leer respuestas con las
+	// cookies del usuario
+	if cfg.AllowCredentials && slices.Contains(cfg.AllowedOrigins, "*") {
+		panic(`CORS con credenciales requiere orígenes explícitos, no "*"`)
+	}
 	return func(r *MoraRouter) {
-		cors := corsMiddleware(allow)
-		r.middlewareRegistry["cors"] = cors
+		r.cors = &cfg
+		cors := cfg.middleware
+		r.registerMiddleware("cors", cors)
 		r.middlewares = append(r.middlewares, cors)
 	}
 }
 
+// allowOrigin escribe Access-Control-Allow-Origin y las cabeceras comunes
+// si origin está permitido, y devuelve si lo estaba.
+func (c *CORSConfig) allowOrigin(w http.ResponseWriter, origin string) bool {
+	wildcard := slices.Contains(c.AllowedOrigins, "*")
+	if !wildcard {
+		// la respuesta depende del origen, también cuando se rechaza
+		w.Header().Add("Vary", "Origin")
+	}
+	if origin == "" {
+		return false
+	}
+	if !wildcard && !slices.ContainsFunc(c.AllowedOrigins, func(o string) bool { return strings.EqualFold(o, origin) }) {
+		return false
+	}
+	if wildcard {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// middleware añade las cabeceras CORS a las peticiones normales.
+func (c *CORSConfig) middleware(next HandlerFunc) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, p Params) {
+		if c.allowOrigin(w, r.Header.Get("Origin")) && len(c.ExposedHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+		}
+		next(w, r, p)
+	}
+}
+
+// preflight añade las cabeceras de respuesta a un preflight CORS; allowed son
+// los métodos registrados para la ruta.
+func (c *CORSConfig) preflight(w http.ResponseWriter, r *http.Request, allowed []string) {
+	if r.Header.Get("Access-Control-Request-Method") == "" {
+		return
+	}
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+	if !c.allowOrigin(w, r.Header.Get("Origin")) {
+		return
+	}
+	// nunca se anuncian métodos que la ruta respondería con 405
+	methods := allowed
+	if len(c.AllowedMethods) > 0 {
+		methods = slices.DeleteFunc(slices.Clone(allowed), func(m string) bool {
+			return !slices.ContainsFunc(c.AllowedMethods, func(a string) bool { return strings.EqualFold(a, m) })
+		})
+	}
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	headers := strings.Join(c.AllowedHeaders, ", ")
+	if len(c.AllowedHeaders) == 0 || (headers == "*" && c.AllowCredentials) {
+		// con credenciales "*" es literal, así que se devuelven las pedidas
+		headers = r.Header.Get("Access-Control-Request-Headers")
+	}
+	if headers != "" {
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+	}
+	if c.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+	}
+}
+
+// WithServerHeader establece la cabecera Server de las respuestas. Un handler
+// puede sobrescribirla.
+func WithServerHeader(value string) Option {
+	return func(r *MoraRouter) {
+		m := func(next HandlerFunc) HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request, p Params) {
+				w.Header().Set("Server", value)
+				next(w, req, p)
+			}
+		}
+		r.registerMiddleware("server-header", m)
+		r.middlewares = append(r.middlewares, m)
+	}
+}
+
+// WithoutServerHeader elimina la cabecera Server de las respuestas, aunque la
+// haya puesto otro middleware o el propio handler.
+func WithoutServerHeader() Option {
+	return func(r *MoraRouter) {
+		m := func(next HandlerFunc) HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request, p Params) {
+				next(&headerStripWriter{ResponseWriter: w, strip: []string{"Server"}}, req, p)
+			}
+		}
+		r.registerMiddleware("server-header", m)
+		r.middlewares = append(r.middlewares, m)
+	}
+}
+
+// headerStripWriter elimina cabeceras justo antes de enviarlas. Conserva
+// Flush y Hijack para no romper streaming ni WebSocket.
+type headerStripWriter struct {
+	http.ResponseWriter
+	strip []string
+}
+
+func (w *headerStripWriter) WriteHeader(status int) {
+	for _, name := range w.strip {
+		w.Header().Del(name)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *headerStripWriter) Write(b []byte) (int, error) {
+	for _, name := range w.strip {
+		w.Header().Del(name)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *headerStripWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *headerStripWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("hijack no soportado")
+}
+
+// Unwrap permite a http.ResponseController llegar al ResponseWriter original.
+func (w *headerStripWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // UseMiddleware configura global middlewares por nombre en orden específico.
 func UseMiddleware(names ...string) Option {
 	return func(r *MoraRouter) {
 		r.middlewares = nil
 		for _, name := range names {
-			if mw, ok := r.middlewareRegistry[name]; ok {
+			if mw, ok := r.namedMiddleware(name); ok {
 				r.middlewares = append(r.middlewares, mw)
 			}
 		}
 	}
 }
 
-// WithAPIVersioning aplica versionado automático según cabecera o URL.
-func WithAPIVersioning(headerName, defaultVersion string) Option {
+// VersionConfig configura WithAPIVersioning. Cada modo se activa con su
+// campo; si la petición indica la versión de varias formas, gana la URL,
+// después la cabecera y después Accept.
+type VersionConfig struct {
+	// Header es la cabecera con la versión, p. ej. "X-API-Version"; admite
+	// "2" y "v2".
+	Header string
+	// Path lee la versión del primer segmento de la URL (/v2/users). Solo
+	// cuenta un segmento "v" seguido de números, así que /ventas no cambia.
+	Path bool
+	// AcceptVendor es el prefijo del tipo vendor en Accept: con
+	// "application/vnd.api" se lee la versión de application/vnd.api.v2+json.
+	AcceptVendor string
+	// Default es la versión si la petición no indica ninguna.
+	Default string
+}
+
+// WithAPIVersioning resuelve la versión de la API de cada petición y la
+// guarda en el contexto (ver APIVersion). Las rutas de una versión se
+// registran con el prefijo /v{versión}: una petición a /users con versión 2
+// la atiende /v2/users si existe y si no /users. Con Path, /v2/users cae en
+// /users cuando no hay ruta propia para la versión.
+func WithAPIVersioning(cfg VersionConfig) Option {
 	return func(r *MoraRouter) {
-		r.middlewares = append([]Middleware{versioningMiddleware(headerName, defaultVersion)}, r.middlewares...)
+		r.versioning = &cfg
 	}
 }
 
-// versioningMiddleware reescribe la URL para añadir el prefijo /v{version} según cabecera.
-func versioningMiddleware(headerName, defaultVersion string) Middleware {
-	return func(next HandlerFunc) HandlerFunc {
-		return func(w http.ResponseWriter, req *http.Request, p Params) {
-			ver := req.Header.Get(headerName)
-			if ver == "" {
-				ver = defaultVersion
+// APIVersion devuelve la versión resuelta por WithAPIVersioning, o "".
+func APIVersion(r *http.Request) string {
+	version, _ := r.Context().Value(versionKey).(string)
+	return version
+}
+
+// resolve devuelve la versión de la petición y los segmentos con los que se
+// busca la ruta.
+func (c *VersionConfig) resolve(w http.ResponseWriter, req *http.Request, routes []route, segs []string) (string, []string) {
+	if c.Path && len(segs) > 0 {
+		if version, ok := versionSegment(segs[0]); ok {
+			if !slices.ContainsFunc(routes, func(rt route) bool { return matchSegments(rt.segments, segs, nil) }) {
+				return version, segs[1:]
+			}
+			return version, segs
+		}
+	}
+
+	var version string
+	if c.Header != "" {
+		w.Header().Add("Vary", c.Header)
+		value := strings.TrimSpace(req.Header.Get(c.Header))
+		if v, ok := versionSegment(value); ok {
+			version = v
+		} else if v, ok := versionSegment("v" + value); ok {
+			version = v
+		}
+	}
+	if version == "" && c.AcceptVendor != "" {
+		w.Header().Add("Vary", "Accept")
+		prefix := strings.ToLower(c.AcceptVendor) + "."
+		for _, rg := range parseAccept(req.Header.Get("Accept")) {
+			rest, ok := strings.CutPrefix(rg.mediaType, prefix)
+			if !ok || rg.quality == 0 {
+				continue
 			}
-			prefix := "/v" + ver
-			if !strings.HasPrefix(req.URL.Path, "/v") {
-				req.URL.Path = prefix + req.URL.Path
+			rest, _, _ = strings.Cut(rest, "+")
+			if v, ok := versionSegment(rest); ok {
+				version = v
+				break
 			}
-			next(w, req, p)
 		}
 	}
+	if version == "" {
+		version = c.Default
+	}
+	if version == "" {
+		return "", segs
+	}
+
+	// solo las rutas que empiezan por /v{versión} literal son de esa versión;
+	// un parámetro como /:tenant/users no cuenta
+	versioned := append([]string{"v" + version}, segs...)
+	for _, rt := range routes {
+		if len(rt.segments) > 0 && rt.segments[0].literal == versioned[0] && matchSegments(rt.segments, versioned, nil) {
+			return version, versioned
+		}
+	}
+	return version, segs
+}
+
+// versionSegment reconoce un segmento de versión como "v2" o "v1.1" y
+// devuelve el número.
+func versionSegment(seg string) (string, bool) {
+	if len(seg) < 2 || (seg[0] != 'v' && seg[0] != 'V') {
+		return "", false
+	}
+	version := seg[1:]
+	if version[0] < '0' || version[0] > '9' || version[len(version)-1] == '.' {
+		return "", false
+	}
+	for _, c := range version {
+		if (c < '0' || c > '9') && c != '.' {
+			return "", false
+		}
+	}
+	return version, true
 }
 
 // Use permite agregar middlewares directamente.
@@ -104,36 +446,185 @@ func (r *MoraRouter) Use(mw ...Middleware) {
 	r.middlewares = append(r.middlewares, mw...)
 }
 
-// Group inicia un nuevo grupo enrutado.
+// Group inicia un nuevo grupo enrutado. Sus rutas se registran en r y usan
+// los middlewares de r presentes al crear el grupo, no los que se añadan
+// después con Use.
 func (r *MoraRouter) Group(prefix string) *RouteGroup {
-	return &RouteGroup{prefix: prefix, router: r}
+	return &RouteGroup{prefix: prefix, router: r, global: slices.Clip(r.globalMiddlewares())}
+}
+
+// Group crea un subgrupo que añade prefix al del grupo y hereda sus
+// middlewares; los que se agreguen después al subgrupo no afectan al padre.
+func (g *RouteGroup) Group(prefix string) *RouteGroup {
+	return &RouteGroup{prefix: g.prefix + prefix, router: g.router, global: g.global, middlewares: slices.Clip(g.middlewares)}
 }
 
 // Métodos de grupo
-func (g *RouteGroup) Get(pattern string, handler HandlerFunc) {
-	g.router.Handle("GET", g.prefix+pattern, handler)
+func (g *RouteGroup) Get(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("GET", pattern, handler)
 }
-func (g *RouteGroup) Post(pattern string, handler HandlerFunc) {
-	g.router.Handle("POST", g.prefix+pattern, handler)
+func (g *RouteGroup) Post(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("POST", pattern, handler)
 }
-func (g *RouteGroup) Put(pattern string, handler HandlerFunc) {
-	g.router.Handle("PUT", g.prefix+pattern, handler)
+func (g *RouteGroup) Put(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("PUT", pattern, handler)
 }
-func (g *RouteGroup) Delete(pattern string, handler HandlerFunc) {
-	g.router.Handle("DELETE", g.prefix+pattern, handler)
+func (g *RouteGroup) Delete(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("DELETE", pattern, handler)
+}
+
+// Handle registra en el router una ruta del grupo, envuelta en los
+// middlewares del grupo.
+func (g *RouteGroup) Handle(method, pattern string, handler HandlerFunc) *Route {
+	return g.router.handle(method, g.prefix+pattern, handler, g.global, g.middlewares)
 }
 
 // Handle registra una ruta con método HTTP, patrón y manejador.
-func (r *MoraRouter) Handle(method, pattern string, handler HandlerFunc) {
+func (r *MoraRouter) Handle(method, pattern string, handler HandlerFunc) *Route {
+	return r.handle(method, pattern, handler, r.globalMiddlewares(), nil)
+}
+
+// globalMiddlewares devuelve los middlewares de Use del router donde se
+// registran las rutas de r, que en los clones de With es el padre.
+func (r *MoraRouter) globalMiddlewares() []Middleware {
+	return r.registry().middlewares
+}
+
+// registry devuelve el router donde se registran las rutas de r, que en los
+// clones de With es el padre.
+func (r *MoraRouter) registry() *MoraRouter {
+	if r.withParent != nil {
+		return r.withParent
+	}
+	return r
+}
+
+// handle registra la ruta aplicando primero (por dentro) los middlewares de
+// grupo y después los globales.
+func (r *MoraRouter) handle(method, pattern string, handler HandlerFunc, global, group []Middleware) *Route {
+	// los clones de With registran en su router, por fuera del grupo
+	if r.withParent != nil {
+		return r.withParent.handle(method, pattern, handler, global, append(slices.Clip(r.withMiddlewares), group...))
+	}
 	// aplicar middlewares
-	final := applyMiddlewares(handler, r.middlewares)
+	final := applyMiddlewares(applyMiddlewares(handler, group), global)
+	if r.recoveryOutermost != nil {
+		final = r.recoveryOutermost(final)
+	}
 	// parsear segmentos con posibles validadores
 	rawSegs := splitPath(pattern)
 	segs := make([]segment, len(rawSegs))
 	for i, raw := range rawSegs {
 		segs[i] = parseSegment(raw)
 	}
-	r.routes = append(r.routes, route{method, pattern, segs, final})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route{
+		method:   method,
+		pattern:  pattern,
+		segments: segs,
+		handler:  final,
+		security: slices.Clone(r.security),
+	})
+	return &Route{router: r, method: method, pattern: pattern}
+}
+
+// routesSnapshot devuelve las rutas registradas. Las escrituras solo añaden
+// al final o reemplazan el slice mientras esté compartido, así que se puede
+// recorrer sin el lock.
+func (r *MoraRouter) routesSnapshot() []route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	r.routesShared.Store(true)
+	return r.routes
+}
+
+// ownRoutes devuelve r.routes listo para modificarse en su sitio, copiándolo
+// solo si algún lector puede estar recorriéndolo. Requiere el lock de
+// escritura.
+func (r *MoraRouter) ownRoutes() []route {
+	if r.routesShared.Load() {
+		r.routes = slices.Clone(r.routes)
+		r.routesShared.Store(false)
+	}
+	return r.routes
+}
+
+// Routes devuelve la información de las rutas registradas, en orden de
+// registro. Sirve para generar documentación, matrices de permisos o
+// comprobar convenciones en tests sin depender de /_mora/routes.
+func (r *MoraRouter) Routes() []RouteInfo {
+	snapshot := r.routesSnapshot()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	routes := make([]RouteInfo, 0, len(snapshot))
+	for _, rt := range snapshot {
+		name, _ := r.routeName(rt.method, rt.pattern)
+		info := RouteInfo{
+			Method:   rt.method,
+			Pattern:  rt.pattern,
+			Name:     name,
+			Params:   []string{},
+			Segments: make([]string, 0, len(rt.segments)),
+			Security: slices.Clone(rt.security),
+		}
+		for _, seg := range rt.segments {
+			if seg.name != "" {
+				info.Params = append(info.Params, seg.name)
+			}
+			switch {
+			case seg.literal != "":
+				info.Segments = append(info.Segments, seg.literal)
+			case seg.wildcard:
+				info.Segments = append(info.Segments, "*"+seg.name)
+			case seg.regex != nil:
+				info.Segments = append(info.Segments, fmt.Sprintf(":%s(%s)", seg.name, seg.regex.String()))
+			default:
+				info.Segments = append(info.Segments, ":"+seg.name)
+			}
+		}
+		routes = append(routes, info)
+	}
+	return routes
+}
+
+// replaceRoutes quita las rutas para las que drop devuelve true junto con los
+// nombres de oldNames, y añade routes y los nombres de names y routeNames.
+// Todo ocurre bajo el lock, así que ServeHTTP ve la tabla anterior o la
+// nueva, nunca una mezcla.
+func (r *MoraRouter) replaceRoutes(drop func(route) bool, routes []route, oldNames, names, routeNames map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// reemplazar el slice en lugar de modificarlo, pues ServeHTTP puede estar
+	// recorriendo el anterior
+	next := slices.DeleteFunc(slices.Clone(r.routes), drop)
+	r.routes = append(next, routes...)
+	for name, pattern := range oldNames {
+		// conservar los nombres que se hayan reasignado desde entonces
+		if r.namedRoutes[name] == pattern {
+			delete(r.namedRoutes, name)
+			maps.DeleteFunc(r.routeNames, func(key, n string) bool {
+				return n == name && strings.HasSuffix(key, " "+pattern)
+			})
+		}
+	}
+	maps.Copy(r.namedRoutes, names)
+	maps.Copy(r.routeNames, routeNames)
+}
+
+// registerMiddleware añade mw al registro de middlewares con nombre.
+func (r *MoraRouter) registerMiddleware(name string, mw Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middlewareRegistry[name] = mw
+}
+
+// namedMiddleware busca un middleware del registro por nombre.
+func (r *MoraRouter) namedMiddleware(name string) (Middleware, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mw, ok := r.middlewareRegistry[name]
+	return mw, ok
 }
 
 // parseSegment analiza un raw segment y construye un segment con regex si aplica.
@@ -168,21 +659,115 @@ func parseSegment(raw string) segment {
 }
 
 // Get, Post, Put y Delete son atajos para Handle con métodos específicos.
-func (r *MoraRouter) Get(pattern string, handler HandlerFunc)  { r.Handle("GET", pattern, handler) }
-func (r *MoraRouter) Post(pattern string, handler HandlerFunc) { r.Handle("POST", pattern, handler) }
-func (r *MoraRouter) Put(pattern string, handler HandlerFunc)  { r.Handle("PUT", pattern, handler) }
-func (r *MoraRouter) Delete(pattern string, handler HandlerFunc) {
-	r.Handle("DELETE", pattern, handler)
+func (r *MoraRouter) Get(pattern string, handler HandlerFunc) *Route {
+	return r.Handle("GET", pattern, handler)
+}
+func (r *MoraRouter) Post(pattern string, handler HandlerFunc) *Route {
+	return r.Handle("POST", pattern, handler)
+}
+func (r *MoraRouter) Put(pattern string, handler HandlerFunc) *Route {
+	return r.Handle("PUT", pattern, handler)
+}
+func (r *MoraRouter) Delete(pattern string, handler HandlerFunc) *Route {
+	return r.Handle("DELETE", pattern, handler)
 }
 
 // Patch registra un manejador para el método PATCH
-func (r *MoraRouter) Patch(pattern string, handler HandlerFunc) {
-	r.Handle("PATCH", pattern, handler)
+func (r *MoraRouter) Patch(pattern string, handler HandlerFunc) *Route {
+	return r.Handle("PATCH", pattern, handler)
 }
 
 // Options registra un manejador para el método OPTIONS
-func (r *MoraRouter) Options(pattern string, handler HandlerFunc) {
-	r.Handle("OPTIONS", pattern, handler)
+func (r *MoraRouter) Options(pattern string, handler HandlerFunc) *Route {
+	return r.Handle("OPTIONS", pattern, handler)
+}
+
+// Query documenta en la especificación OpenAPI un parámetro de query string
+// de la ruta; typ es un tipo de esquema como "string", "integer" o "boolean".
+// No valida la petición: solo la documenta.
+func (rt *Route) Query(name, typ string, required bool) *Route {
+	return rt.update(func(r *route) {
+		r.query = append(slices.Clip(r.query), queryParam{name, typ, required})
+	})
+}
+
+// Doc pone el resumen y la descripción de la operación en la especificación
+// OpenAPI.
+func (rt *Route) Doc(summary, description string) *Route {
+	return rt.update(func(r *route) {
+		r.summary, r.description = summary, description
+	})
+}
+
+// Accepts documenta en la especificación OpenAPI el requestBody de la ruta
+// con el esquema del tipo de body, p. ej. un CreateUser{} para un handler de
+// BindJSON, y contentType como application/json o application/xml.
+func (rt *Route) Accepts(contentType string, body any) *Route {
+	return rt.update(func(r *route) {
+		r.doc.requestType, r.doc.requestContent = reflect.TypeOf(body), contentType
+	})
+}
+
+// Returns documenta en la especificación OpenAPI que la ruta responde con
+// status y un cuerpo JSON del tipo de body, p. ej. []User{}.
+func (rt *Route) Returns(status int, body any) *Route {
+	return rt.update(func(r *route) {
+		responses := make(map[int]reflect.Type, len(r.doc.responses)+1)
+		maps.Copy(responses, r.doc.responses)
+		responses[status] = reflect.TypeOf(body)
+		r.doc.responses = responses
+	})
+}
+
+// Document añade a la documentación OpenAPI de la ruta los cuerpos de
+// ejemplo de doc.
+func (rt *Route) Document(doc RouteDoc) *Route {
+	return rt.update(func(r *route) {
+		r.doc.info = doc
+	})
+}
+
+// Tag añade etiquetas a la operación en la especificación OpenAPI; las
+// herramientas como Swagger UI agrupan las operaciones por etiqueta.
+func (rt *Route) Tag(tags ...string) *Route {
+	return rt.update(func(r *route) {
+		r.tags = append(slices.Clip(r.tags), tags...)
+	})
+}
+
+// Cache fija el tiempo que WithCache guarda las respuestas de la ruta, en
+// lugar del TTL global. Sin WithCache no tiene efecto; un ttl <= 0 equivale
+// a NoCache.
+func (rt *Route) Cache(ttl time.Duration) *Route {
+	if ttl <= 0 {
+		return rt.NoCache()
+	}
+	return rt.update(func(r *route) {
+		r.cacheTTL = ttl
+	})
+}
+
+// NoCache excluye la ruta de WithCache: el handler se ejecuta en cada
+// petición y su respuesta no se guarda.
+func (rt *Route) NoCache() *Route {
+	return rt.update(func(r *route) {
+		r.cacheTTL = -1
+	})
+}
+
+// update aplica fn a la última ruta registrada con el método y el patrón de rt.
+func (rt *Route) update(fn func(r *route)) *Route {
+	r := rt.router
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	routes := r.ownRoutes()
+	for i := len(routes) - 1; i >= 0; i-- {
+		if routes[i].method == rt.method && routes[i].pattern == rt.pattern {
+			fn(&routes[i])
+			break
+		}
+	}
+	return rt
 }
 
 // NotFound permite personalizar el manejador 404.
@@ -198,8 +783,72 @@ func (r *MoraRouter) Mount(prefix string, h http.Handler) {
 	r.mounts = append(r.mounts, mount{prefix: p, handler: http.StripPrefix(p, h)})
 }
 
+// ProxyOptions configura Proxy.
+type ProxyOptions struct {
+	// ForwardedHeaders añade X-Forwarded-For, X-Forwarded-Host y
+	// X-Forwarded-Proto; sin él se eliminan los que traiga el cliente.
+	ForwardedHeaders bool
+	// PreserveHost reenvía la cabecera Host original en lugar de la del destino.
+	PreserveHost bool
+	// Director modifica la petición saliente después de reescribir la URL.
+	Director func(*http.Request)
+	// ErrorHandler atiende los fallos del backend; por defecto responde 502.
+	ErrorHandler func(http.ResponseWriter, *http.Request, error)
+	// Transport sustituye a http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+// Proxy monta un proxy inverso hacia targetURL bajo prefix. Como en Mount, el
+// prefijo se elimina y el resto del path se añade al del destino: con
+// Proxy("/api", "http://svc/v1"), /api/users va a http://svc/v1/users. Las
+// cabeceras hop-by-hop nunca se reenvían.
+func (r *MoraRouter) Proxy(prefix, targetURL string, opts ...ProxyOptions) {
+	target, err := url.Parse(targetURL)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		panic(fmt.Sprintf("destino de proxy inválido: %s", targetURL))
+	}
+	var o ProxyOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			if o.ForwardedHeaders {
+				pr.SetXForwarded()
+			}
+			if o.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			if o.Director != nil {
+				o.Director(pr.Out)
+			}
+		},
+		ErrorHandler: o.ErrorHandler,
+		Transport:    o.Transport,
+	}
+	r.Mount(prefix, proxy)
+}
+
 // ServeHTTP despacha la petición incluyendo mounts, OPTIONS automáticos y manejo 405.
 func (r *MoraRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// limpiar la ruta antes de los montajes para que no se pueda salir de ellos
+	if r.pathCleaning != nil {
+		if clean := cleanPath(req.URL.Path); clean != req.URL.Path {
+			if r.pathCleaning.Redirect && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+				// escapar la ruta: un "/\host" literal en Location sería una
+				// redirección abierta para los navegadores
+				location := (&url.URL{Path: clean}).EscapedPath()
+				if req.URL.RawQuery != "" {
+					location += "?" + req.URL.RawQuery
+				}
+				http.Redirect(w, req, location, http.StatusMovedPermanently)
+				return
+			}
+			req.URL.Path = clean
+			req.URL.RawPath = ""
+		}
+	}
 	path := req.URL.Path
 	// primero, manejar montajes externos
 	for _, m := range r.mounts {
@@ -208,27 +857,52 @@ func (r *MoraRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 	}
-	// traducir ruta según i18n y Accept-Language
-	lang := parseAcceptLanguage(req.Header.Get("Accept-Language"))
-	if transMap, ok := r.i18n[lang]; ok {
-		if newPath, ok2 := transMap[path]; ok2 {
-			path = newPath
-			req.URL.Path = path
-		}
+	// negociar el idioma con Accept-Language y traducir la ruta según i18n
+	languages := parseAcceptLanguage(req.Header.Get("Accept-Language"))
+	r.mu.RLock()
+	routes := r.routes
+	r.routesShared.Store(true)
+	locale, negotiated := negotiateLocale(languages, r.i18n)
+	newPath, translated := r.i18n[locale][path]
+	r.mu.RUnlock()
+	if !negotiated && len(languages) > 0 {
+		locale = canonicalLocale(languages[0])
+	}
+	if locale != "" {
+		req = req.WithContext(context.WithValue(req.Context(), localeKey, locale))
+	}
+	if translated {
+		path = newPath
+		req.URL.Path = path
 	}
 	// particionar path
 	pathSegs := splitPath(path)
+	if r.mergeSlashes {
+		pathSegs = slices.DeleteFunc(pathSegs, func(seg string) bool { return seg == "" })
+	}
+	// resolver la versión de la API y preferir la ruta de esa versión
+	if r.versioning != nil {
+		var version string
+		version, pathSegs = r.versioning.resolve(w, req, routes, pathSegs)
+		if version != "" {
+			req = req.WithContext(context.WithValue(req.Context(), versionKey, version))
+		}
+	}
 	// recolectar métodos permitidos para esta ruta
 	var allowed []string
-	for _, rt := range r.routes {
-		// verificar coincidencia de segmentos ignorando método
-		if matchSegments(rt.segments, pathSegs, nil) {
+	for _, rt := range routes {
+		// verificar coincidencia de segmentos ignorando método; una ruta con
+		// parámetros y un comodín pueden coincidir con el mismo método
+		if matchSegments(rt.segments, pathSegs, nil) && !slices.Contains(allowed, rt.method) {
 			allowed = append(allowed, rt.method)
 		}
 	}
 	// manejo automático de OPTIONS
 	if req.Method == http.MethodOptions {
 		if len(allowed) > 0 {
+			if r.cors != nil {
+				r.cors.preflight(w, req, allowed)
+			}
 			w.Header().Set("Allow", strings.Join(allowed, ","))
 			w.WriteHeader(http.StatusNoContent)
 		} else {
@@ -237,14 +911,37 @@ func (r *MoraRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	// manejar petición normal buscando método exacto
-	for _, rt := range r.routes {
+	for _, rt := range routes {
 		if req.Method != rt.method {
 			continue
 		}
 		params := make(Params)
 		if matchSegments(rt.segments, pathSegs, params) {
 			// embed en Context
-			req2 := req.WithContext(context.WithValue(req.Context(), paramsKey, params))
+			ctx := context.WithValue(req.Context(), paramsKey, params)
+			ctx = context.WithValue(ctx, patternKey, rt.pattern)
+			if rt.cacheTTL != 0 {
+				ctx = context.WithValue(ctx, cacheTTLKey, rt.cacheTTL)
+			}
+			if r.render != nil {
+				ctx = context.WithValue(ctx, renderKey, r.render)
+			}
+			if r.services != nil {
+				ctx = context.WithValue(ctx, servicesKey, r.services)
+			}
+			if r.validator != nil {
+				ctx = context.WithValue(ctx, validatorKey, r.validator)
+			}
+			if r.validationErrors != nil {
+				ctx = context.WithValue(ctx, validationErrorsKey, r.validationErrors)
+			}
+			r.mu.RLock()
+			name, ok := r.routeName(rt.method, rt.pattern)
+			r.mu.RUnlock()
+			if ok {
+				ctx = context.WithValue(ctx, nameKey, name)
+			}
+			req2 := req.WithContext(ctx)
 			rt.handler(w, req2, params)
 			return
 		}
@@ -255,10 +952,223 @@ func (r *MoraRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	// canonicalizar rutas con mayúsculas a su forma en minúsculas
+	if r.lowercaseRedirect && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		if target, ok := r.lowercaseTarget(routes, req.Method, pathSegs, req.URL.EscapedPath()); ok {
+			if strings.HasSuffix(path, "/") && len(pathSegs) > 0 {
+				target += "/"
+			}
+			if req.URL.RawQuery != "" {
+				target += "?" + req.URL.RawQuery
+			}
+			http.Redirect(w, req, target, http.StatusMovedPermanently)
+			return
+		}
+	}
+	// en modo estricto, una ruta con la forma correcta pero un valor inválido responde 400
+	if r.strictParams {
+		for _, rt := range routes {
+			if name, ok := regexMismatch(rt.segments, pathSegs); ok {
+				http.Error(w, fmt.Sprintf("invalid value for parameter %q", name), http.StatusBadRequest)
+				return
+			}
+		}
+	}
 	// no encontrado
 	r.notFound(w, req, nil)
 }
 
+// WithRender usa rd como Render compartido del router, disponible con
+// r.Render() y GetRender(req), para que todas las respuestas usen la misma
+// indentación, charset y plantillas.
+func WithRender(rd *Render) Option {
+	return func(r *MoraRouter) {
+		r.render = rd
+	}
+}
+
+// Render devuelve el Render compartido del router.
+func (r *MoraRouter) Render() *Render {
+	return r.render
+}
+
+// GetRender devuelve el Render del router que atiende la petición, o uno
+// con las opciones por defecto si la petición no pasó por un router.
+func GetRender(req *http.Request) *Render {
+	if rd, ok := req.Context().Value(renderKey).(*Render); ok {
+		return rd
+	}
+	return NewRender()
+}
+
+// WithValidator hace que los helpers de binding de las rutas del router
+// validen con v en lugar de DefaultValidator, de modo que sus reglas,
+// transformadores y mensajes no afectan a otras partes de la aplicación.
+func WithValidator(v *Validator) Option {
+	return func(r *MoraRouter) {
+		r.validator = v
+	}
+}
+
+// GetValidator devuelve el Validator del router que atiende la petición, o
+// DefaultValidator si el router no tiene uno propio.
+func GetValidator(req *http.Request) *Validator {
+	if v, ok := req.Context().Value(validatorKey).(*Validator); ok {
+		return v
+	}
+	return DefaultValidator
+}
+
+// WithStrictParamValidation responde 400 en lugar de 404 cuando una ruta
+// coincide en estructura pero un parámetro no cumple su expresión regular.
+func WithStrictParamValidation() Option {
+	return func(r *MoraRouter) {
+		r.strictParams = true
+	}
+}
+
+// WithLowercaseRedirect responde 301 a las peticiones GET y HEAD que no
+// coinciden con ninguna ruta pero sí lo harían ignorando mayúsculas en los
+// segmentos estáticos, redirigiendo a la forma registrada (normalmente en
+// minúsculas). Los valores de los parámetros no se tocan.
+func WithLowercaseRedirect() Option {
+	return func(r *MoraRouter) {
+		r.lowercaseRedirect = true
+	}
+}
+
+// WithMergeSlashes trata las barras consecutivas como una sola al buscar la
+// ruta, de modo que /users//posts coincide con /users/posts. Sin esta opción
+// un segmento vacío solo coincide con un comodín y la petición acaba en 404.
+func WithMergeSlashes() Option {
+	return func(r *MoraRouter) {
+		r.mergeSlashes = true
+	}
+}
+
+// PathCleaningOptions configura WithPathCleaning.
+type PathCleaningOptions struct {
+	// Redirect responde a GET y HEAD con un 301 a la ruta limpia en lugar de
+	// atenderlas con ella.
+	Redirect bool
+}
+
+// WithPathCleaning canonicaliza req.URL.Path antes de enrutar: une las barras
+// consecutivas y resuelve los segmentos "." y "..", sin salir nunca de "/".
+// net/http ya decodifica el path, así que también cubre %2e%2e. Se aplica
+// antes de Mount, Static y SPA, que reciben siempre la ruta limpia.
+func WithPathCleaning(opts ...PathCleaningOptions) Option {
+	var cfg PathCleaningOptions
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	return func(r *MoraRouter) {
+		r.pathCleaning = &cfg
+	}
+}
+
+// cleanPath devuelve p sin barras repetidas ni segmentos "." y "..",
+// conservando la barra final.
+func cleanPath(p string) string {
+	clean := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	return clean
+}
+
+// lowercaseTarget busca una ruta del método cuyos segmentos estáticos
+// coincidan con pathSegs ignorando mayúsculas y devuelve la ruta canónica,
+// escapada para usarla en Location.
+func (r *MoraRouter) lowercaseTarget(routes []route, method string, pathSegs []string, escaped string) (string, bool) {
+	for _, rt := range routes {
+		if rt.method != method {
+			continue
+		}
+		canon := slices.Clone(pathSegs)
+		changed, ok := false, true
+		for i, seg := range rt.segments {
+			if seg.wildcard || i >= len(canon) {
+				break
+			}
+			if seg.name != "" {
+				continue
+			}
+			if !strings.EqualFold(seg.literal, canon[i]) {
+				ok = false
+				break
+			}
+			if canon[i] != seg.literal {
+				canon[i] = seg.literal
+				changed = true
+			}
+		}
+		if ok && changed && matchSegments(rt.segments, canon, nil) {
+			return escapeCanonicalPath(escaped, pathSegs, canon), true
+		}
+	}
+	return "", false
+}
+
+// escapeCanonicalPath construye la ruta escapada de canon. Si la petición
+// tiene los mismos segmentos que pathSegs conserva su forma escapada, con
+// %2F y %5C incluidos, y solo reemplaza los segmentos que cambian; si no,
+// escapa cada segmento de canon. Un "/\host" literal en Location sería una
+// redirección abierta para los navegadores.
+func escapeCanonicalPath(escaped string, pathSegs, canon []string) string {
+	pieces := splitPath(escaped)
+	if len(pieces) == len(canon) {
+		for i := range canon {
+			if canon[i] == pathSegs[i] {
+				continue
+			}
+			if seg, err := url.PathUnescape(pieces[i]); err != nil || seg != pathSegs[i] {
+				pieces = nil
+				break
+			}
+			pieces[i] = url.PathEscape(canon[i])
+		}
+		if pieces != nil {
+			return "/" + strings.Join(pieces, "/")
+		}
+	}
+	pieces = make([]string, len(canon))
+	for i, seg := range canon {
+		pieces[i] = url.PathEscape(seg)
+	}
+	return "/" + strings.Join(pieces, "/")
+}
+
+// regexMismatch indica si los segmentos coinciden estructuralmente con la ruta
+// salvo por un parámetro con regex, cuyo nombre devuelve.
+func regexMismatch(segs []segment, pathSegs []string) (string, bool) {
+	n := len(segs)
+	if n > 0 && segs[n-1].wildcard {
+		if len(pathSegs) < n-1 {
+			return "", false
+		}
+	} else if len(pathSegs) != n {
+		return "", false
+	}
+	failed := ""
+	for i, seg := range segs {
+		if seg.wildcard {
+			break
+		}
+		val := pathSegs[i]
+		if seg.name == "" {
+			if seg.literal != val {
+				return "", false
+			}
+			continue
+		}
+		if seg.regex != nil && !seg.regex.MatchString(val) && failed == "" {
+			failed = seg.name
+		}
+	}
+	return failed, failed != ""
+}
+
 // matchSegments verifica si los segments de ruta concuerdan con los pathSegs.
 // Si params no es nil, lo llena con valores dinámicos capturados.
 func matchSegments(segs []segment, pathSegs []string, params Params) bool {
@@ -290,7 +1200,8 @@ func matchSegments(segs []segment, pathSegs []string, params Params) bool {
 
 		val := pathSegs[i]
 		if seg.name != "" {
-			if seg.regex != nil && !seg.regex.MatchString(val) {
+			// un segmento vacío (//) no es un valor de parámetro válido
+			if val == "" || (seg.regex != nil && !seg.regex.MatchString(val)) {
 				return false
 			}
 			if params != nil {
@@ -351,52 +1262,83 @@ func loggingMiddleware(next HandlerFunc) HandlerFunc {
 	}
 }
 
-// recoveryMiddleware captura panic y responde 500 con información detallada.
-func recoveryMiddleware(next HandlerFunc) HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request, p Params) {
-		defer func() {
-			if err := recover(); err != nil {
-				// Capturar stack trace para debugging
-				buf := make([]byte, 4096)
-				n := runtime.Stack(buf, false)
-				stackTrace := string(buf[:n])
+// recoveryMiddleware captura panic y responde 500. Los clientes JSON reciben
+// {"error": ..., "request_id": ...}; con MORA_ENV=development la respuesta
+// incluye además el panic y el stack trace.
+func recoveryMiddleware(opts ...RecoveryOptions) Middleware {
+	var o RecoveryOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			sw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// http.ErrAbortHandler aborta la respuesta a propósito
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				stackTrace := debug.Stack()
 
 				// Formatear y registrar el error
-				errMsg := fmt.Sprintf("[Mora][Recovery] panic en %s %s: %v\n%s",
-					r.Method, r.URL.Path, err, stackTrace)
-				log.Printf("%s", errMsg)
+				log.Printf("[Mora][Recovery] panic en %s %s: %v\n%s", r.Method, r.URL.Path, rec, stackTrace)
 
-				// En modo de desarrollo, podríamos devolver el stack trace
-				// (Se podría añadir una opción para configurar esto)
-				isDev := os.Getenv("MORA_ENV") == "development"
+				if o.OnPanic != nil {
+					err, ok := rec.(error)
+					if !ok {
+						err = fmt.Errorf("%v", rec)
+					}
+					o.OnPanic(err, stackTrace)
+				}
 
-				w.WriteHeader(http.StatusInternalServerError)
+				// Si el handler ya envió las cabeceras no se puede cambiar el
+				// estado; escribir otro evitaría solo el aviso de net/http
+				if sw.wroteHeader {
+					return
+				}
+
+				isDev := os.Getenv("MORA_ENV") == "development"
+				if wantsJSON(r) {
+					body := map[string]string{"error": "internal server error"}
+					if id := cmp.Or(w.Header().Get("X-Request-ID"), r.Header.Get("X-Request-ID")); id != "" {
+						body["request_id"] = id
+					}
+					if isDev {
+						body["panic"] = fmt.Sprint(rec)
+						body["stack"] = string(stackTrace)
+					}
+					JSON(w, http.StatusInternalServerError, body)
+					return
+				}
 				if isDev {
 					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-					fmt.Fprintf(w, "Internal Server Error: %v\n\n%s", err, stackTrace)
-				} else {
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					w.WriteHeader(http.StatusInternalServerError)
+					fmt.Fprintf(w, "Internal Server Error: %v\n\n%s", rec, stackTrace)
+					return
 				}
-			}
-		}()
-		next(w, r, p)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}()
+			next(sw, r, p)
+		}
 	}
 }
 
-// corsMiddleware configura cabeceras CORS.
-func corsMiddleware(allow string) Middleware {
-	return func(next HandlerFunc) HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request, p Params) {
-			w.Header().Set("Access-Control-Allow-Origin", allow)
-			if r.Method == http.MethodOptions {
-				w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "*")
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
-			next(w, r, p)
-		}
+// wantsJSON indica si el cliente es una API JSON: envía JSON o prefiere
+// application/json frente a text/html en Accept.
+func wantsJSON(r *http.Request) bool {
+	if isJSONMediaType(requestMediaType(r)) {
+		return true
 	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+	ranges := parseAccept(accept)
+	return acceptQuality(ranges, "application/json") > acceptQuality(ranges, "text/html")
 }
 
 // JSON codifica automáticamente la respuesta en JSON.
@@ -406,48 +1348,249 @@ func JSON(w http.ResponseWriter, status int, data interface{}) {
 	_ = json.NewEncoder(w).Encode(data)
 }
 
+// StreamJSON escribe un array JSON elemento a elemento a partir de un canal,
+// haciendo flush tras cada elemento si el ResponseWriter lo permite.
+// Si un elemento falla al codificarse deja el array sin cerrar, para que el
+// cliente note que la respuesta está truncada, y devuelve el error. Tras un
+// error deja de leer ch: el productor debe enviar con un select sobre
+// r.Context().Done(), que net/http cancela al volver el handler, para no
+// quedarse bloqueado.
+func StreamJSON(w http.ResponseWriter, status int, ch <-chan interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	// Cada elemento se codifica primero en un buffer para no dejar el array
+	// con una coma colgando si la codificación falla.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	sep := ""
+	for item := range ch {
+		buf.Reset()
+		if err := enc.Encode(item); err != nil {
+			flush()
+			return fmt.Errorf("stream JSON: %w", err)
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		if _, err := buf.WriteTo(w); err != nil {
+			return err
+		}
+		sep = ","
+		flush()
+	}
+
+	_, err := io.WriteString(w, "]")
+	flush()
+	return err
+}
+
 // BindJSON decodifica JSON en struct T antes de llamar al handler y valida tags `validate`.
+// Responde 415 si la petición declara un Content-Type que no es JSON; sin
+// Content-Type se intenta igualmente como JSON. HandleJSON lo registra y
+// documenta T como requestBody en la especificación OpenAPI.
 func BindJSON[T any](h func(http.ResponseWriter, *http.Request, Params, T)) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request, p Params) {
+		if mediaType := requestMediaType(r); mediaType != "" && !isJSONMediaType(mediaType) {
+			unsupportedMediaType(w, mediaType, "application/json")
+			return
+		}
 		var obj T
 		dec := json.NewDecoder(r.Body)
 		if err := dec.Decode(&obj); err != nil {
 			http.Error(w, fmt.Sprintf("invalid JSON: %v", err), http.StatusBadRequest)
 			return
 		}
-		if err := validate(obj); err != nil {
-			http.Error(w, fmt.Sprintf("validation error: %v", err), http.StatusBadRequest)
+		if errs := GetValidator(r).Validate(obj); len(errs) > 0 {
+			handleValidationErrors(w, r, errs)
 			return
 		}
 		h(w, r, p, obj)
 	}
 }
 
+// StreamJSONArray recorre un array JSON del cuerpo de la petición elemento a
+// elemento, llamando a fn con cada uno sin cargar el array completo en memoria.
+// Se detiene y devuelve el primer error de fn.
+func StreamJSONArray(r *http.Request, fn func(item json.RawMessage) error) error {
+	dec := json.NewDecoder(r.Body)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("invalid JSON: expected array")
+	}
+
+	for dec.More() {
+		var item json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	// consumir el ']' final
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
+
 // BindXML decodifica XML en struct T antes de llamar al handler y valida tags `validate`.
+// Como BindJSON, responde 415 ante un Content-Type que no es XML; HandleXML
+// lo registra documentando T.
 func BindXML[T any](h func(http.ResponseWriter, *http.Request, Params, T)) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request, p Params) {
+		if mediaType := requestMediaType(r); mediaType != "" && !isXMLMediaType(mediaType) {
+			unsupportedMediaType(w, mediaType, "application/xml", "text/xml")
+			return
+		}
 		var obj T
 		dec := xml.NewDecoder(r.Body)
 		if err := dec.Decode(&obj); err != nil {
 			http.Error(w, fmt.Sprintf("invalid XML: %v", err), http.StatusBadRequest)
 			return
 		}
-		if err := validate(obj); err != nil {
-			http.Error(w, fmt.Sprintf("validation error: %v", err), http.StatusBadRequest)
+		if errs := GetValidator(r).Validate(obj); len(errs) > 0 {
+			handleValidationErrors(w, r, errs)
 			return
 		}
 		h(w, r, p, obj)
 	}
 }
 
-// validate inspecciona tags `validate` en campos de structs y aplica reglas básicas.
-// Usa el nuevo sistema de validación para validar structs.
-func validate(obj any) error {
-	errors := ValidateStruct(obj)
-	if len(errors) > 0 {
-		return errors
+// HandleJSON registra en r (un MoraRouter o un RouteGroup) el handler h con
+// BindJSON y documenta T como su requestBody application/json, como
+// Route.Accepts.
+func HandleJSON[T any](r RouteRegistrar, method, pattern string, h func(http.ResponseWriter, *http.Request, Params, T)) *Route {
+	var body T
+	return r.Handle(method, pattern, BindJSON(h)).Accepts("application/json", body)
+}
+
+// HandleXML registra h con BindXML y documenta T como su requestBody
+// application/xml.
+func HandleXML[T any](r RouteRegistrar, method, pattern string, h func(http.ResponseWriter, *http.Request, Params, T)) *Route {
+	var body T
+	return r.Handle(method, pattern, BindXML(h)).Accepts("application/xml", body)
+}
+
+// BindAuto decodifica el cuerpo en struct T según su Content-Type: JSON (o
+// sin Content-Type), XML, o formulario urlencoded o multipart con los tags
+// `form` de Form.Bind. Así un mismo handler acepta varios formatos. Otros
+// tipos se responden con 415 y el resultado se valida como en BindJSON.
+func BindAuto[T any](h func(http.ResponseWriter, *http.Request, Params, T)) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, p Params) {
+		var obj T
+		switch mediaType := requestMediaType(r); {
+		case mediaType == "" || isJSONMediaType(mediaType):
+			if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+				http.Error(w, fmt.Sprintf("invalid JSON: %v", err), http.StatusBadRequest)
+				return
+			}
+		case isXMLMediaType(mediaType):
+			if err := xml.NewDecoder(r.Body).Decode(&obj); err != nil {
+				http.Error(w, fmt.Sprintf("invalid XML: %v", err), http.StatusBadRequest)
+				return
+			}
+		case mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data":
+			form, err := NewForm(r, 32<<20)
+			if err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, ErrFormTooLarge) {
+					status = http.StatusRequestEntityTooLarge
+				}
+				http.Error(w, fmt.Sprintf("error processing form: %v", err), status)
+				return
+			}
+			defer form.RemoveAll()
+			if err := form.Bind(&obj); err != nil {
+				http.Error(w, fmt.Sprintf("invalid form: %v", err), http.StatusBadRequest)
+				return
+			}
+		default:
+			unsupportedMediaType(w, mediaType, "application/json", "application/xml",
+				"application/x-www-form-urlencoded", "multipart/form-data")
+			return
+		}
+		if errs := GetValidator(r).Validate(obj); len(errs) > 0 {
+			handleValidationErrors(w, r, errs)
+			return
+		}
+		h(w, r, p, obj)
 	}
-	return nil
+}
+
+// requestMediaType devuelve el tipo de Content-Type en minúsculas y sin
+// parámetros, o "" si la petición no lo declara.
+func requestMediaType(r *http.Request) string {
+	contentType := strings.TrimSpace(r.Header.Get("Content-Type"))
+	if contentType == "" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil && mediaType == "" {
+		// un Content-Type mal formado nunca coincide con los esperados
+		return strings.ToLower(contentType)
+	}
+	return mediaType
+}
+
+// isJSONMediaType acepta application/json y los sufijos +json, como
+// application/merge-patch+json.
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isXMLMediaType acepta application/xml, text/xml y los sufijos +xml.
+func isXMLMediaType(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// unsupportedMediaType responde 415 indicando los tipos que acepta la ruta.
+func unsupportedMediaType(w http.ResponseWriter, mediaType string, expected ...string) {
+	http.Error(w, fmt.Sprintf("unsupported Content-Type %q: expected %s", mediaType, strings.Join(expected, " or ")),
+		http.StatusUnsupportedMediaType)
+}
+
+// WithValidationErrorHandler fija cómo responde el router a las peticiones
+// cuyo struct no pasa la validación en BindJSON, BindXML, BindAuto,
+// BindParams, BindRequest y BindHeaders, p. ej. para usar el formato de
+// errores de tu API. Sin esta opción se usa WriteValidationErrors.
+func WithValidationErrorHandler(fn func(http.ResponseWriter, *http.Request, ValidationErrors)) Option {
+	return func(r *MoraRouter) {
+		r.validationErrors = fn
+	}
+}
+
+// handleValidationErrors responde con el manejador de WithValidationErrorHandler
+// del router que atiende la petición, o con WriteValidationErrors.
+func handleValidationErrors(w http.ResponseWriter, r *http.Request, errs ValidationErrors) {
+	if fn, ok := r.Context().Value(validationErrorsKey).(func(http.ResponseWriter, *http.Request, ValidationErrors)); ok {
+		fn(w, r, errs)
+		return
+	}
+	WriteValidationErrors(w, r, errs)
+}
+
+// WriteValidationErrors responde 400 con un JSON {"errors": [...]} donde cada
+// error tiene field, message, rule y value.
+func WriteValidationErrors(w http.ResponseWriter, r *http.Request, errs ValidationErrors) {
+	JSON(w, http.StatusBadRequest, map[string]any{"errors": errs})
 }
 
 // splitPath divide la ruta en segmentos, eliminando barras inicial y final.
@@ -459,14 +1602,73 @@ func splitPath(p string) []string {
 	return strings.Split(p, "/")
 }
 
-// Name asigna un nombre a una ruta para su inversión de URL.
+// Name asigna un nombre a una ruta para su inversión de URL. Vale para
+// todos los métodos del patrón; si cada método tiene su nombre, como
+// users.index y users.create en /users, usa Route.Name.
 func (r *MoraRouter) Name(name, pattern string) {
+	r.nameRoute(name, pattern, "")
+}
+
+// Name asigna un nombre a la ruta para su inversión de URL. RouteName y
+// ForRouteNames lo ven solo en las peticiones de este método.
+func (rt *Route) Name(name string) *Route {
+	rt.router.nameRoute(name, rt.pattern, rt.method)
+	return rt
+}
+
+// nameRoute registra name para pattern con cada uno de methods; el método
+// "" vale para los que no tengan un nombre propio.
+func (r *MoraRouter) nameRoute(name, pattern string, methods ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.namedRoutes[name] = pattern
+	for _, method := range methods {
+		r.routeNames[routeKey(method, pattern)] = name
+	}
+}
+
+// routeKey es la clave de una ruta en routeNames.
+func routeKey(method, pattern string) string {
+	return method + " " + pattern
+}
+
+// routeName busca el nombre de la ruta de method y pattern, o el de todo el
+// patrón si el método no tiene uno propio. El llamador tiene r.mu.
+func (r *MoraRouter) routeName(method, pattern string) (string, bool) {
+	if name, ok := r.routeNames[routeKey(method, pattern)]; ok {
+		return name, true
+	}
+	name, ok := r.routeNames[routeKey("", pattern)]
+	return name, ok
+}
+
+// RouteName devuelve el nombre de la ruta que atendió la petición, o "" si
+// no tiene nombre.
+func RouteName(r *http.Request) string {
+	name, _ := r.Context().Value(nameKey).(string)
+	return name
+}
+
+// ForRouteNames aplica mw solo cuando el nombre de la ruta coincidente
+// empieza por prefix; pensado para registrarse con Use.
+func ForRouteNames(prefix string, mw Middleware) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		wrapped := mw(next)
+		return func(w http.ResponseWriter, req *http.Request, p Params) {
+			if name := RouteName(req); name != "" && strings.HasPrefix(name, prefix) {
+				wrapped(w, req, p)
+				return
+			}
+			next(w, req, p)
+		}
+	}
 }
 
 // URL genera la URL de la ruta nombrada con los parámetros dados.
 func (r *MoraRouter) URL(name string, params ...string) (string, error) {
+	r.mu.RLock()
 	pattern, ok := r.namedRoutes[name]
+	r.mu.RUnlock()
 	if !ok {
 		return "", fmt.Errorf("ruta no encontrada: %s", name)
 	}
@@ -490,59 +1692,211 @@ func (r *MoraRouter) URL(name string, params ...string) (string, error) {
 	return "/" + strings.Join(result, "/"), nil
 }
 
+// WithTrustedProxies indica qué proxies (IPs o rangos CIDR) pueden fijar las
+// cabeceras X-Forwarded-Host y X-Forwarded-Proto, y la IP de X-Forwarded-For
+// que registra WithStructuredLogging.
+func WithTrustedProxies(proxies ...string) Option {
+	return func(r *MoraRouter) {
+		for _, proxy := range proxies {
+			if !strings.Contains(proxy, "/") {
+				if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+					proxy += "/32"
+				} else {
+					proxy += "/128"
+				}
+			}
+			_, ipNet, err := net.ParseCIDR(proxy)
+			if err != nil {
+				panic(fmt.Sprintf("proxy de confianza inválido: %s", proxy))
+			}
+			r.trustedProxies = append(r.trustedProxies, ipNet)
+		}
+	}
+}
+
+// isTrustedProxy comprueba si la petición llega desde un proxy de confianza.
+func (r *MoraRouter) isTrustedProxy(req *http.Request) bool {
+	if len(r.trustedProxies) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range r.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP devuelve la IP del cliente: la primera de X-Forwarded-For si la
+// petición llega de un proxy de confianza, o la de RemoteAddr.
+func (r *MoraRouter) clientIP(req *http.Request) string {
+	if r.isTrustedProxy(req) {
+		if ip := firstHeaderValue(req.Header.Get("X-Forwarded-For")); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// requestOrigin resuelve el esquema y host de la petición, respetando las
+// cabeceras X-Forwarded-* solo si provienen de un proxy de confianza.
+func (r *MoraRouter) requestOrigin(req *http.Request) (scheme, host string) {
+	scheme = "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	host = req.Host
+
+	if r.isTrustedProxy(req) {
+		if proto := firstHeaderValue(req.Header.Get("X-Forwarded-Proto")); proto != "" {
+			scheme = strings.ToLower(proto)
+		}
+		if fwdHost := firstHeaderValue(req.Header.Get("X-Forwarded-Host")); fwdHost != "" {
+			host = fwdHost
+		}
+	}
+	return scheme, host
+}
+
+// firstHeaderValue devuelve el primer elemento de una cabecera separada por comas.
+func firstHeaderValue(v string) string {
+	return strings.TrimSpace(strings.SplitN(v, ",", 2)[0])
+}
+
+// AbsoluteURL genera la URL absoluta (esquema + host + ruta) de una ruta nombrada.
+func (r *MoraRouter) AbsoluteURL(req *http.Request, name string, params ...string) (string, error) {
+	path, err := r.URL(name, params...)
+	if err != nil {
+		return "", err
+	}
+	scheme, host := r.requestOrigin(req)
+	return scheme + "://" + host + path, nil
+}
+
 // Param obtiene un parámetro de ruta desde el context.Context de la petición
 func Param(r *http.Request, name string) string {
-	if p, ok := r.Context().Value(paramsKey).(Params); ok {
-		return p[name]
-	}
-	return ""
+	return ParamsFromContext(r.Context())[name]
 }
 
-// WithMetrics registra un endpoint /metrics y un middleware para latencias
+// WithMetrics registra un endpoint /metrics y un middleware para latencias.
+// También expone /_mora/timings con los percentiles por ruta, protegido por
+// WithDebugAuth.
 func WithMetrics() Option {
 	return func(r *MoraRouter) {
 		// middleware
-		m := metricsMiddleware
-		r.middlewareRegistry["metrics"] = m
+		m := r.metrics.middleware
+		r.registerMiddleware("metrics", m)
 		r.middlewares = append(r.middlewares, m)
-		// endpoint
+		// endpoints
 		r.Get("/metrics", func(w http.ResponseWriter, req *http.Request, p Params) {
-			metricsHandler(w)
+			r.metrics.ServeHTTP(w, req)
 		})
+		r.Get("/_mora/timings", r.debugOnly(r.metrics.timingsHandler))
 	}
 }
 
-var (
-	metricsMu sync.Mutex
-	latencies []time.Duration
-)
+// WithMaxConcurrentRequests limita el número de peticiones atendidas a la vez;
+// el exceso recibe 503 con Retry-After. Un n menor o igual que 0 significa
+// sin límite.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(r *MoraRouter) {
+		if n <= 0 {
+			return
+		}
+		r.Use(concurrencyLimitMiddleware(n, r.metrics))
+	}
+}
 
-func metricsMiddleware(next HandlerFunc) HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request, p Params) {
-		start := time.Now()
-		next(w, r, p)
-		dur := time.Since(start)
-		metricsMu.Lock()
-		latencies = append(latencies, dur)
-		metricsMu.Unlock()
+func concurrencyLimitMiddleware(n int, metrics *MetricsCollector) Middleware {
+	sem := make(chan struct{}, n)
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				next(w, r, p)
+			default:
+				metrics.rejected.Add(1)
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			}
+		}
+	}
+}
+
+// WithAudit emite un AuditEvent a sink tras atender cada petición
+// POST, PUT, PATCH o DELETE.
+func WithAudit(sink func(AuditEvent)) Option {
+	return func(r *MoraRouter) {
+		r.Use(auditMiddleware(sink))
+	}
+}
+
+func auditMiddleware(sink func(AuditEvent)) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request, p Params) {
+			switch req.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				next(w, req, p)
+				return
+			}
+
+			ev := &AuditEvent{Method: req.Method}
+			ev.Pattern, _ = req.Context().Value(patternKey).(string)
+			// si el JWT va antes, los claims ya están en la petición
+			if claims := GetClaims(req); claims != nil {
+				ev.Identity = claimSubject(claims)
+			}
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next(sw, req.WithContext(context.WithValue(req.Context(), auditKey, ev)), p)
+
+			ev.Status = sw.status
+			ev.Timestamp = time.Now()
+			sink(*ev)
+		}
 	}
 }
 
-func metricsHandler(w http.ResponseWriter) {
-	metricsMu.Lock()
-	defer metricsMu.Unlock()
-	total := time.Duration(0)
-	for _, d := range latencies {
-		total += d
+// WithSlowRequestLog registra un aviso con la ruta, el estado y los parámetros
+// de las peticiones que tardan más que threshold, independiente de WithLogging.
+func WithSlowRequestLog(threshold time.Duration) Option {
+	return func(r *MoraRouter) {
+		m := slowRequestMiddleware(threshold)
+		r.registerMiddleware("slowlog", m)
+		r.middlewares = append(r.middlewares, m)
 	}
-	avg := time.Duration(0)
-	if len(latencies) > 0 {
-		avg = total / time.Duration(len(latencies))
+}
+
+func slowRequestMiddleware(threshold time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request, p Params) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next(sw, req, p)
+
+			duration := time.Since(start)
+			if duration <= threshold {
+				return
+			}
+			pattern, _ := req.Context().Value(patternKey).(string)
+			log.Printf("[Mora][WARN] slow request %s %s route=%s status=%d duration=%s threshold=%s params=%v",
+				req.Method, req.URL.Path, pattern, sw.status, duration.Round(time.Microsecond), threshold, map[string]string(p))
+		}
 	}
-	fmt.Fprintf(w, "# HELP http_handler_latency_seconds_average average latency in seconds\n")
-	fmt.Fprintf(w, "http_handler_latency_seconds_average %f\n", avg.Seconds())
-	fmt.Fprintf(w, "# HELP http_handler_requests_total total handled requests\n")
-	fmt.Fprintf(w, "http_handler_requests_total %d\n", len(latencies))
 }
 
 // WithCache activa un middleware de caching en memoria por ruta
@@ -557,32 +1911,97 @@ var (
 	cacheStore = map[string]cacheEntry{}
 )
 
-func cacheMiddleware(ttl time.Duration) Middleware {
+// cacheMiddleware guarda cada respuesta durante ttl, o durante el de
+// Route.Cache si la ruta tiene uno. Las respuestas 200 se guardan con un ETag
+// (el del handler o un hash del cuerpo), así que una petición GET o HEAD cuyo
+// If-None-Match coincida recibe un 304 sin ejecutar el handler. Las rutas con
+// Route.NoCache pasan directamente al handler.
+func cacheMiddleware(defaultTTL time.Duration) Middleware {
 	return func(next HandlerFunc) HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			ttl := defaultTTL
+			if routeTTL, ok := r.Context().Value(cacheTTLKey).(time.Duration); ok {
+				if routeTTL < 0 {
+					next(w, r, p)
+					return
+				}
+				ttl = routeTTL
+			}
 			key := r.Method + ":" + r.URL.RequestURI()
 			cacheMu.Lock()
 			e, ok := cacheStore[key]
 			cacheMu.Unlock()
-			if ok && time.Now().Before(e.expire) {
-				for k, vs := range e.header {
-					for _, v := range vs {
+			if !ok || !time.Now().Before(e.expire) {
+				// capturar la respuesta completa para calcular el ETag antes de enviarla
+				rec := &cacheRecorder{header: http.Header{}, status: http.StatusOK}
+				next(rec, r, p)
+				e = cacheEntry{rec.header, rec.status, rec.buf.Bytes(), rec.header.Get("ETag"), time.Now().Add(ttl)}
+				if e.status == http.StatusOK && e.etag == "" {
+					sum := sha256.Sum256(e.body)
+					e.etag = fmt.Sprintf(`"%x"`, sum[:16])
+					e.header.Set("ETag", e.etag)
+				}
+				cacheMu.Lock()
+				cacheStore[key] = e
+				cacheMu.Unlock()
+			}
+
+			if e.etag != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+				etagMatches(r.Header.Get("If-None-Match"), e.etag) {
+				// un 304 conserva solo las cabeceras de validación y caché
+				for _, k := range []string{"ETag", "Cache-Control", "Expires", "Vary", "Content-Location"} {
+					for _, v := range e.header.Values(k) {
 						w.Header().Add(k, v)
 					}
 				}
-				w.WriteHeader(e.status)
-				w.Write(e.body)
+				w.WriteHeader(http.StatusNotModified)
 				return
 			}
-			// capture response
-			buf := &bytes.Buffer{}
-			rw := &responseBuffer{ResponseWriter: w, buf: buf, header: http.Header{}, status: http.StatusOK}
-			next(rw, r, p)
-			cacheMu.Lock()
-			cacheStore[key] = cacheEntry{rw.header, rw.status, buf.Bytes(), time.Now().Add(ttl)}
-			cacheMu.Unlock()
+			for k, vs := range e.header {
+				for _, v := range vs {
+					w.Header().Add(k, v)
+				}
+			}
+			w.WriteHeader(e.status)
+			w.Write(e.body)
+		}
+	}
+}
+
+// etagMatches indica si la lista de If-None-Match contiene etag (o "*"),
+// usando la comparación débil que exige RFC 9110 para GET y HEAD.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
 		}
 	}
+	return false
+}
+
+// cacheRecorder acumula la respuesta del handler sin enviarla al cliente.
+type cacheRecorder struct {
+	header http.Header
+	buf    bytes.Buffer
+	status int
+	wrote  bool
+}
+
+func (c *cacheRecorder) Header() http.Header { return c.header }
+func (c *cacheRecorder) Write(b []byte) (int, error) {
+	c.wrote = true
+	return c.buf.Write(b)
+}
+func (c *cacheRecorder) WriteHeader(status int) {
+	if !c.wrote {
+		c.status = status
+		c.wrote = true
+	}
 }
 
 func (r *responseBuffer) Header() http.Header { return r.header }
@@ -630,6 +2049,93 @@ func rateLimitMiddleware(max int, window time.Duration) Middleware {
 	}
 }
 
+// WithAcceptEnforcement responde 406 Not Acceptable cuando la cabecera Accept
+// no admite ninguno de los tipos supported. Las peticiones sin Accept o con
+// */* pasan siempre, salvo que excluyan esos tipos de forma explícita con q=0.
+func WithAcceptEnforcement(supported ...string) Option {
+	return func(r *MoraRouter) {
+		m := func(next HandlerFunc) HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request, p Params) {
+				header := req.Header.Get("Accept")
+				if header == "" {
+					next(w, req, p)
+					return
+				}
+				ranges := parseAccept(header)
+				for _, typ := range supported {
+					if acceptQuality(ranges, typ) > 0 {
+						next(w, req, p)
+						return
+					}
+				}
+				http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+			}
+		}
+		r.registerMiddleware("accept", m)
+		r.middlewares = append(r.middlewares, m)
+	}
+}
+
+// acceptRange es un rango de tipos de Accept con su calidad.
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept interpreta la cabecera Accept y devuelve los rangos ordenados
+// por calidad descendente, conservando el orden original en caso de empate.
+// Una calidad ausente o mal formada vale 1.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if mediaType == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(key) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q >= 0 && q <= 1 {
+				quality = q
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType, quality})
+	}
+	slices.SortStableFunc(ranges, func(a, b acceptRange) int {
+		return cmp.Compare(b.quality, a.quality)
+	})
+	return ranges
+}
+
+// acceptQuality devuelve la calidad que los rangos asignan a mediaType según
+// el rango más específico que lo cubre (tipo exacto, tipo/* y luego */*), o 0
+// si ninguno lo cubre.
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	mediaType, _, _ = strings.Cut(strings.ToLower(mediaType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	major, _, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, 0
+	for _, rg := range ranges {
+		level := 0
+		switch rg.mediaType {
+		case mediaType:
+			level = 3
+		case major + "/*":
+			level = 2
+		case "*/*":
+			level = 1
+		}
+		if level > specificity {
+			quality, specificity = rg.quality, level
+		}
+	}
+	return quality
+}
+
 // Handy responders
 
 // Error responde con un código y mensaje simple
@@ -648,6 +2154,39 @@ func FileDownload(w http.ResponseWriter, r *http.Request, filePath string) {
 	http.ServeFile(w, r, filePath)
 }
 
+// NotModifiedSince fija Last-Modified a modtime y, si la copia del cliente
+// según If-Modified-Since sigue vigente, responde 304 y devuelve true para que
+// el handler termine sin renderizar. Sigue las reglas de http.ServeContent:
+// solo aplica a GET y HEAD, cede ante If-None-Match y compara con precisión
+// de segundos. Un modtime cero no se considera nunca vigente.
+func NotModifiedSince(w http.ResponseWriter, r *http.Request, modtime time.Time) bool {
+	if modtime.IsZero() || modtime.Equal(time.Unix(0, 0)) {
+		return false
+	}
+	w.Header().Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if r.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// Last-Modified solo tiene precisión de segundos
+	if modtime.Truncate(time.Second).After(since) {
+		return false
+	}
+
+	h := w.Header()
+	delete(h, "Content-Type")
+	delete(h, "Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
 // WithHotReload habilita recarga automática de rutas al detectar cambios en el archivo dado.
 func WithHotReload(filePath string, interval time.Duration) Option {
 	return func(r *MoraRouter) {
@@ -660,13 +2199,19 @@ func WithHotReload(filePath string, interval time.Duration) Option {
 func WithI18n(translations map[string]map[string]string) Option {
 	return func(r *MoraRouter) {
 		// translations[rutaNombre][lang] = patrón traducido
+		r.mu.Lock()
+		defer r.mu.Unlock()
 		r.i18n = translations
 	}
 }
 
 // WithSwagger registra un endpoint /openapi.json que expone la especificación OpenAPI generada automáticamente.
-func WithSwagger() Option {
+// Un SwaggerOptions opcional fija el título, la descripción, la versión y los servidores de la especificación.
+func WithSwagger(opts ...SwaggerOptions) Option {
 	return func(r *MoraRouter) {
+		if len(opts) > 0 {
+			r.openAPI = opts[0]
+		}
 		r.Get("/openapi.json", func(w http.ResponseWriter, req *http.Request, p Params) {
 			JSON(w, http.StatusOK, r.BuildOpenAPISpec())
 		})
@@ -676,7 +2221,9 @@ func WithSwagger() Option {
 // BuildOpenAPISpec genera un mapa con la especificación OpenAPI 3.0 a partir de las rutas registradas.
 func (r *MoraRouter) BuildOpenAPISpec() map[string]interface{} {
 	paths := make(map[string]map[string]interface{})
-	for _, rt := range r.routes {
+	schemas := newSchemaBuilder()
+	var tags []string
+	for _, rt := range r.routesSnapshot() {
 		if paths[rt.pattern] == nil {
 			paths[rt.pattern] = make(map[string]interface{})
 		}
@@ -684,15 +2231,27 @@ func (r *MoraRouter) BuildOpenAPISpec() map[string]interface{} {
 		var params []map[string]interface{}
 		for _, seg := range rt.segments {
 			if seg.name != "" {
+				schema := map[string]string{"type": "string"}
+				if seg.regex != nil {
+					schema["pattern"] = seg.regex.String()
+				}
 				params = append(params, map[string]interface{}{
 					"name":     seg.name,
 					"in":       "path",
 					"required": true,
-					"schema":   map[string]string{"type": "string"},
+					"schema":   schema,
 				})
 			}
 		}
-		paths[rt.pattern][strings.ToLower(rt.method)] = map[string]interface{}{
+		for _, q := range rt.query {
+			params = append(params, map[string]interface{}{
+				"name":     q.name,
+				"in":       "query",
+				"required": q.required,
+				"schema":   map[string]string{"type": q.typ},
+			})
+		}
+		operation := map[string]interface{}{
 			"parameters": params,
 			"responses": map[string]interface{}{
 				"200": map[string]interface{}{
@@ -707,28 +2266,104 @@ func (r *MoraRouter) BuildOpenAPISpec() map[string]interface{} {
 				},
 			},
 		}
+		// cuerpos y ejemplos de Accepts, Returns y Document
+		schemas.operationBody(operation, rt.doc)
+		if rt.summary != "" {
+			operation["summary"] = rt.summary
+		}
+		if rt.description != "" {
+			operation["description"] = rt.description
+		}
+		if len(rt.tags) > 0 {
+			operation["tags"] = rt.tags
+			for _, tag := range rt.tags {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+		}
+		// cualquiera de los esquemas exigidos autoriza la operación
+		if len(rt.security) > 0 {
+			var security []map[string][]string
+			for _, name := range rt.security {
+				security = append(security, map[string][]string{name: {}})
+			}
+			operation["security"] = security
+		}
+		paths[rt.pattern][strings.ToLower(rt.method)] = operation
 	}
 
-	// Versionar automáticamente la API
-	version := "1.0.0"
+	// los campos vacíos de SwaggerOptions conservan los valores por defecto
+	info := map[string]interface{}{
+		"title":       cmp.Or(r.openAPI.Title, "API generada con MoraRouter"),
+		"description": cmp.Or(r.openAPI.Description, "Documentación automática de la API"),
+		"version":     cmp.Or(r.openAPI.Version, "1.0.0"),
+	}
 
-	return map[string]interface{}{
+	spec := map[string]interface{}{
 		"openapi": "3.0.0",
-		"info": map[string]interface{}{
-			"title":       "API generada con MoraRouter",
-			"description": "Documentación automática de la API",
-			"version":     version,
-		},
-		"paths": paths,
+		"info":    info,
+		"paths":   paths,
 		"components": map[string]interface{}{
-			"schemas": map[string]interface{}{},
+			"schemas":         schemas.schemas,
+			"securitySchemes": r.securitySchemes,
 		},
 	}
+	if len(r.openAPI.Servers) > 0 {
+		spec["servers"] = r.openAPI.Servers
+	}
+	// etiquetas en orden alfabético para que las herramientas las agrupen
+	if len(tags) > 0 {
+		slices.Sort(tags)
+		tagList := make([]map[string]string, len(tags))
+		for i, tag := range tags {
+			tagList[i] = map[string]string{"name": tag}
+		}
+		spec["tags"] = tagList
+	}
+	return spec
+}
+
+// WithSecurityScheme declara un esquema de seguridad en la especificación
+// OpenAPI, por ejemplo {"type": "apiKey", "in": "header", "name": "X-API-Key"}.
+// Las rutas lo exigen con Secure.
+func WithSecurityScheme(name string, scheme map[string]interface{}) Option {
+	return func(r *MoraRouter) {
+		r.securitySchemes[name] = scheme
+	}
+}
+
+// Secure declara en la especificación OpenAPI que las rutas ya registradas con
+// el patrón exigen alguno de los esquemas dados, o cualquiera de los declarados
+// si no se indica ninguno. No añade autenticación: solo la documenta.
+func (r *MoraRouter) Secure(pattern string, schemes ...string) {
+	if len(schemes) == 0 {
+		for name := range r.securitySchemes {
+			schemes = append(schemes, name)
+		}
+		slices.Sort(schemes)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	routes := r.ownRoutes()
+	for i := range routes {
+		if routes[i].pattern == pattern {
+			routes[i].security = schemes
+		}
+	}
 }
 
 // WithJWT agrega un middleware de autenticación JWT HMAC-SHA256 usando una clave secreta.
+// Declara además el esquema "bearerAuth" en OpenAPI y lo exige a las rutas
+// registradas después, que son las que pasan por el middleware.
 func WithJWT(secret string) Option {
 	return func(r *MoraRouter) {
+		r.securitySchemes["bearerAuth"] = map[string]interface{}{
+			"type":         "http",
+			"scheme":       "bearer",
+			"bearerFormat": "JWT",
+		}
+		r.security = append(r.security, "bearerAuth")
 		r.Use(jwtMiddleware([]byte(secret)))
 	}
 }
@@ -766,6 +2401,10 @@ func jwtMiddleware(secret []byte) Middleware {
 				http.Error(w, "Invalid claims", http.StatusUnauthorized)
 				return
 			}
+			// informar la identidad a WithAudit si se registró antes que el JWT
+			if ev, ok := req.Context().Value(auditKey).(*AuditEvent); ok {
+				ev.Identity = claimSubject(claims)
+			}
 			ctx := context.WithValue(req.Context(), contextKey("claims"), claims)
 			req2 := req.WithContext(ctx)
 			next(w, req2, p)
@@ -781,6 +2420,14 @@ func GetClaims(req *http.Request) map[string]any {
 	return nil
 }
 
+// claimSubject devuelve el claim "sub" como texto, o "" si no existe.
+func claimSubject(claims map[string]any) string {
+	if sub, ok := claims["sub"]; ok && sub != nil {
+		return fmt.Sprint(sub)
+	}
+	return ""
+}
+
 // RequireRole crea un middleware que verifica que 'roles' en los claims JWT incluya el rol dado.
 func RequireRole(role string) Middleware {
 	return func(next HandlerFunc) HandlerFunc {
@@ -819,26 +2466,124 @@ func WithWebSocket(path string, h http.Handler) Option {
 
 // Static sirve archivos estáticos desde un directorio bajo el prefijo.
 func (r *MoraRouter) Static(prefix, dir string) {
-	fs := http.FileServer(http.Dir(dir))
-	r.Mount(prefix, http.StripPrefix(prefix, fs))
+	r.StaticFS(prefix, os.DirFS(dir))
+}
+
+// StaticFS sirve archivos estáticos desde fsys (por ejemplo un embed.FS) bajo
+// el prefijo.
+func (r *MoraRouter) StaticFS(prefix string, fsys fs.FS) {
+	// Mount ya quita el prefijo antes de llegar al file server
+	r.Mount(prefix, http.FileServerFS(fsys))
+}
+
+// singleFileTypes completa mime.TypeByExtension para los archivos que se
+// suelen servir con File y que no están en la tabla integrada de Go.
+var singleFileTypes = map[string]string{
+	".ico":         "image/x-icon",
+	".webmanifest": "application/manifest+json",
+}
+
+// File sirve el archivo filePath en la ruta exacta pattern para GET y HEAD,
+// p. ej. r.File("/favicon.ico", "./public/favicon.ico"). El Content-Type sale
+// de la extensión, y http.ServeFile atiende Range e If-Modified-Since; además
+// se envía un ETag débil y Cache-Control de 24 horas, como en los estáticos.
+func (r *MoraRouter) File(pattern, filePath string) {
+	contentType := mime.TypeByExtension(filepath.Ext(filePath))
+	if contentType == "" {
+		contentType = singleFileTypes[strings.ToLower(filepath.Ext(filePath))]
+	}
+
+	handler := func(w http.ResponseWriter, req *http.Request, p Params) {
+		stat, err := os.Stat(filePath)
+		if err != nil || stat.IsDir() {
+			http.NotFound(w, req)
+			return
+		}
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Header().Set("Cache-Control", "max-age=86400")
+		w.Header().Set("ETag", fmt.Sprintf(`W/"%x-%x"`, stat.Size(), stat.ModTime().UnixNano()))
+		http.ServeFile(w, req, filePath)
+	}
+	r.Get(pattern, handler)
+	r.Handle(http.MethodHead, pattern, handler)
 }
 
 // SPA sirve una single-page app: archivos estáticos y fallback al index.
 func (r *MoraRouter) SPA(prefix, dir, indexFile string) {
-	r.Static(prefix, dir)
-	r.Get(prefix+"/*filepath", func(w http.ResponseWriter, req *http.Request, p Params) {
-		http.ServeFile(w, req, filepath.Join(dir, indexFile))
-	})
+	r.SPAFS(prefix, os.DirFS(dir), indexFile)
+}
+
+// SPAFS sirve una single-page app desde fsys: los archivos que existen se
+// sirven tal cual y el resto de rutas devuelve indexFile.
+func (r *MoraRouter) SPAFS(prefix string, fsys fs.FS, indexFile string) {
+	r.Get(strings.TrimSuffix(prefix, "/")+"/*path", spaHandler(fsys, indexFile))
 }
 
-// parseAcceptLanguage obtiene el primer lenguaje de Accept-Language.
-func parseAcceptLanguage(header string) string {
-	if header == "" {
+// parseAcceptLanguage devuelve los idiomas de Accept-Language en minúsculas
+// y ordenados por calidad (RFC 7231 5.3.5), sin los rechazados con q=0.
+func parseAcceptLanguage(header string) []string {
+	var languages []string
+	for _, rg := range parseAccept(header) {
+		if rg.quality > 0 {
+			languages = append(languages, rg.mediaType)
+		}
+	}
+	return languages
+}
+
+// negotiateLocale elige el primer idioma aceptado que tiene traducciones,
+// probando cada etiqueta y después sus prefijos (en-US → en) antes de pasar
+// a la siguiente. Devuelve la clave tal como aparece en translations.
+func negotiateLocale(languages []string, translations map[string]map[string]string) (string, bool) {
+	if len(translations) == 0 {
+		return "", false
+	}
+	for _, lang := range languages {
+		if lang == "*" {
+			continue
+		}
+		for tag := lang; tag != ""; {
+			for key := range translations {
+				if strings.EqualFold(key, tag) {
+					return key, true
+				}
+			}
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return "", false
+}
+
+// canonicalLocale da formato a una etiqueta de idioma: idioma en minúsculas,
+// script con mayúscula inicial y región en mayúsculas (zh-hant-tw → zh-Hant-TW).
+func canonicalLocale(tag string) string {
+	if tag == "*" {
 		return ""
 	}
-	parts := strings.Split(header, ",")
-	lang := strings.TrimSpace(strings.SplitN(parts[0], ";", 2)[0])
-	return lang
+	parts := strings.Split(strings.ToLower(tag), "-")
+	for i, part := range parts[1:] {
+		switch len(part) {
+		case 2:
+			parts[i+1] = strings.ToUpper(part)
+		case 4:
+			parts[i+1] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Locale devuelve el idioma negociado para la petición: el de WithI18n que
+// mejor encaja con Accept-Language o, si ninguno encaja, el preferido por el
+// cliente. Devuelve "" si la petición no indica idioma.
+func Locale(r *http.Request) string {
+	locale, _ := r.Context().Value(localeKey).(string)
+	return locale
 }
 
 func (c DefaultController) Index(w http.ResponseWriter, r *http.Request, p Params) {
@@ -857,33 +2602,166 @@ func (c DefaultController) Delete(w http.ResponseWriter, r *http.Request, p Para
 	http.Error(w, "Not Implemented", http.StatusNotImplemented)
 }
 
-// Resource registra automáticamente todas las rutas REST para un recurso.
-func (r *MoraRouter) Resource(pathPrefix string, controller ResourceController) {
+// ResourceOptions personaliza los patrones y nombres de las rutas de un recurso.
+type ResourceOptions struct {
+	// Nombre del parámetro de miembro (por defecto "id")
+	ParamName string
+	// Expresión regular que debe cumplir el parámetro, p. ej. `\d+`
+	IDPattern string
+	// Nombre base para URL reversal (por defecto el último segmento del prefijo)
+	Name string
+	// Acciones a registrar ("index", "show", "create", "update", "delete");
+	// vacío registra todas. "update" incluye PUT y PATCH
+	Only []string
+	// Acciones a omitir de las que registraría Only
+	Except []string
+}
+
+// resourceActions son las acciones que admiten Only y Except.
+var resourceActions = []string{"index", "show", "create", "update", "delete"}
+
+// enabled indica si la acción debe registrarse según Only y Except.
+func (o ResourceOptions) enabled(action string) bool {
+	if len(o.Only) > 0 && !slices.Contains(o.Only, action) {
+		return false
+	}
+	return !slices.Contains(o.Except, action)
+}
+
+// Resource registra automáticamente todas las rutas REST para un recurso;
+// unas ResourceOptions opcionales equivalen a ResourceWithOptions. Las rutas
+// llevan el nombre del recurso como etiqueta OpenAPI.
+func (r *MoraRouter) Resource(pathPrefix string, controller ResourceController, opts ...ResourceOptions) *ResourceRoutes {
+	var o ResourceOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	return r.ResourceWithOptions(pathPrefix, controller, o)
+}
+
+// ResourceWithOptions registra las rutas REST de un recurso con patrones personalizados.
+func (r *MoraRouter) ResourceWithOptions(pathPrefix string, controller ResourceController, opts ResourceOptions) *ResourceRoutes {
+	for _, action := range append(append([]string{}, opts.Only...), opts.Except...) {
+		if !slices.Contains(resourceActions, action) {
+			panic(fmt.Sprintf("acción de recurso desconocida: %s", action))
+		}
+	}
+
 	// Normalizar prefix
 	prefix := "/" + strings.Trim(pathPrefix, "/")
 
+	// Segmento de miembro, p. ej. /:id o /:user_id(\d+)
+	paramName := opts.ParamName
+	if paramName == "" {
+		paramName = "id"
+	}
+	member := prefix + "/:" + paramName
+	if opts.IDPattern != "" {
+		member += "(" + opts.IDPattern + ")"
+	}
+
+	// Nombre base para URL reversal
+	resourceName := opts.Name
+	if resourceName == "" {
+		resourceName = filepath.Base(prefix)
+	}
+	routes := &ResourceRoutes{router: r, prefix: prefix, member: member, name: resourceName, collectionAt: len(r.registry().routesSnapshot())}
+
 	// GET /recursos (Index) - listar todos
-	r.Get(prefix, controller.Index)
+	if opts.enabled("index") {
+		r.Get(prefix, controller.Index).Tag(resourceName).Name(resourceName + ".index")
+	}
 
 	// GET /recursos/:id (Show) - mostrar uno
-	r.Get(prefix+"/:id", controller.Show)
+	if opts.enabled("show") {
+		r.Get(member, controller.Show).Tag(resourceName).Name(resourceName + ".show")
+	}
 
 	// POST /recursos (Create) - crear uno nuevo
-	r.Post(prefix, controller.Create)
+	if opts.enabled("create") {
+		r.Post(prefix, controller.Create).Tag(resourceName).Name(resourceName + ".create")
+	}
 
-	// PUT/PATCH /recursos/:id (Update) - actualizar uno existente
-	r.Put(prefix+"/:id", controller.Update)
+	// PUT/PATCH /recursos/:id (Update) - actualizar uno existente; PATCH usa
+	// Patch si el controlador lo implementa
+	if opts.enabled("update") {
+		r.Put(member, controller.Update).Tag(resourceName).Name(resourceName + ".update")
+		patch := controller.Update
+		if patcher, ok := controller.(ResourcePatcher); ok {
+			patch = patcher.Patch
+		}
+		r.Patch(member, patch).Tag(resourceName).Name(resourceName + ".update")
+	}
 
 	// DELETE /recursos/:id (Delete) - eliminar uno
-	r.Delete(prefix+"/:id", controller.Delete)
+	if opts.enabled("delete") {
+		r.Delete(member, controller.Delete).Tag(resourceName).Name(resourceName + ".delete")
+	}
+	return routes
+}
+
+// Member registra una ruta sobre un elemento del recurso, p. ej.
+// Member("POST", "/activate", h) para /users/:id/activate, con nombre
+// users.activate.
+func (rr *ResourceRoutes) Member(method, path string, handler HandlerFunc) *ResourceRoutes {
+	pattern := rr.member + "/" + strings.Trim(path, "/")
+	rr.router.Handle(method, pattern, handler).Tag(rr.name).Name(rr.name + "." + nestedResourceName(path))
+	return rr
+}
+
+// Collection registra una ruta sobre la colección del recurso, p. ej.
+// Collection("GET", "/search", h) para /users/search, con nombre users.search.
+// Se coloca antes de las rutas de miembro para que no la capture /users/:id.
+func (rr *ResourceRoutes) Collection(method, path string, handler HandlerFunc) *ResourceRoutes {
+	pattern := rr.prefix + "/" + strings.Trim(path, "/")
+	rr.router.Handle(method, pattern, handler).Tag(rr.name).Name(rr.name + "." + nestedResourceName(path))
+
+	// mover la ruta recién añadida a su posición, sobre una copia para no
+	// alterar las rutas que recorre ServeHTTP
+	target := rr.router.registry()
+	target.mu.Lock()
+	routes := slices.Clone(target.routes)
+	// buscarla desde el final por método y patrón, por si otra goroutine ha
+	// registrado rutas después; una recarga puede haber quitado anteriores
+	i := len(routes) - 1
+	for i >= 0 && (routes[i].method != method || routes[i].pattern != pattern) {
+		i--
+	}
+	if i >= 0 {
+		added := routes[i]
+		routes = slices.Delete(routes, i, i+1)
+		target.routes = slices.Insert(routes, min(rr.collectionAt, len(routes)), added)
+	}
+	target.mu.Unlock()
+	rr.collectionAt++
+	return rr
+}
+
+// NestedResource registra las rutas REST de un recurso anidado bajo otro,
+// p. ej. "/users/:user_id/posts". Los parámetros del padre llegan en Params y
+// los nombres reflejan el anidamiento ("users.posts.show").
+func (r *MoraRouter) NestedResource(pathPrefix string, controller ResourceController) *ResourceRoutes {
+	return r.NestedResourceWithOptions(pathPrefix, controller, ResourceOptions{})
+}
+
+// NestedResourceWithOptions es NestedResource con patrones personalizados.
+func (r *MoraRouter) NestedResourceWithOptions(pathPrefix string, controller ResourceController, opts ResourceOptions) *ResourceRoutes {
+	if opts.Name == "" {
+		opts.Name = nestedResourceName(pathPrefix)
+	}
+	return r.ResourceWithOptions(pathPrefix, controller, opts)
+}
 
-	// Generar nombres para URL reversal
-	resourceName := filepath.Base(prefix)
-	r.Name(resourceName+".index", prefix)
-	r.Name(resourceName+".show", prefix+"/:id")
-	r.Name(resourceName+".create", prefix)
-	r.Name(resourceName+".update", prefix+"/:id")
-	r.Name(resourceName+".delete", prefix+"/:id")
+// nestedResourceName une los segmentos estáticos del prefijo con puntos,
+// p. ej. "/users/:user_id/posts" -> "users.posts".
+func nestedResourceName(pathPrefix string) string {
+	var parts []string
+	for _, raw := range splitPath(pathPrefix) {
+		if parseSegment(raw).literal != "" {
+			parts = append(parts, raw)
+		}
+	}
+	return strings.Join(parts, ".")
 }
 
 // MacroRegistry almacena las macros disponibles
@@ -953,60 +2831,66 @@ func (r *MoraRouter) UseMacro(prefix, macroName string, handler HandlerFunc) {
 	// Nombrar la ruta para URL reversal
 	if prefix != "" && prefix != "/" {
 		base := filepath.Base(strings.TrimRight(prefix, "/"))
-		r.Name(base+"."+macro.name, path)
+		r.nameRoute(base+"."+macro.name, path, macro.methods...)
 	}
 }
 
-// With aplica middlewares temporalmente a las siguientes operaciones de ruta
+// With devuelve un router cuyas rutas se registran en r envueltas además en
+// middlewares; las que se registren directamente en r no los usan.
 func (r *MoraRouter) With(middlewares ...Middleware) *MoraRouter {
-	// Crear un nuevo router temporal con los mismos datos
-	clone := &MoraRouter{
-		routes:             r.routes,
+	clone := r.clone()
+	clone.withParent, clone.withMiddlewares = r, middlewares
+	if r.withParent != nil {
+		clone.withParent = r.withParent
+		clone.withMiddlewares = append(slices.Clip(r.withMiddlewares), middlewares...)
+	}
+	return clone
+}
+
+// clone devuelve un router con la configuración de r y una copia de sus
+// rutas. Lo que se registre en él no llega a r, salvo en los clones de With.
+func (r *MoraRouter) clone() *MoraRouter {
+	return &MoraRouter{
+		routes:             slices.Clip(r.routesSnapshot()),
 		middlewares:        append([]Middleware{}, r.middlewares...),
 		notFound:           r.notFound,
 		namedRoutes:        r.namedRoutes,
+		routeNames:         r.routeNames,
+		render:             r.render,
+		services:           r.services,
+		validator:          r.validator,
+		validationErrors:   r.validationErrors,
+		securitySchemes:    r.securitySchemes,
+		security:           r.security,
+		recoveryOutermost:  r.recoveryOutermost,
 		mounts:             r.mounts,
 		middlewareRegistry: r.middlewareRegistry,
 		i18n:               r.i18n,
+		openAPI:            r.openAPI,
+		metrics:            r.metrics,
+		cors:               r.cors,
+		versioning:         r.versioning,
+		pathCleaning:       r.pathCleaning,
+		webSockets:         r.webSockets,
+		mu:                 r.mu,
 	}
-
-	// Agregar los middlewares temporales
-	clone.middlewares = append(clone.middlewares, middlewares...)
-
-	return clone
 }
 
-// Use agrega middlewares a un grupo específico
+// Use agrega middlewares al grupo. Solo envuelven las rutas que el grupo
+// registre después, no las de otros grupos ni las del router.
 func (g *RouteGroup) Use(middlewares ...Middleware) *RouteGroup {
-	// Crear una copia del grupo
-	newGroup := &RouteGroup{
-		prefix: g.prefix,
-		router: &MoraRouter{
-			routes:             g.router.routes,
-			middlewares:        append([]Middleware{}, g.router.middlewares...),
-			notFound:           g.router.notFound,
-			namedRoutes:        g.router.namedRoutes,
-			mounts:             g.router.mounts,
-			middlewareRegistry: g.router.middlewareRegistry,
-			i18n:               g.router.i18n,
-		},
-	}
-
-	// Agregar middlewares
-	newGroup.router.middlewares = append(newGroup.router.middlewares, middlewares...)
-
-	return newGroup
+	g.middlewares = append(slices.Clip(g.middlewares), middlewares...)
+	return g
 }
 
 // With aplica middlewares temporales a las siguientes operaciones de ruta en el grupo
 func (g *RouteGroup) With(middlewares ...Middleware) *RouteGroup {
-	// Crear una copia del grupo
-	newGroup := &RouteGroup{
-		prefix: g.prefix,
-		router: g.router.With(middlewares...),
+	return &RouteGroup{
+		prefix:      g.prefix,
+		router:      g.router,
+		global:      g.global,
+		middlewares: append(slices.Clip(g.middlewares), middlewares...),
 	}
-
-	return newGroup
 }
 
 // WebSocket handler is implemented in websocket.go
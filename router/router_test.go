@@ -2,8 +2,18 @@ package router
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestBasicRouting verifica que las rutas básicas funcionen correctamente
@@ -203,3 +213,1172 @@ func TestJSONResponse(t *testing.T) {
 		t.Errorf("Expected user name 'Test User', got '%s'", user.Name)
 	}
 }
+
+// TestAbsoluteURL verifica la generación de URLs absolutas con cabeceras X-Forwarded-*
+func TestAbsoluteURL(t *testing.T) {
+	r := New(WithTrustedProxies("10.0.0.0/8"))
+	r.Name("user.show", "/users/:id")
+
+	// Petición desde un proxy de confianza
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "api.example.com, internal.local")
+
+	url, err := r.AbsoluteURL(req, "user.show", "42")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if url != "https://api.example.com/users/42" {
+		t.Errorf("Expected 'https://api.example.com/users/42', got '%s'", url)
+	}
+
+	// Las cabeceras de un cliente no confiable se ignoran
+	req.RemoteAddr = "203.0.113.9:4567"
+	req.Host = "example.org"
+	url, err = r.AbsoluteURL(req, "user.show", "42")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if url != "http://example.org/users/42" {
+		t.Errorf("Expected 'http://example.org/users/42', got '%s'", url)
+	}
+}
+
+// TestOpenAPISecuritySchemes verifica que la especificación declare y aplique los esquemas de seguridad
+func TestOpenAPISecuritySchemes(t *testing.T) {
+	r := New(
+		WithSecurityScheme("apiKey", map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"}),
+		WithJWT("secret"),
+	)
+	r.Get("/profile", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	r.Get("/reports", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	r.Secure("/reports", "apiKey")
+
+	data, err := json.Marshal(r.BuildOpenAPISpec())
+	if err != nil {
+		t.Fatalf("Error marshaling spec: %v", err)
+	}
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Security []map[string][]string `json:"security"`
+		} `json:"paths"`
+		Components struct {
+			SecuritySchemes map[string]map[string]string `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("Error decoding spec: %v", err)
+	}
+
+	bearer := spec.Components.SecuritySchemes["bearerAuth"]
+	if bearer["type"] != "http" || bearer["scheme"] != "bearer" || bearer["bearerFormat"] != "JWT" {
+		t.Errorf("Expected bearer JWT security scheme, got %v", bearer)
+	}
+	if spec.Components.SecuritySchemes["apiKey"]["in"] != "header" {
+		t.Errorf("Expected apiKey security scheme, got %v", spec.Components.SecuritySchemes)
+	}
+
+	// Las rutas registradas tras WithJWT exigen el bearer
+	security := spec.Paths["/profile"]["get"].Security
+	if len(security) != 1 || security[0]["bearerAuth"] == nil {
+		t.Errorf("Expected /profile to require bearerAuth, got %v", security)
+	}
+	// Secure reemplaza los esquemas de la ruta
+	security = spec.Paths["/reports"]["get"].Security
+	if len(security) != 1 || security[0]["apiKey"] == nil {
+		t.Errorf("Expected /reports to require apiKey, got %v", security)
+	}
+}
+
+// TestOpenAPIBodySchemas verifica que los tipos de Accepts y Returns aparezcan en la especificación
+func TestOpenAPIBodySchemas(t *testing.T) {
+	type Address struct {
+		City string `json:"city" validate:"required"`
+	}
+	type CreateUser struct {
+		Name    string    `json:"name" validate:"required,min=2,max=50"`
+		Email   string    `json:"email,omitempty" validate:"email"`
+		Age     int       `json:"age" validate:"gte=18"`
+		Role    string    `json:"role" validate:"in=admin|user"`
+		Tags    []string  `json:"tags" validate:"max=5"`
+		Address *Address  `json:"address" validate:"required"`
+		Born    time.Time `json:"born"`
+		Secret  string    `json:"-"`
+	}
+	type User struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+
+	noop := func(w http.ResponseWriter, r *http.Request, p Params) {}
+	r := New()
+	HandleJSON(r, http.MethodPost, "/users", func(w http.ResponseWriter, r *http.Request, p Params, u CreateUser) {}).
+		Returns(http.StatusCreated, User{})
+	HandleXML(r.Group("/users"), http.MethodPut, "/:id", func(w http.ResponseWriter, r *http.Request, p Params, u User) {})
+	r.Get("/users", noop).Returns(http.StatusOK, []User{})
+	// el mismo handler en otra ruta no hereda su documentación
+	r.Get("/health", noop)
+
+	data, err := json.Marshal(r.BuildOpenAPISpec())
+	if err != nil {
+		t.Fatalf("Error marshaling spec: %v", err)
+	}
+	type schema struct {
+		Ref        string                     `json:"$ref"`
+		Type       string                     `json:"type"`
+		Format     string                     `json:"format"`
+		Items      *schema                    `json:"items"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	type media map[string]struct {
+		Schema schema `json:"schema"`
+	}
+	var spec struct {
+		Paths map[string]map[string]struct {
+			RequestBody struct {
+				Content media `json:"content"`
+			} `json:"requestBody"`
+			Responses map[string]struct {
+				Content media `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("Error decoding spec: %v", err)
+	}
+
+	post := spec.Paths["/users"]["post"]
+	if ref := post.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/CreateUser" {
+		t.Errorf("Expected CreateUser request body, got '%s'", ref)
+	}
+	if ref := post.Responses["201"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/User" {
+		t.Errorf("Expected User 201 response, got %v", post.Responses)
+	}
+	if ref := spec.Paths["/users/:id"]["put"].RequestBody.Content["application/xml"].Schema.Ref; ref != "#/components/schemas/User" {
+		t.Errorf("Expected User XML request body, got '%s'", ref)
+	}
+	if s := spec.Paths["/users"]["get"].Responses["200"].Content["application/json"].Schema; s.Type != "array" || s.Items == nil || s.Items.Ref != "#/components/schemas/User" {
+		t.Errorf("Expected array of User, got %+v", s)
+	}
+	if s := spec.Paths["/health"]["get"].Responses["200"].Content["application/json"].Schema; s.Type != "object" || s.Items != nil {
+		t.Errorf("Expected the generic response on a route sharing the handler, got %+v", s)
+	}
+
+	create := spec.Components.Schemas["CreateUser"]
+	if strings.Join(create.Required, ",") != "name,address" {
+		t.Errorf("Expected required [name address], got %v", create.Required)
+	}
+	if _, ok := create.Properties["Secret"]; ok {
+		t.Errorf("Expected fields tagged json:\"-\" to be skipped")
+	}
+	for name, want := range map[string]string{
+		"name":    `{"maxLength":50,"minLength":2,"type":"string"}`,
+		"email":   `{"format":"email","type":"string"}`,
+		"age":     `{"minimum":18,"type":"integer"}`,
+		"role":    `{"enum":["admin","user"],"type":"string"}`,
+		"tags":    `{"items":{"type":"string"},"maxItems":5,"type":"array"}`,
+		"address": `{"$ref":"#/components/schemas/Address"}`,
+		"born":    `{"format":"date-time","type":"string"}`,
+	} {
+		if got := string(create.Properties[name]); got != want {
+			t.Errorf("Expected %s schema %s, got %s", name, want, got)
+		}
+	}
+	if address := spec.Components.Schemas["Address"]; len(address.Required) != 1 || address.Required[0] != "city" {
+		t.Errorf("Expected Address component with required city, got %+v", address)
+	}
+}
+
+// TestOpenAPIExamples verifica que los ejemplos de RouteDoc se incluyan en la operación
+func TestOpenAPIExamples(t *testing.T) {
+	type Login struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	r := New()
+	r.Post("/login", BindJSON(func(w http.ResponseWriter, r *http.Request, p Params, l Login) {})).
+		Accepts("application/json", Login{}).
+		Doc("Log in", "").
+		Document(RouteDoc{
+			Example:          Login{Email: "ana@example.com", Password: "secret"},
+			ResponseExamples: map[int]any{http.StatusOK: map[string]string{"token": "abc"}},
+		})
+
+	data, err := json.Marshal(r.BuildOpenAPISpec())
+	if err != nil {
+		t.Fatalf("Error marshaling spec: %v", err)
+	}
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Summary     string `json:"summary"`
+			RequestBody struct {
+				Content map[string]struct {
+					Schema   map[string]string          `json:"schema"`
+					Examples map[string]json.RawMessage `json:"examples"`
+				} `json:"content"`
+			} `json:"requestBody"`
+			Responses map[string]struct {
+				Content map[string]struct {
+					Examples map[string]json.RawMessage `json:"examples"`
+				} `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("Error decoding spec: %v", err)
+	}
+
+	op := spec.Paths["/login"]["post"]
+	if op.Summary != "Log in" {
+		t.Errorf("Expected summary 'Log in', got '%s'", op.Summary)
+	}
+	body := op.RequestBody.Content["application/json"]
+	if body.Schema["$ref"] != "#/components/schemas/Login" {
+		t.Errorf("Expected Login schema next to the example, got %v", body.Schema)
+	}
+	if got := string(body.Examples["default"]); got != `{"value":{"email":"ana@example.com","password":"secret"}}` {
+		t.Errorf("Expected request example, got %s", got)
+	}
+	if got := string(op.Responses["200"].Content["application/json"].Examples["default"]); got != `{"value":{"token":"abc"}}` {
+		t.Errorf("Expected response example, got %s", got)
+	}
+}
+
+// TestOpenAPIQueryParams verifica que los parámetros declarados con Query se documenten junto a los de path
+func TestOpenAPIQueryParams(t *testing.T) {
+	r := New()
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request, p Params) {}).
+		Query("page", "integer", false).
+		Query("q", "string", true)
+	r.Post("/users", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	r.Group("/api").Get("/items/:id", func(w http.ResponseWriter, r *http.Request, p Params) {}).Query("expand", "boolean", false)
+
+	data, err := json.Marshal(r.BuildOpenAPISpec())
+	if err != nil {
+		t.Fatalf("Error marshaling spec: %v", err)
+	}
+	type param struct {
+		Name     string            `json:"name"`
+		In       string            `json:"in"`
+		Required bool              `json:"required"`
+		Schema   map[string]string `json:"schema"`
+	}
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Parameters []param `json:"parameters"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("Error decoding spec: %v", err)
+	}
+
+	params := spec.Paths["/users"]["get"].Parameters
+	if len(params) != 2 {
+		t.Fatalf("Expected 2 query parameters, got %+v", params)
+	}
+	if p := params[0]; p.Name != "page" || p.In != "query" || p.Required || p.Schema["type"] != "integer" {
+		t.Errorf("Expected optional integer 'page', got %+v", p)
+	}
+	if p := params[1]; p.Name != "q" || !p.Required || p.Schema["type"] != "string" {
+		t.Errorf("Expected required string 'q', got %+v", p)
+	}
+	// Solo la ruta del método indicado
+	if params := spec.Paths["/users"]["post"].Parameters; len(params) != 0 {
+		t.Errorf("Expected no parameters for POST /users, got %+v", params)
+	}
+	params = spec.Paths["/api/items/:id"]["get"].Parameters
+	if len(params) != 2 || params[0].In != "path" || params[1].Name != "expand" || params[1].In != "query" {
+		t.Errorf("Expected path 'id' and query 'expand', got %+v", params)
+	}
+}
+
+// TestOpenAPITagsAndSummaries verifica las etiquetas y resúmenes de las operaciones, incluidos los de Resource
+func TestOpenAPITagsAndSummaries(t *testing.T) {
+	r := New()
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request, p Params) {}).
+		Doc("Health check", "Reports whether the service is up").
+		Tag("ops")
+	r.Resource("/products", ProductController{}).
+		Collection("GET", "/search", func(w http.ResponseWriter, r *http.Request, p Params) {})
+
+	data, err := json.Marshal(r.BuildOpenAPISpec())
+	if err != nil {
+		t.Fatalf("Error marshaling spec: %v", err)
+	}
+	type operation struct {
+		Summary     string   `json:"summary"`
+		Description string   `json:"description"`
+		Tags        []string `json:"tags"`
+	}
+	var spec struct {
+		Paths map[string]map[string]operation `json:"paths"`
+		Tags  []struct {
+			Name string `json:"name"`
+		} `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("Error decoding spec: %v", err)
+	}
+
+	health := spec.Paths["/health"]["get"]
+	if health.Summary != "Health check" || health.Description != "Reports whether the service is up" {
+		t.Errorf("Expected summary and description, got %+v", health)
+	}
+	if len(health.Tags) != 1 || health.Tags[0] != "ops" {
+		t.Errorf("Expected tag 'ops', got %v", health.Tags)
+	}
+
+	// Las rutas del recurso llevan su nombre como etiqueta
+	for path, methods := range map[string][]string{
+		"/products":        {"get", "post"},
+		"/products/:id":    {"get", "put", "patch", "delete"},
+		"/products/search": {"get"},
+	} {
+		for _, method := range methods {
+			if tags := spec.Paths[path][method].Tags; len(tags) != 1 || tags[0] != "products" {
+				t.Errorf("Expected tag 'products' for %s %s, got %v", method, path, tags)
+			}
+		}
+	}
+
+	if len(spec.Tags) != 2 || spec.Tags[0].Name != "ops" || spec.Tags[1].Name != "products" {
+		t.Errorf("Expected top-level tags [ops products], got %+v", spec.Tags)
+	}
+}
+
+// TestSwaggerOptions verifica los metadatos configurables y los valores por defecto de la especificación
+func TestSwaggerOptions(t *testing.T) {
+	info := func(r *MoraRouter) map[string]interface{} {
+		return r.BuildOpenAPISpec()["info"].(map[string]interface{})
+	}
+
+	// Sin opciones se mantienen los valores por defecto
+	r := New(WithSwagger())
+	if got := info(r); got["title"] != "API generada con MoraRouter" || got["version"] != "1.0.0" {
+		t.Errorf("Expected default info, got %v", got)
+	}
+	if _, ok := r.BuildOpenAPISpec()["servers"]; ok {
+		t.Error("Expected no servers block by default")
+	}
+
+	r = New(WithSwagger(SwaggerOptions{
+		Title:   "Shop API",
+		Version: "2.1.0",
+		Servers: []OpenAPIServer{
+			{URL: "https://api.example.com", Description: "Production"},
+			{URL: "http://localhost:8080"},
+		},
+	}))
+	got := info(r)
+	if got["title"] != "Shop API" || got["version"] != "2.1.0" {
+		t.Errorf("Expected custom title and version, got %v", got)
+	}
+	if got["description"] != "Documentación automática de la API" {
+		t.Errorf("Expected default description, got %v", got["description"])
+	}
+
+	resp := NewTestClient(r).Get("/openapi.json")
+	var spec struct {
+		Servers []map[string]string `json:"servers"`
+	}
+	if err := json.Unmarshal(resp.Body, &spec); err != nil {
+		t.Fatalf("Error decoding spec: %v", err)
+	}
+	if len(spec.Servers) != 2 || spec.Servers[0]["url"] != "https://api.example.com" ||
+		spec.Servers[0]["description"] != "Production" || spec.Servers[1]["url"] != "http://localhost:8080" {
+		t.Errorf("Expected two servers, got %v", spec.Servers)
+	}
+	if _, ok := spec.Servers[1]["description"]; ok {
+		t.Errorf("Expected no description for second server, got %v", spec.Servers[1])
+	}
+}
+
+// TestSwaggerUI verifica que la página de Swagger UI apunte a la especificación y no cargue recursos externos
+func TestSwaggerUI(t *testing.T) {
+	r := New(WithSwagger(), WithSwaggerUI("/docs"))
+
+	resp := NewTestClient(r).Get("/docs")
+	if !resp.IsOK() || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected HTML page, got %d '%s'", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	body := resp.Text()
+	if !strings.Contains(body, `const specURL = "/openapi.json";`) {
+		t.Errorf("Expected the page to load /openapi.json")
+	}
+	if strings.Contains(body, "http://") || strings.Contains(body, "https://") || strings.Contains(body, "<script src") {
+		t.Errorf("Expected no external assets in the page")
+	}
+	if resp := NewTestClient(r).Get("/openapi.json"); !resp.IsOK() {
+		t.Errorf("Expected the spec endpoint, got %d", resp.StatusCode)
+	}
+
+	// URL de especificación y título propios, escapados
+	r = New(WithSwaggerUIAdvanced(SwaggerUIOptions{Path: "/api/docs", SpecURL: "/api/spec.json", Title: "<Shop> API"}))
+	body = NewTestClient(r).Get("/api/docs").Text()
+	if !strings.Contains(body, `const specURL = "/api/spec.json";`) {
+		t.Errorf("Expected the custom spec URL in the page")
+	}
+	if !strings.Contains(body, "<title>&lt;Shop&gt; API</title>") {
+		t.Errorf("Expected an escaped custom title in the page")
+	}
+}
+
+// TestConcurrentRegistration registra, nombra y documenta rutas mientras se
+// atienden peticiones traducidas con i18n; con -race detecta accesos sin
+// sincronizar
+func TestConcurrentRegistration(t *testing.T) {
+	r := New(WithI18n(map[string]map[string]string{
+		"es": {"/usuarios": "/users"},
+	}))
+	users := r.Get("/users", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte(RouteName(r)))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/usuarios", nil)
+			req.Header.Set("Accept-Language", "es")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", w.Code)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		pattern := "/items/" + strconv.Itoa(i)
+		r.Get(pattern, func(w http.ResponseWriter, r *http.Request, p Params) {}).Tag("items")
+		users.Cache(time.Duration(i+1) * time.Second)
+		r.Name("items."+strconv.Itoa(i), pattern)
+		r.Name("users", "/users")
+		if _, err := r.URL("users"); err != nil {
+			t.Fatalf("Error generating URL: %v", err)
+		}
+	}
+	<-done
+
+	if resp := NewTestClient(r).WithHeader("Accept-Language", "es").Get("/usuarios"); resp.Text() != "users" {
+		t.Errorf("Expected route name 'users', got '%s'", resp.Text())
+	}
+	if resp := NewTestClient(r).Get("/items/199"); !resp.IsOK() {
+		t.Errorf("Expected status 200 for a route registered while serving, got %d", resp.StatusCode)
+	}
+}
+
+// TestRouteAnnotationsCopyOnWrite verifica que Tag, Doc y Cache modifiquen la
+// tabla en su sitio salvo que un lector tenga una instantánea de ella
+func TestRouteAnnotationsCopyOnWrite(t *testing.T) {
+	r := New()
+	users := r.Get("/users", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	before := &r.routes[0]
+	users.Tag("users").Doc("List users", "")
+	if &r.routes[0] != before {
+		t.Errorf("Expected annotations to update the route table in place")
+	}
+
+	snapshot := r.routesSnapshot()
+	users.Cache(time.Minute)
+	if snapshot[0].cacheTTL != 0 {
+		t.Errorf("Expected the snapshot to keep cacheTTL 0, got %v", snapshot[0].cacheTTL)
+	}
+	if r.routes[0].cacheTTL != time.Minute || r.routes[0].summary != "List users" {
+		t.Errorf("Expected the table to keep the annotations, got %+v", r.routes[0])
+	}
+}
+
+// TestCloneRoutesIndependent verifica que las rutas de los clones de With y
+// de los grupos, registradas en paralelo con peticiones, queden en el router
+// original con sus middlewares y sin añadirlos a las demás rutas
+func TestCloneRoutesIndependent(t *testing.T) {
+	r := New()
+	handler := func(name string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			w.Write([]byte(name))
+		}
+	}
+	tag := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request, p Params) {
+				w.Header().Add("X-Middleware", name)
+				next(w, r, p)
+			}
+		}
+	}
+	// tres rutas dejan capacidad libre en el slice (len 3, cap 4)
+	for _, path := range []string{"/a", "/b", "/c"} {
+		r.Get(path, handler(path))
+	}
+	clone := r.With(tag("with"))
+	group := r.Group("/g").Use(tag("group"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/a", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", w.Code)
+				return
+			}
+		}
+	}()
+	clone.Get("/clone", handler("/clone"))
+	clone.With(tag("nested")).Get("/nested", handler("/nested"))
+	group.Get("/group", handler("/g/group"))
+	r.Get("/parent", handler("/parent"))
+	<-done
+
+	for path, want := range map[string]string{
+		"/a":       "",
+		"/clone":   "with",
+		"/nested":  "with,nested",
+		"/g/group": "group",
+		"/parent":  "",
+	} {
+		resp := NewTestClient(r).Get(path)
+		if resp.Text() != path {
+			t.Errorf("Expected '%s', got %d '%s'", path, resp.StatusCode, resp.Text())
+		}
+		if got := strings.Join(resp.Header.Values("X-Middleware"), ","); got != want {
+			t.Errorf("Expected middlewares '%s' for %s, got '%s'", want, path, got)
+		}
+	}
+}
+
+// TestHotReloadReplacesRoutes verifica que cada recarga sustituya las rutas
+// cargadas antes en lugar de duplicarlas, sin tocar las registradas en código
+func TestHotReloadReplacesRoutes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "routes.json")
+	write := func(config string) {
+		if err := os.WriteFile(file, []byte(config), 0o644); err != nil {
+			t.Fatalf("Error writing config: %v", err)
+		}
+	}
+	write(`{"routes": [
+		{"method": "GET", "pattern": "/old", "name": "old"},
+		{"method": "GET", "pattern": "/hello"},
+		{"method": "GET", "pattern": "/items", "group": "api", "name": "items"}
+	], "groups": {"api": "/api"}}`)
+
+	r := New()
+	r.Get("/hello", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte("static"))
+	})
+	hr := NewHotReloader(r, file, time.Hour)
+	for i := 0; i < 3; i++ {
+		if err := hr.ReloadRoutes(); err != nil {
+			t.Fatalf("Error reloading routes: %v", err)
+		}
+	}
+	if n := len(r.routesSnapshot()); n != 4 {
+		t.Errorf("Expected 4 routes after repeated reloads, got %d", n)
+	}
+
+	write(`{"routes": [{"method": "GET", "pattern": "/new", "name": "new"}]}`)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status 200 for /hello during reload, got %d", w.Code)
+				return
+			}
+		}
+	}()
+	if err := hr.ReloadRoutes(); err != nil {
+		t.Fatalf("Error reloading routes: %v", err)
+	}
+	<-done
+
+	client := NewTestClient(r)
+	for path, status := range map[string]int{"/hello": 200, "/new": 200, "/old": 404, "/api/items": 404} {
+		if resp := client.Get(path); resp.StatusCode != status {
+			t.Errorf("Expected status %d for %s, got %d", status, path, resp.StatusCode)
+		}
+	}
+	// La ruta de código con el mismo patrón que una cargada sobrevive a la recarga
+	if resp := client.Get("/hello"); resp.Text() != "static" {
+		t.Errorf("Expected the static /hello handler, got '%s'", resp.Text())
+	}
+	if _, err := r.URL("old"); err == nil {
+		t.Error("Expected name 'old' to be removed")
+	}
+	if u, err := r.URL("new"); err != nil || u != "/new" {
+		t.Errorf("Expected URL '/new', got '%s' (%v)", u, err)
+	}
+}
+
+// TestHotReloadYAML verifica que los archivos YAML se carguen con el
+// decodificador de SetDecoder y que sin él se rechacen
+func TestHotReloadYAML(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "routes.yml")
+	// decodeYAMLTags lee JSON, válido también como YAML
+	config := `{"routes": [
+		{"method": "GET", "pattern": "/users/:id", "name": "users.show", "middleware": ["tag"],
+		 "handler_file": "users.go", "handler_func": "Show"},
+		{"method": "POST", "pattern": "/items", "group": "api", "params": {"id": "\\d+"}}
+	], "groups": {"api": "/api"}}`
+	if err := os.WriteFile(file, []byte(config), 0o644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+
+	r := New()
+	r.registerMiddleware("tag", func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			w.Header().Set("X-Tag", "yes")
+			next(w, r, p)
+		}
+	})
+	hr := NewHotReloader(r, file, time.Hour)
+	if err := hr.ReloadRoutes(); err == nil || !strings.Contains(err.Error(), "SetDecoder") {
+		t.Fatalf("Expected a SetDecoder error for a YAML file without decoder, got %v", err)
+	}
+	hr.SetDecoder(decodeYAMLTags)
+	if err := hr.ReloadRoutes(); err != nil {
+		t.Fatalf("Error reloading routes: %v", err)
+	}
+
+	client := NewTestClient(r)
+	resp := client.Get("/users/7")
+	if !resp.IsOK() || resp.Header.Get("X-Tag") != "yes" {
+		t.Errorf("Expected 200 with X-Tag from middleware, got %d '%s'", resp.StatusCode, resp.Header.Get("X-Tag"))
+	}
+	if resp := client.Post("/api/items", nil); !resp.IsOK() {
+		t.Errorf("Expected status 200 for grouped route, got %d", resp.StatusCode)
+	}
+	if u, err := r.URL("users.show", "7"); err != nil || u != "/users/7" {
+		t.Errorf("Expected URL '/users/7', got '%s' (%v)", u, err)
+	}
+
+	// Los campos con guion bajo llegan aunque el decodificador ignore las etiquetas json
+	var routes RouteCollection
+	if err := decodeYAMLTags([]byte(config), &routes); err != nil {
+		t.Fatalf("Error decoding config: %v", err)
+	}
+	if def := routes.Routes[0]; def.HandlerFile != "users.go" || def.HandlerFunc != "Show" {
+		t.Errorf("Expected handler_file and handler_func to decode, got %+v", def)
+	}
+	if got := routes.Routes[1].Params["id"]; got != `\d+` {
+		t.Errorf("Expected param pattern '\\d+', got '%s'", got)
+	}
+}
+
+// decodeYAMLTags imita a las librerías YAML: asigna los campos por su
+// etiqueta yaml, o por el nombre en minúsculas, e ignora las json
+func decodeYAMLTags(data []byte, v any) error {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return assignYAMLTags(reflect.ValueOf(v).Elem(), doc)
+}
+
+func assignYAMLTags(dst reflect.Value, src any) error {
+	switch dst.Kind() {
+	case reflect.Struct:
+		fields, _ := src.(map[string]any)
+		for i := range dst.NumField() {
+			sf := dst.Type().Field(i)
+			name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+			if name == "" {
+				name = strings.ToLower(sf.Name)
+			}
+			if value, ok := fields[name]; ok {
+				if err := assignYAMLTags(dst.Field(i), value); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice:
+		items, _ := src.([]any)
+		dst.Set(reflect.MakeSlice(dst.Type(), len(items), len(items)))
+		for i, item := range items {
+			if err := assignYAMLTags(dst.Index(i), item); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		entries, _ := src.(map[string]any)
+		dst.Set(reflect.MakeMap(dst.Type()))
+		for key, item := range entries {
+			value := reflect.New(dst.Type().Elem()).Elem()
+			if err := assignYAMLTags(value, item); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(key), value)
+		}
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %T", src)
+		}
+		dst.SetString(s)
+	}
+	return nil
+}
+
+// TestHotReloadWatch verifica que StartWatch recargue al notificarse un cambio,
+// sin esperar al intervalo de sondeo, y que Stop cierre el watcher
+func TestHotReloadWatch(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("notificaciones del sistema de archivos solo en Linux")
+	}
+	file := filepath.Join(t.TempDir(), "routes.json")
+	write := func(pattern string) {
+		config := `{"routes": [{"method": "GET", "pattern": "` + pattern + `"}]}`
+		if err := os.WriteFile(file, []byte(config), 0o644); err != nil {
+			t.Fatalf("Error writing config: %v", err)
+		}
+	}
+
+	r := New()
+	client := NewTestClient(r)
+	waitFor := func(path string) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !client.Get(path).IsOK() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %s to be loaded", path)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	write("/first")
+	// un intervalo de una hora garantiza que las recargas vienen de las notificaciones
+	hr := NewHotReloader(r, file, time.Hour)
+	hr.StartWatch()
+	waitFor("/first")
+
+	write("/second")
+	waitFor("/second")
+
+	// guardar escribiendo otro archivo y renombrándolo encima
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, []byte(`{"routes": [{"method": "GET", "pattern": "/third"}]}`), 0o644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		t.Fatalf("Error renaming config: %v", err)
+	}
+	waitFor("/third")
+	if resp := client.Get("/first"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for replaced route, got %d", resp.StatusCode)
+	}
+
+	hr.Stop()
+	hr.mu.Lock()
+	watcher := hr.watcher
+	hr.mu.Unlock()
+	if watcher != nil {
+		t.Error("Expected watcher to be closed on Stop")
+	}
+}
+
+// TestMergeSlashes verifica el tratamiento de las barras consecutivas con y sin WithMergeSlashes
+func TestMergeSlashes(t *testing.T) {
+	register := func(r *MoraRouter) {
+		r.Get("/users/posts", func(w http.ResponseWriter, r *http.Request, p Params) {
+			w.Write([]byte("posts"))
+		})
+		r.Get("/users/:id/posts", func(w http.ResponseWriter, r *http.Request, p Params) {
+			w.Write([]byte("posts of " + p["id"]))
+		})
+	}
+
+	merged := New(WithMergeSlashes())
+	register(merged)
+	for path, want := range map[string]string{
+		"/users//posts":    "posts",
+		"//users///posts/": "posts",
+		"/users/7//posts":  "posts of 7",
+		"/users/7/posts":   "posts of 7",
+	} {
+		if resp := NewTestClient(merged).Get(path); !resp.IsOK() || resp.Text() != want {
+			t.Errorf("Expected '%s' for %s, got %d '%s'", want, path, resp.StatusCode, resp.Text())
+		}
+	}
+
+	// Sin la opción el segmento vacío no es un valor de :id
+	strict := New()
+	register(strict)
+	for _, path := range []string{"/users//posts", "/users/7//posts"} {
+		if resp := NewTestClient(strict).Get(path); !resp.IsNotFound() {
+			t.Errorf("Expected status 404 for %s, got %d '%s'", path, resp.StatusCode, resp.Text())
+		}
+	}
+}
+
+// TestLowercaseRedirect verifica la redirección de rutas con mayúsculas a su forma canónica
+func TestLowercaseRedirect(t *testing.T) {
+	r := New(WithLowercaseRedirect())
+	r.Get("/users/profile", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte("profile"))
+	})
+	r.Get("/users/:name/posts", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	r.Get("/:lang/about", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	r.Post("/forms/submit", func(w http.ResponseWriter, r *http.Request, p Params) {})
+
+	client := NewTestClient(r)
+
+	resp := client.Get("/Users/Profile")
+	if resp.StatusCode != http.StatusMovedPermanently {
+		t.Fatalf("Expected status 301, got %d", resp.StatusCode)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/users/profile" {
+		t.Errorf("Expected Location '/users/profile', got '%s'", loc)
+	}
+
+	// Los valores de los parámetros conservan sus mayúsculas y la query se mantiene
+	resp = client.Get("/USERS/Ana/Posts?page=2")
+	if loc := resp.Header.Get("Location"); resp.StatusCode != http.StatusMovedPermanently || loc != "/users/Ana/posts?page=2" {
+		t.Errorf("Expected 301 to '/users/Ana/posts?page=2', got %d '%s'", resp.StatusCode, loc)
+	}
+
+	// Location mantiene escapados los valores: "\" y "/" codificados no
+	// pueden convertirse en una redirección a otro host ni perderse
+	for path, want := range map[string]string{
+		"/USERS/%5Cevil.com/Posts": "/users/%5Cevil.com/posts",
+		"/%2F%2Fevil.com/ABOUT":    "/%2F%2Fevil.com/about",
+	} {
+		resp = client.Get(path)
+		if loc := resp.Header.Get("Location"); resp.StatusCode != http.StatusMovedPermanently || loc != want {
+			t.Errorf("Expected 301 to '%s' for %s, got %d '%s'", want, path, resp.StatusCode, loc)
+		}
+	}
+
+	// La forma canónica se sirve sin redirección
+	if resp := client.Get("/users/profile"); !resp.IsOK() || resp.Text() != "profile" {
+		t.Errorf("Expected 'profile', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+
+	// Solo GET y HEAD se redirigen, y sin ruta equivalente sigue siendo 404
+	if resp := client.Post("/Forms/Submit", nil); !resp.IsNotFound() {
+		t.Errorf("Expected status 404 for POST, got %d", resp.StatusCode)
+	}
+	if resp := client.Get("/Missing/Page"); !resp.IsNotFound() {
+		t.Errorf("Expected status 404 for unknown path, got %d", resp.StatusCode)
+	}
+
+	// Sin la opción no hay redirección
+	plain := New()
+	plain.Get("/users/profile", func(w http.ResponseWriter, r *http.Request, p Params) {})
+	if resp := NewTestClient(plain).Get("/Users/Profile"); !resp.IsNotFound() {
+		t.Errorf("Expected status 404 without WithLowercaseRedirect, got %d", resp.StatusCode)
+	}
+}
+
+// TestAcceptLanguageNegotiation verifica la negociación de idioma con
+// calidades, el retroceso a la etiqueta base y el helper Locale
+func TestAcceptLanguageNegotiation(t *testing.T) {
+	r := New(WithI18n(map[string]map[string]string{
+		"es": {"/usuarios": "/users"},
+		"fr": {"/utilisateurs": "/users"},
+	}))
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte(Locale(r)))
+	})
+
+	tests := []struct {
+		header, path, locale string
+		status               int
+	}{
+		// La calidad más alta gana aunque no sea la primera
+		{"es;q=0.5, fr;q=0.9", "/utilisateurs", "fr", http.StatusOK},
+		{"es;q=0.5, fr;q=0.9", "/usuarios", "fr", http.StatusNotFound},
+		// es-MX no existe y retrocede a es
+		{"es-MX, en;q=0.8", "/usuarios", "es", http.StatusOK},
+		// q=0 rechaza el idioma
+		{"fr;q=0, es", "/usuarios", "es", http.StatusOK},
+		// Sin traducción disponible se expone la preferencia del cliente
+		{"de-at, en;q=0.5", "/users", "de-AT", http.StatusOK},
+		{"", "/users", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp := NewTestClient(r).WithHeader("Accept-Language", tt.header).Get(tt.path)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%q %s: Expected status %d, got %d", tt.header, tt.path, tt.status, resp.StatusCode)
+			continue
+		}
+		if tt.status == http.StatusOK && resp.Text() != tt.locale {
+			t.Errorf("%q: Expected locale %q, got %q", tt.header, tt.locale, resp.Text())
+		}
+	}
+
+	if got := parseAcceptLanguage("en;q=0.2, pt-BR, *;q=0.1"); !slices.Equal(got, []string{"pt-br", "en", "*"}) {
+		t.Errorf("Expected languages sorted by quality, got %v", got)
+	}
+}
+
+// TestProxy verifica la reescritura de path, las cabeceras reenviadas y los
+// hooks de Proxy
+func TestProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"path":      r.URL.Path,
+			"query":     r.URL.RawQuery,
+			"host":      r.Host,
+			"forwarded": r.Header.Get("X-Forwarded-For"),
+			"hop":       r.Header.Get("X-Hop"),
+			"director":  r.Header.Get("X-Director"),
+		})
+	}))
+	defer backend.Close()
+
+	r := New()
+	r.Proxy("/api", backend.URL+"/v1")
+	r.Proxy("/fwd", backend.URL, ProxyOptions{
+		ForwardedHeaders: true,
+		PreserveHost:     true,
+		Director: func(req *http.Request) {
+			req.Header.Set("X-Director", "yes")
+		},
+	})
+	var proxyErr error
+	r.Proxy("/down", "http://127.0.0.1:1", ProxyOptions{
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			proxyErr = err
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+	get := func(path string, header http.Header) map[string]string {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer resp.Body.Close()
+		var body map[string]string
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+
+	// El prefijo se elimina y el resto se añade al path del destino; las
+	// cabeceras nombradas en Connection no se reenvían
+	body := get("/api/users/1?x=1", http.Header{"Connection": {"X-Hop"}, "X-Hop": {"secret"}, "X-Forwarded-For": {"6.6.6.6"}})
+	if body["path"] != "/v1/users/1" || body["query"] != "x=1" {
+		t.Errorf("Expected /v1/users/1?x=1, got %s?%s", body["path"], body["query"])
+	}
+	if body["hop"] != "" {
+		t.Errorf("Expected hop-by-hop header to be stripped, got %q", body["hop"])
+	}
+	if body["forwarded"] != "" {
+		t.Errorf("Expected client X-Forwarded-For to be dropped, got %q", body["forwarded"])
+	}
+
+	body = get("/fwd/ping", nil)
+	if body["path"] != "/ping" || body["director"] != "yes" {
+		t.Errorf("Expected /ping with director header, got %v", body)
+	}
+	if body["forwarded"] != "127.0.0.1" {
+		t.Errorf("Expected X-Forwarded-For 127.0.0.1, got %q", body["forwarded"])
+	}
+	if body["host"] != strings.TrimPrefix(server.URL, "http://") {
+		t.Errorf("Expected original host, got %q", body["host"])
+	}
+
+	resp, err := http.Get(server.URL + "/down/x")
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || proxyErr == nil {
+		t.Errorf("Expected custom error handler, got status %d and error %v", resp.StatusCode, proxyErr)
+	}
+}
+
+// TestRoutesInfo verifica la lista pública de rutas y su uso en /_mora/routes
+func TestRoutesInfo(t *testing.T) {
+	r := New(WithSecurityScheme("apiKey", map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"}))
+	noop := func(w http.ResponseWriter, r *http.Request, p Params) {}
+	r.Get("/users", noop)
+	r.Get("/users/:id(\\d+)", noop)
+	r.Put("/orgs/:org/users/:id", noop)
+	r.Get("/files/*path", noop)
+	r.Name("user.show", "/users/:id(\\d+)")
+	r.Secure("/orgs/:org/users/:id", "apiKey")
+
+	want := []RouteInfo{
+		{Method: "GET", Pattern: "/users", Params: []string{}, Segments: []string{"users"}},
+		{Method: "GET", Pattern: "/users/:id(\\d+)", Name: "user.show", Params: []string{"id"}, Segments: []string{"users", ":id(^\\d+$)"}},
+		{Method: "PUT", Pattern: "/orgs/:org/users/:id", Params: []string{"org", "id"}, Segments: []string{"orgs", ":org", "users", ":id"}, Security: []string{"apiKey"}},
+		{Method: "GET", Pattern: "/files/*path", Params: []string{"path"}, Segments: []string{"files", "*path"}},
+	}
+	if got := r.Routes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected routes %+v, got %+v", want, got)
+	}
+
+	// Las rutas de Resource comparten patrón y cada método tiene su nombre
+	res := New()
+	res.Resource("/products", ProductController{})
+	names := make(map[string]string)
+	for _, info := range res.Routes() {
+		names[info.Method+" "+info.Pattern] = info.Name
+	}
+	wantNames := map[string]string{
+		"GET /products":        "products.index",
+		"POST /products":       "products.create",
+		"GET /products/:id":    "products.show",
+		"PUT /products/:id":    "products.update",
+		"PATCH /products/:id":  "products.update",
+		"DELETE /products/:id": "products.delete",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("Expected resource route names %v, got %v", wantNames, names)
+	}
+
+	// El endpoint de depuración usa la misma información, ordenada
+	d := New(WithDebug())
+	d.Get("/users/:id", noop)
+	d.Name("user.show", "/users/:id")
+	var listed []RouteInfo
+	NewTestClient(d).Get("/_mora/routes").AssertStatus(t, http.StatusOK).JSON(&listed)
+	if len(listed) != 3 || listed[2].Pattern != "/users/:id" || listed[2].Name != "user.show" || listed[2].Params[0] != "id" {
+		t.Errorf("Expected /users/:id named user.show in /_mora/routes, got %+v", listed)
+	}
+}
+
+// TestAPIVersioning verifica los modos de WithAPIVersioning y que /ventas no se reescriba
+func TestAPIVersioning(t *testing.T) {
+	r := New(WithAPIVersioning(VersionConfig{
+		Header:       "X-API-Version",
+		Path:         true,
+		AcceptVendor: "application/vnd.api",
+		Default:      "1",
+	}))
+	reply := func(name string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p Params) {
+			w.Write([]byte(name + " " + APIVersion(r) + " " + p["tenant"]))
+		}
+	}
+	r.Get("/v1/users", reply("users-v1"))
+	r.Get("/v2/users", reply("users-v2"))
+	r.Get("/ventas", reply("ventas"))
+	r.Get("/status", reply("status"))
+	r.Get("/:tenant/items", reply("items"))
+
+	tests := []struct {
+		path, header, accept, want string
+	}{
+		{"/users", "", "", "users-v1 1 "},
+		{"/users", "2", "", "users-v2 2 "},
+		{"/users", "v2", "", "users-v2 2 "},
+		{"/users", "", "application/vnd.api.v2+json", "users-v2 2 "},
+		{"/users", "1", "application/vnd.api.v2+json", "users-v1 1 "},
+		{"/users", "../admin", "", "users-v1 1 "},
+		{"/v2/users", "1", "", "users-v2 2 "},
+		{"/v2/status", "", "", "status 2 "},
+		{"/ventas", "", "", "ventas 1 "},
+		{"/ventas", "2", "", "ventas 2 "},
+		{"/acme/items", "2", "", "items 2 acme"},
+	}
+	for _, tc := range tests {
+		client := NewTestClient(r)
+		if tc.header != "" {
+			client.WithHeader("X-API-Version", tc.header)
+		}
+		if tc.accept != "" {
+			client.WithHeader("Accept", tc.accept)
+		}
+		if resp := client.Get(tc.path); resp.StatusCode != http.StatusOK || resp.Text() != tc.want {
+			t.Errorf("Expected '%s' for %s (header %q, accept %q), got %d '%s'", tc.want, tc.path, tc.header, tc.accept, resp.StatusCode, resp.Text())
+		}
+	}
+
+	// Una versión sin ruta propia ni ruta sin versión es un 404
+	if resp := NewTestClient(r).WithHeader("X-API-Version", "3").Get("/users"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown version, got %d", resp.StatusCode)
+	}
+	resp := NewTestClient(r).Get("/users")
+	if vary := resp.Header.Values("Vary"); !slices.Contains(vary, "X-API-Version") || !slices.Contains(vary, "Accept") {
+		t.Errorf("Expected Vary to include X-API-Version and Accept, got %v", vary)
+	}
+
+	// Sin versión ni Default se usa la ruta sin versión y APIVersion es ""
+	r = New(WithAPIVersioning(VersionConfig{Header: "X-API-Version"}))
+	r.Get("/v2/users", reply("users-v2"))
+	r.Get("/users", reply("users"))
+	if resp := NewTestClient(r).Get("/users"); resp.Text() != "users  " {
+		t.Errorf("Expected the unversioned route without a version, got '%s'", resp.Text())
+	}
+}
+
+// TestPathCleaning verifica que WithPathCleaning limpie la ruta antes de los
+// montajes y que redirija a la forma limpia si se pide
+func TestPathCleaning(t *testing.T) {
+	r := New(WithPathCleaning())
+	echo := func(w http.ResponseWriter, r *http.Request, p Params) {
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	}
+	r.Get("/admin", echo)
+	r.Get("/users/:id/posts", echo)
+	r.Post("/users/:id/posts", echo)
+	r.Mount("/public", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("mount " + r.URL.Path))
+	}))
+
+	tests := map[string]string{
+		"//users//7/./posts":       "GET /users/7/posts",
+		"/users/7/x/../posts":      "GET /users/7/posts",
+		"/public/../admin":         "GET /admin",
+		"/public/%2e%2e/admin":     "GET /admin",
+		"/../../admin":             "GET /admin",
+		"/public/css//site.css":    "mount /css/site.css",
+		"/public/img/../logo.png/": "mount /logo.png/",
+	}
+	for target, want := range tests {
+		if resp := NewTestClient(r).Get(target); resp.Text() != want {
+			t.Errorf("Expected '%s' for %s, got %d '%s'", want, target, resp.StatusCode, resp.Text())
+		}
+	}
+
+	// Con Redirect, GET y HEAD reciben un 301 con la query intacta
+	r = New(WithPathCleaning(PathCleaningOptions{Redirect: true}))
+	r.Get("/users/:id/posts", echo)
+	r.Post("/users/:id/posts", echo)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "//users/7/../8//posts?page=2", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/users/8/posts?page=2" {
+		t.Errorf("Expected 301 to '/users/8/posts?page=2', got %d '%s'", w.Code, w.Header().Get("Location"))
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/8/posts", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for a clean path, got %d", w.Code)
+	}
+	// Barras invertidas y "//host" no producen una redirección a otro host
+	for target, want := range map[string]string{
+		"/%5Cevil.com/./":   "/%5Cevil.com/",
+		"//evil.com/./":     "/evil.com/",
+		"/.//evil.com/":     "/evil.com/",
+		"/%5C%5Cevil.com//": "/%5C%5Cevil.com/",
+	} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != want {
+			t.Errorf("Expected 301 to '%s' for %s, got %d '%s'", want, target, w.Code, w.Header().Get("Location"))
+		}
+	}
+	// Los demás métodos se atienden con la ruta limpia
+	if resp := NewTestClient(r).Post("/users/7/./posts", nil); resp.Text() != "POST /users/7/posts" {
+		t.Errorf("Expected 'POST /users/7/posts', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ShutdownTimeout es el tiempo que Serve y ListenAndServe esperan a que
+// terminen las peticiones en curso durante el apagado.
+var ShutdownTimeout = 10 * time.Second
+
+// shutdownSignals son las señales que terminan ListenAndServe.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// ListenAndServe escucha en addr hasta recibir SIGINT o SIGTERM y entonces
+// apaga el servidor de forma ordenada, como Serve. Tras un apagado limpio
+// devuelve nil, así que main puede registrar directamente el error:
+//
+//	if err := r.ListenAndServe(":8080"); err != nil {
+//		log.Fatal(err)
+//	}
+func (r *MoraRouter) ListenAndServe(addr string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+	defer stop()
+	return r.Serve(ctx, &http.Server{Addr: addr})
+}
+
+// Serve atiende peticiones con srv hasta que ctx termina (por ejemplo con
+// signal.NotifyContext). srv permite fijar timeouts, límites o TLS: escucha
+// en srv.Addr, con TLS si srv.TLSConfig trae certificados, y usa el router
+// como handler si srv.Handler es nil.
+//
+// Al apagarse deja de aceptar conexiones, espera a las peticiones en curso
+// hasta ShutdownTimeout y avisa a los clientes WebSocket del router con un
+// mensaje y un cierre 1001 (ver MoraRouter.ShutdownWebSockets), ya que
+// http.Server no sigue las conexiones secuestradas.
+func (r *MoraRouter) Serve(ctx context.Context, srv *http.Server) error {
+	if srv.Handler == nil {
+		srv.Handler = r
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if tls := srv.TLSConfig; tls != nil && (len(tls.Certificates) > 0 || tls.GetCertificate != nil) {
+			serveErr <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	// los WebSocket se cierran a la vez que se esperan las peticiones HTTP
+	wsErr := make(chan error, 1)
+	go func() {
+		wsErr <- r.ShutdownWebSockets(shutdownCtx)
+	}()
+
+	err := srv.Shutdown(shutdownCtx)
+	if serr := <-serveErr; !errors.Is(serr, http.ErrServerClosed) {
+		err = errors.Join(err, serr)
+	}
+	return errors.Join(err, <-wsErr)
+}
@@ -0,0 +1,233 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SSEEvent is a message pushed to Server-Sent Events clients
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// SSEClient represents a connected Server-Sent Events client
+type SSEClient struct {
+	ID      string
+	Request *http.Request
+	Hub     *SSEHub
+	Send    chan SSEEvent
+}
+
+// SSEHub keeps track of the connected Server-Sent Events clients and
+// broadcasts events to them, like WebSocketHub does for WebSocket connections
+type SSEHub struct {
+	// Registered clients, owned by the event loop
+	Clients map[*SSEClient]bool
+
+	// Register requests
+	Register chan *SSEClient
+
+	// Unregister requests
+	Unregister chan *SSEClient
+
+	// OnConnect and OnDisconnect are called from the event loop when a client
+	// joins or leaves the hub
+	OnConnect    func(client *SSEClient)
+	OnDisconnect func(client *SSEClient)
+
+	// Events to send to every client
+	broadcast chan SSEEvent
+
+	// Number of registered clients, readable outside the event loop
+	count atomic.Int64
+
+	// quit asks the event loop to stop; done is closed once every client
+	// has been released
+	quit     chan struct{}
+	done     chan struct{}
+	quitOnce sync.Once
+}
+
+// NewSSEHub creates a new hub; start its event loop with Run
+func NewSSEHub() *SSEHub {
+	return &SSEHub{
+		Clients:    make(map[*SSEClient]bool),
+		Register:   make(chan *SSEClient),
+		Unregister: make(chan *SSEClient),
+		broadcast:  make(chan SSEEvent),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Run starts the hub's event loop
+func (h *SSEHub) Run() {
+	for {
+		select {
+		case <-h.quit:
+			for client := range h.Clients {
+				h.removeClient(client)
+			}
+			close(h.done)
+			return
+
+		case client := <-h.Register:
+			h.Clients[client] = true
+			h.count.Add(1)
+			if h.OnConnect != nil {
+				h.OnConnect(client)
+			}
+
+		case client := <-h.Unregister:
+			if _, ok := h.Clients[client]; ok {
+				h.removeClient(client)
+			}
+
+		case evt := <-h.broadcast:
+			for client := range h.Clients {
+				// Don't block the hub on a slow client
+				select {
+				case client.Send <- evt:
+				default:
+					log.Printf("SSE hub: failed to send to client %s, removing", client.ID)
+					h.removeClient(client)
+				}
+			}
+		}
+	}
+}
+
+// removeClient drops a client from the hub and closes its Send channel,
+// which ends its handler
+func (h *SSEHub) removeClient(client *SSEClient) {
+	delete(h.Clients, client)
+	h.count.Add(-1)
+	if h.OnDisconnect != nil {
+		h.OnDisconnect(client)
+	}
+	close(client.Send)
+}
+
+// Broadcast sends an event with the given name and data to every client.
+// An empty event name sends a plain "message" event
+func (h *SSEHub) Broadcast(event, data string) {
+	h.BroadcastEvent(SSEEvent{Event: event, Data: data})
+}
+
+// BroadcastEvent sends evt to every client
+func (h *SSEHub) BroadcastEvent(evt SSEEvent) {
+	select {
+	case h.broadcast <- evt:
+	case <-h.quit:
+	}
+}
+
+// Count returns the number of connected clients
+func (h *SSEHub) Count() int {
+	return int(h.count.Load())
+}
+
+// Shutdown stops the event loop and ends every client's stream. It returns
+// ctx.Err() if the loop doesn't stop before ctx is done
+func (h *SSEHub) Shutdown(ctx context.Context) error {
+	h.quitOnce.Do(func() {
+		close(h.quit)
+	})
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Handler streams the hub's events to the client until it disconnects or
+// the hub shuts down
+func (h *SSEHub) Handler() HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, p Params) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		client := &SSEClient{
+			ID:      fmt.Sprintf("%d", time.Now().UnixNano()),
+			Request: r,
+			Hub:     h,
+			Send:    make(chan SSEEvent, 64),
+		}
+		select {
+		case h.Register <- client:
+		case <-h.quit:
+			http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case evt, ok := <-client.Send:
+				if !ok {
+					return
+				}
+				if err := writeSSEEvent(w, evt); err != nil {
+					h.unregister(client)
+					return
+				}
+				flusher.Flush()
+
+			case <-r.Context().Done():
+				// The client went away
+				h.unregister(client)
+				return
+			}
+		}
+	}
+}
+
+// unregister hands a client to the event loop for removal, unless the hub
+// is shutting down
+func (h *SSEHub) unregister(client *SSEClient) {
+	select {
+	case h.Unregister <- client:
+	case <-h.quit:
+	}
+}
+
+// SSE serves the events of hub on path
+func (r *MoraRouter) SSE(path string, hub *SSEHub) {
+	r.Get(path, hub.Handler())
+}
+
+// writeSSEEvent writes evt in the text/event-stream format; multi-line data
+// is sent as one "data:" field per line
+func writeSSEEvent(w io.Writer, evt SSEEvent) error {
+	var b strings.Builder
+	if evt.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", evt.ID)
+	}
+	if evt.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", evt.Event)
+	}
+	for _, line := range strings.Split(evt.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
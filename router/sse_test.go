@@ -0,0 +1,95 @@
+package router
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readSSEEvent lee líneas hasta la línea en blanco que termina un evento
+func readSSEEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Error reading event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, line)
+	}
+}
+
+// waitForClients espera a que el hub tenga n clientes
+func waitForClients(t *testing.T, hub *SSEHub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Count() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d clients, got %d", n, hub.Count())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestSSEHubBroadcast verifica que un evento llegue a todos los clientes SSE conectados
+func TestSSEHubBroadcast(t *testing.T) {
+	hub := NewSSEHub()
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+
+	r := New()
+	r.SSE("/events", hub)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	var readers []*bufio.Reader
+	var cancels []context.CancelFunc
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error connecting: %v", err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Expected Content-Type 'text/event-stream', got '%s'", ct)
+		}
+		readers = append(readers, bufio.NewReader(resp.Body))
+		cancels = append(cancels, cancel)
+	}
+	waitForClients(t, hub, 2)
+
+	hub.Broadcast("greeting", "hello\nworld")
+	for i, reader := range readers {
+		if got := readSSEEvent(t, reader); got != "event: greeting\ndata: hello\ndata: world" {
+			t.Errorf("Expected the event on client %d, got %q", i, got)
+		}
+	}
+
+	// Al desconectarse un cliente sale del hub
+	cancels[0]()
+	waitForClients(t, hub, 1)
+
+	hub.BroadcastEvent(SSEEvent{ID: "7", Data: "still here"})
+	if got := readSSEEvent(t, readers[1]); got != "id: 7\ndata: still here" {
+		t.Errorf("Expected the event on the remaining client, got %q", got)
+	}
+
+	// Shutdown termina los streams abiertos
+	if err := hub.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Error shutting down: %v", err)
+	}
+	if _, err := readers[1].ReadString('\n'); err == nil {
+		t.Errorf("Expected the stream to end after Shutdown")
+	}
+}
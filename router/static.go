@@ -1,9 +1,21 @@
 package router
 
 import (
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
 	"net/http"
+	"net/url"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // StaticOptions contains configuration for static file serving
@@ -12,6 +24,8 @@ type StaticOptions struct {
 	URLPrefix string
 	// The filesystem directory to serve files from
 	Directory string
+	// Filesystem to serve files from instead of Directory (e.g. an embed.FS)
+	FS fs.FS
 	// Cache control header value (e.g., "max-age=3600")
 	CacheControl string
 	// List of file extensions to compress if browser supports it
@@ -20,6 +34,8 @@ type StaticOptions struct {
 	DirectoryListing bool
 	// Whether to set Content-Type headers based on file extensions
 	SetContentType bool
+	// Whether to serve a precompressed <file>.gz sibling to clients accepting gzip
+	ServePrecompressed bool
 }
 
 // StaticFilesOption adds middleware to serve static files from a directory
@@ -38,10 +54,27 @@ func StaticFilesOption(urlPrefix, dir string) Option {
 // WithStaticFiles is an alias to StaticFilesOption for backward compatibility
 var WithStaticFiles = StaticFilesOption
 
+// WithStaticFS serves static files from fsys, such as an embed.FS, with the
+// same defaults as StaticFilesOption
+func WithStaticFS(urlPrefix string, fsys fs.FS) Option {
+	return WithStaticFilesAdvanced(StaticOptions{
+		URLPrefix:      urlPrefix,
+		FS:             fsys,
+		SetContentType: true,
+		CacheControl:   "max-age=86400", // Default cache of 24 hours
+		CompressExtensions: []string{
+			".html", ".css", ".js", ".json", ".txt", ".xml", ".svg",
+		},
+	})
+}
+
 // WithStaticFilesAdvanced adds middleware to serve static files with advanced options
 func WithStaticFilesAdvanced(options StaticOptions) Option {
 	return func(r *MoraRouter) {
-		fileServer := http.FileServer(http.Dir(options.Directory))
+		var fsrv http.FileSystem = http.Dir(options.Directory)
+		if options.FS != nil {
+			fsrv = http.FS(options.FS)
+		}
 
 		// Ensure prefix starts with /
 		if !strings.HasPrefix(options.URLPrefix, "/") {
@@ -53,16 +86,52 @@ func WithStaticFilesAdvanced(options StaticOptions) Option {
 			options.URLPrefix += "/"
 		}
 
-		// Strip the URL prefix when serving files
-		handler := http.StripPrefix(options.URLPrefix, fileServer)
+		handler := func(w http.ResponseWriter, req *http.Request, p Params) {
+			name := "/" + p["path"]
 
-		// Register the handler for GET and HEAD requests
-		r.Get(options.URLPrefix+"*path", func(w http.ResponseWriter, req *http.Request, p Params) {
-			path := p["path"]
+			// Reject any ".." segment before touching the filesystem
+			if containsDotDot(name) {
+				http.NotFound(w, req)
+				return
+			}
+
+			// http.Dir and http.FS reject paths escaping the filesystem
+			f, err := fsrv.Open(name)
+			if err != nil {
+				http.NotFound(w, req)
+				return
+			}
+			defer f.Close()
+
+			stat, err := f.Stat()
+			if err != nil {
+				http.NotFound(w, req)
+				return
+			}
+
+			// Serve a directory's index.html, or its listing if enabled
+			if stat.IsDir() {
+				indexName := filepath.ToSlash(filepath.Join(name, "index.html"))
+				index, err := fsrv.Open(indexName)
+				if err != nil {
+					if options.DirectoryListing {
+						serveDirectoryListing(w, req, f, options.URLPrefix, name)
+						return
+					}
+					http.NotFound(w, req)
+					return
+				}
+				defer index.Close()
+				if stat, err = index.Stat(); err != nil || stat.IsDir() {
+					http.NotFound(w, req)
+					return
+				}
+				f, name = index, indexName
+			}
 
 			// Handle content type if enabled
 			if options.SetContentType {
-				ext := filepath.Ext(path)
+				ext := filepath.Ext(stat.Name())
 				switch ext {
 				case ".css":
 					w.Header().Set("Content-Type", "text/css")
@@ -81,42 +150,173 @@ func WithStaticFilesAdvanced(options StaticOptions) Option {
 				w.Header().Set("Cache-Control", options.CacheControl)
 			}
 
-			// Serve the file using the standard file server
-			handler.ServeHTTP(w, req)
-		})
+			// Serve the .gz sibling instead if there is one, with the content
+			// type of the original file so ServeContent does not sniff gzip
+			content, contentStat := f, stat
+			if options.ServePrecompressed {
+				w.Header().Add("Vary", "Accept-Encoding")
+				if acceptsGzip(req) {
+					if gz, err := fsrv.Open(name + ".gz"); err == nil {
+						defer gz.Close()
+						if gzStat, err := gz.Stat(); err == nil && !gzStat.IsDir() {
+							if w.Header().Get("Content-Type") == "" {
+								w.Header().Set("Content-Type", originalContentType(f, stat.Name()))
+							}
+							w.Header().Set("Content-Encoding", "gzip")
+							content, contentStat = gz, gzStat
+						}
+					}
+				}
+			}
+
+			// ServeContent only validates ETags it is given, so derive a weak
+			// one from the file's size and modification time
+			w.Header().Set("ETag", fmt.Sprintf(`W/"%x-%x"`, contentStat.Size(), contentStat.ModTime().UnixNano()))
+
+			// ServeContent handles Range, If-Range, If-None-Match and
+			// If-Modified-Since
+			http.ServeContent(w, req, stat.Name(), contentStat.ModTime(), content)
+		}
+
+		// Register the handler for GET and HEAD requests
+		r.Get(options.URLPrefix+"*path", handler)
+		r.Handle(http.MethodHead, options.URLPrefix+"*path", handler)
+	}
+}
+
+// originalContentType returns the content type of the uncompressed file,
+// from its extension or, failing that, by sniffing its first 512 bytes
+func originalContentType(f http.File, name string) string {
+	if ctype := mime.TypeByExtension(filepath.Ext(name)); ctype != "" {
+		return ctype
+	}
+	var buf [512]byte
+	n, _ := io.ReadFull(f, buf[:])
+	return http.DetectContentType(buf[:n])
+}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip
+func acceptsGzip(req *http.Request) bool {
+	for _, part := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") && strings.TrimSpace(coding) != "*" {
+			continue
+		}
+		// "q=0" means the coding is not acceptable
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// containsDotDot reports whether any segment of the slash-separated path is ".."
+func containsDotDot(name string) bool {
+	return slices.Contains(strings.Split(name, "/"), "..")
+}
+
+// serveDirectoryListing writes an HTML index of dir, sorted by the sort
+// (name, size or modified) and order (asc or desc) query parameters.
+// Directories are listed before files.
+func serveDirectoryListing(w http.ResponseWriter, req *http.Request, dir http.File, urlPrefix, name string) {
+	entries, err := dir.Readdir(-1)
+	if err != nil {
+		http.Error(w, "Error reading directory", http.StatusInternalServerError)
+		return
+	}
+
+	query := req.URL.Query()
+	less := func(a, b fs.FileInfo) bool { return a.Name() < b.Name() }
+	switch query.Get("sort") {
+	case "size":
+		less = func(a, b fs.FileInfo) bool { return a.Size() < b.Size() }
+	case "modified":
+		less = func(a, b fs.FileInfo) bool { return a.ModTime().Before(b.ModTime()) }
+	}
+	desc := query.Get("order") == "desc"
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		if desc {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+
+	// Links are absolute so they work with or without a trailing slash
+	base := strings.TrimSuffix(urlPrefix, "/") + strings.TrimSuffix(name, "/") + "/"
+	title := html.EscapeString(strings.TrimSuffix(name, "/") + "/")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head><title>Index of %s</title></head>\n<body>\n", title)
+	fmt.Fprintf(&b, "<h1>Index of %s</h1>\n<table>\n", title)
+	fmt.Fprintf(&b, "<tr><th><a href=\"?sort=name\">Name</a></th><th><a href=\"?sort=size\">Size</a></th><th><a href=\"?sort=modified\">Modified</a></th></tr>\n")
+	if strings.Trim(name, "/") != "" {
+		parent := path.Dir(strings.TrimSuffix(base, "/")) + "/"
+		fmt.Fprintf(&b, "<tr><td><a href=\"%s\">../</a></td><td></td><td></td></tr>\n", html.EscapeString(parent))
+	}
+	for _, entry := range entries {
+		display, size := entry.Name(), strconv.FormatInt(entry.Size(), 10)
+		href := base + url.PathEscape(entry.Name())
+		if entry.IsDir() {
+			display += "/"
+			href += "/"
+			size = "-"
+		}
+		fmt.Fprintf(&b, "<tr><td><a href=\"%s\">%s</a></td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(href), html.EscapeString(display), size, entry.ModTime().UTC().Format(time.RFC3339))
+	}
+	b.WriteString("</table>\n</body>\n</html>\n")
+
+	if req.Method != http.MethodHead {
+		io.WriteString(w, b.String())
 	}
 }
 
 // SPA serves a single-page app with client-side routing support
 func WithSPA(urlPrefix, dir string, indexFile string) Option {
-	if indexFile == "" {
-		indexFile = "index.html"
-	}
+	return WithSPAFS(urlPrefix, os.DirFS(dir), indexFile)
+}
 
+// WithSPAFS serves a single-page app from fsys, such as an embed.FS, with
+// client-side routing support
+func WithSPAFS(urlPrefix string, fsys fs.FS, indexFile string) Option {
 	return func(r *MoraRouter) {
-		fs := http.Dir(dir)
-		fileServer := http.FileServer(fs)
-
 		// Ensure prefix starts with /
 		if !strings.HasPrefix(urlPrefix, "/") {
 			urlPrefix = "/" + urlPrefix
 		}
 		// Serve the main route and any sub-route
-		r.Get(urlPrefix+"*path", func(w http.ResponseWriter, req *http.Request, p Params) {
-			path := p["path"]
-
-			// First check if the file exists
-			if path != "" {
-				if _, err := fs.Open(path); err == nil {
-					// File exists, serve it directly
-					fileServer.ServeHTTP(w, req)
-					return
-				}
+		r.Get(urlPrefix+"*path", spaHandler(fsys, indexFile))
+	}
+}
+
+// spaHandler serves the file named by the path parameter if it exists in
+// fsys, and indexFile otherwise
+func spaHandler(fsys fs.FS, indexFile string) HandlerFunc {
+	if indexFile == "" {
+		indexFile = "index.html"
+	}
+
+	return func(w http.ResponseWriter, req *http.Request, p Params) {
+		name := path.Clean(p["path"])
+
+		// First check if the file exists; directories fall back to the index
+		if fs.ValidPath(name) && name != "." {
+			if stat, err := fs.Stat(fsys, name); err == nil && !stat.IsDir() {
+				// File exists, serve it directly
+				http.ServeFileFS(w, req, fsys, name)
+				return
 			}
+		}
 
-			// File doesn't exist, fallback to index.html
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			http.ServeFile(w, req, filepath.Join(dir, indexFile))
-		})
+		// File doesn't exist, fallback to index.html
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeFileFS(w, req, fsys, indexFile)
 	}
 }
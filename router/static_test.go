@@ -0,0 +1,263 @@
+package router
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestStaticFilesRange verifica las peticiones parciales y condicionales sobre archivos estáticos
+func TestStaticFilesRange(t *testing.T) {
+	dir := t.TempDir()
+	content := make([]byte, 1000)
+	for i := range content {
+		content[i] = byte('a' + i%26)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "clip.mp4"), content, 0o644); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+
+	r := New(WithStaticFiles("/static", dir))
+	client := NewTestClient(r)
+
+	// Rango de bytes: 206 con Content-Range
+	resp := client.WithHeader("Range", "bytes=100-199").Get("/static/clip.mp4")
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("Expected status 206, got %d", resp.StatusCode)
+	}
+	if cr := resp.Header.Get("Content-Range"); cr != "bytes 100-199/1000" {
+		t.Errorf("Expected Content-Range 'bytes 100-199/1000', got '%s'", cr)
+	}
+	if !bytes.Equal(resp.Body, content[100:200]) {
+		t.Errorf("Expected bytes 100-199, got %d bytes", len(resp.Body))
+	}
+
+	// Petición completa con validadores
+	resp = NewTestClient(r).Get("/static/clip.mp4")
+	if !resp.IsOK() || len(resp.Body) != len(content) {
+		t.Fatalf("Expected full file, got %d with %d bytes", resp.StatusCode, len(resp.Body))
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" || resp.Header.Get("Last-Modified") == "" || resp.Header.Get("Accept-Ranges") != "bytes" {
+		t.Errorf("Expected ETag, Last-Modified and Accept-Ranges, got %v", resp.Header)
+	}
+
+	// Revalidación con el ETag recibido
+	resp = NewTestClient(r).WithHeader("If-None-Match", etag).Get("/static/clip.mp4")
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected status 304 for matching ETag, got %d", resp.StatusCode)
+	}
+
+	// HEAD también está registrado
+	resp = NewTestClient(r).exec(httptest.NewRequest(http.MethodHead, "/static/clip.mp4", nil))
+	if !resp.IsOK() || len(resp.Body) != 0 {
+		t.Errorf("Expected empty 200 for HEAD, got %d with %d bytes", resp.StatusCode, len(resp.Body))
+	}
+
+	if resp := NewTestClient(r).Get("/static/missing.mp4"); !resp.IsNotFound() {
+		t.Errorf("Expected status 404 for missing file, got %d", resp.StatusCode)
+	}
+}
+
+// TestStaticFS verifica que los archivos estáticos y la SPA se sirven desde un fs.FS
+func TestStaticFS(t *testing.T) {
+	assets := fstest.MapFS{
+		"index.html":   {Data: []byte("<html>app</html>")},
+		"css/site.css": {Data: []byte("body{}")},
+	}
+
+	r := New(WithStaticFS("/static", assets), WithSPAFS("/app/", assets, ""))
+	r.StaticFS("/assets", assets)
+	r.SPAFS("/web", assets, "index.html")
+
+	resp := NewTestClient(r).Get("/static/css/site.css")
+	if !resp.IsOK() || resp.Text() != "body{}" {
+		t.Errorf("Expected 'body{}', got %d '%s'", resp.StatusCode, resp.Text())
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/css" {
+		t.Errorf("Expected Content-Type 'text/css', got '%s'", ct)
+	}
+	if resp := NewTestClient(r).Get("/static/missing.css"); !resp.IsNotFound() {
+		t.Errorf("Expected status 404 for missing file, got %d", resp.StatusCode)
+	}
+
+	if resp := NewTestClient(r).Get("/assets/css/site.css"); resp.Text() != "body{}" {
+		t.Errorf("Expected 'body{}' from StaticFS, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+
+	for _, prefix := range []string{"/app", "/web"} {
+		// Los archivos existentes se sirven tal cual
+		if resp := NewTestClient(r).Get(prefix + "/css/site.css"); resp.Text() != "body{}" {
+			t.Errorf("Expected 'body{}' from %s, got %d '%s'", prefix, resp.StatusCode, resp.Text())
+		}
+		// Las rutas del cliente y los directorios caen en el index
+		for _, path := range []string{"/users/42", "/css"} {
+			resp := NewTestClient(r).Get(prefix + path)
+			if !resp.IsOK() || resp.Text() != "<html>app</html>" {
+				t.Errorf("Expected index for %s%s, got %d '%s'", prefix, path, resp.StatusCode, resp.Text())
+			}
+		}
+	}
+}
+
+// TestStaticDirectoryListing verifica el listado HTML de directorios sin index
+func TestStaticDirectoryListing(t *testing.T) {
+	assets := fstest.MapFS{
+		"docs/b.txt":                            {Data: []byte("bb")},
+		"docs/a.txt":                            {Data: []byte("aaaa")},
+		"docs/<img src=x onerror=alert(1)>.txt": {Data: []byte("x")},
+		"docs/img/logo.svg":                     {Data: []byte("<svg/>")},
+		"site/index.html":                       {Data: []byte("home")},
+	}
+
+	r := New(WithStaticFilesAdvanced(StaticOptions{URLPrefix: "/files", FS: assets, DirectoryListing: true}))
+
+	resp := NewTestClient(r).Get("/files/docs")
+	if !resp.IsOK() {
+		t.Fatalf("Expected status 200 for listing, got %d", resp.StatusCode)
+	}
+	body := resp.Text()
+	if strings.Contains(body, "<img") || !strings.Contains(body, "&lt;img src=x onerror=alert(1)&gt;.txt") {
+		t.Errorf("Expected escaped file name in listing, got:\n%s", body)
+	}
+	// Directorios primero y después archivos por nombre
+	img, a, b := strings.Index(body, `href="/files/docs/img/"`), strings.Index(body, `href="/files/docs/a.txt"`), strings.Index(body, `href="/files/docs/b.txt"`)
+	if img < 0 || a < 0 || b < 0 || !(img < a && a < b) {
+		t.Errorf("Expected img/, a.txt, b.txt in order, got:\n%s", body)
+	}
+	if !strings.Contains(body, `href="/files/"`) {
+		t.Errorf("Expected a parent link, got:\n%s", body)
+	}
+
+	// Orden por tamaño descendente
+	body = NewTestClient(r).Get("/files/docs/?sort=size&order=desc").Text()
+	if a, b := strings.Index(body, "a.txt"), strings.Index(body, "b.txt"); a < 0 || b < 0 || a > b {
+		t.Errorf("Expected a.txt before b.txt by size desc, got:\n%s", body)
+	}
+
+	// Un index.html tiene prioridad sobre el listado
+	if resp := NewTestClient(r).Get("/files/site/"); resp.Text() != "home" {
+		t.Errorf("Expected index.html, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+
+	// ".." no puede salir de la raíz
+	if resp := NewTestClient(r).Get("/files/docs/../../etc"); !resp.IsNotFound() {
+		t.Errorf("Expected status 404 for path traversal, got %d", resp.StatusCode)
+	}
+
+	// Sin DirectoryListing los directorios sin index devuelven 404
+	r = New(WithStaticFS("/files", assets))
+	if resp := NewTestClient(r).Get("/files/docs"); !resp.IsNotFound() {
+		t.Errorf("Expected status 404 without listing, got %d", resp.StatusCode)
+	}
+}
+
+// TestStaticPrecompressed verifica que se sirva el .gz hermano cuando el cliente acepta gzip
+func TestStaticPrecompressed(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("console.log('app')"))
+	zw.Close()
+
+	var license bytes.Buffer
+	zw = gzip.NewWriter(&license)
+	zw.Write([]byte("MIT License\n"))
+	zw.Close()
+
+	assets := fstest.MapFS{
+		"app.js":     {Data: []byte("console.log('app')")},
+		"app.js.gz":  {Data: gz.Bytes()},
+		"site.css":   {Data: []byte("body{}")},
+		"LICENSE":    {Data: []byte("MIT License\n")},
+		"LICENSE.gz": {Data: license.Bytes()},
+	}
+	r := New(WithStaticFilesAdvanced(StaticOptions{URLPrefix: "/static", FS: assets, ServePrecompressed: true}))
+
+	resp := NewTestClient(r).WithHeader("Accept-Encoding", "br, gzip").Get("/static/app.js")
+	if !resp.IsOK() || resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip-encoded 200, got %d with encoding '%s'", resp.StatusCode, resp.Header.Get("Content-Encoding"))
+	}
+	if !bytes.Equal(resp.Body, gz.Bytes()) {
+		t.Errorf("Expected the .gz file contents")
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.Contains(ct, "javascript") {
+		t.Errorf("Expected the original JavaScript content type, got '%s'", ct)
+	}
+	if vary := resp.Header.Get("Vary"); vary != "Accept-Encoding" {
+		t.Errorf("Expected Vary 'Accept-Encoding', got '%s'", vary)
+	}
+
+	// Sin extensión conocida el tipo se detecta en el original, no en el .gz
+	resp = NewTestClient(r).WithHeader("Accept-Encoding", "gzip").Get("/static/LICENSE")
+	if ct := resp.Header.Get("Content-Type"); resp.Header.Get("Content-Encoding") != "gzip" || !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected gzip-encoded text/plain for LICENSE, got '%s' encoded as '%s'", ct, resp.Header.Get("Content-Encoding"))
+	}
+
+	// Sin gzip aceptado, o sin .gz, se sirve el archivo original
+	for _, tc := range []struct{ path, encoding, body string }{
+		{"/static/app.js", "", "console.log('app')"},
+		{"/static/app.js", "gzip;q=0", "console.log('app')"},
+		{"/static/site.css", "gzip", "body{}"},
+	} {
+		resp := NewTestClient(r).WithHeader("Accept-Encoding", tc.encoding).Get(tc.path)
+		if resp.Header.Get("Content-Encoding") != "" || resp.Text() != tc.body {
+			t.Errorf("Expected uncompressed '%s' for %s with Accept-Encoding '%s', got '%s' encoded as '%s'",
+				tc.body, tc.path, tc.encoding, resp.Text(), resp.Header.Get("Content-Encoding"))
+		}
+	}
+}
+
+// TestFile verifica que File sirva un único archivo con su tipo y cabeceras de caché
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	icon := []byte{0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x10, 0x10}
+	if err := os.WriteFile(filepath.Join(dir, "favicon.ico"), icon, 0o644); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "robots.txt"), []byte("User-agent: *\n"), 0o644); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+
+	r := New()
+	r.File("/favicon.ico", filepath.Join(dir, "favicon.ico"))
+	r.File("/robots.txt", filepath.Join(dir, "robots.txt"))
+	r.File("/missing.html", filepath.Join(dir, "missing.html"))
+
+	resp := NewTestClient(r).Get("/favicon.ico")
+	if !resp.IsOK() || !bytes.Equal(resp.Body, icon) {
+		t.Fatalf("Expected the icon bytes, got %d with %d bytes", resp.StatusCode, len(resp.Body))
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "image/x-icon" && ct != "image/vnd.microsoft.icon" {
+		t.Errorf("Expected an icon Content-Type, got '%s'", ct)
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "max-age=86400" {
+		t.Errorf("Expected Cache-Control 'max-age=86400', got '%s'", cc)
+	}
+
+	// Revalidación con el ETag recibido
+	etag := resp.Header.Get("ETag")
+	if resp := NewTestClient(r).WithHeader("If-None-Match", etag).Get("/favicon.ico"); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected status 304 for matching ETag, got %d", resp.StatusCode)
+	}
+
+	resp = NewTestClient(r).Get("/robots.txt")
+	if resp.Text() != "User-agent: *\n" || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected robots.txt as text/plain, got '%s' '%s'", resp.Header.Get("Content-Type"), resp.Text())
+	}
+
+	// HEAD también está registrado
+	resp = NewTestClient(r).exec(httptest.NewRequest(http.MethodHead, "/favicon.ico", nil))
+	if !resp.IsOK() || len(resp.Body) != 0 {
+		t.Errorf("Expected empty 200 for HEAD, got %d with %d bytes", resp.StatusCode, len(resp.Body))
+	}
+
+	if resp := NewTestClient(r).Get("/missing.html"); !resp.IsNotFound() {
+		t.Errorf("Expected status 404 for a missing file, got %d", resp.StatusCode)
+	}
+}
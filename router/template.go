@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -29,6 +31,12 @@ type TemplateManager struct {
 	errorHandler func(error)
 	disableCache bool
 	development  bool
+	translations *Translations
+
+	// modTimes and sharedModTimes record the files parsed by the last load
+	// so development renders only reparse what changed
+	modTimes       map[string]time.Time
+	sharedModTimes map[string]time.Time
 }
 
 // NewTemplateManager creates a new template manager for the given directory
@@ -72,6 +80,14 @@ func (tm *TemplateManager) WithFuncs(funcMap template.FuncMap) *TemplateManager
 	return tm
 }
 
+// WithTranslations registers the t, tf and tn template functions backed by
+// bundle. Templates rendered through RenderTemplateView resolve messages in
+// the request's negotiated locale; other renders use the bundle's fallback.
+func (tm *TemplateManager) WithTranslations(bundle *Translations) *TemplateManager {
+	tm.translations = bundle
+	return tm.WithFuncs(bundle.Funcs(bundle.Fallback))
+}
+
 // WithCSS adds a CSS file to be available as a function in templates
 func (tm *TemplateManager) WithCSS(name, path string) *TemplateManager {
 	tm.cssMap[name] = path
@@ -109,12 +125,90 @@ func (tm *TemplateManager) Reload() {
 
 	// Clear existing templates
 	tm.templates = make(map[string]*template.Template)
+	tm.modTimes = make(map[string]time.Time)
+	tm.sharedModTimes = tm.statShared()
 
 	// Create base function map with asset helpers
 	funcMap := tm.createFuncMap()
 
 	// Find all template files
-	err := filepath.Walk(tm.directory, func(path string, info os.FileInfo, err error) error {
+	err := tm.walkTemplates(func(path, relPath string, info os.FileInfo) error {
+		tmpl, err := tm.parseTemplate(path, relPath, funcMap)
+		if err != nil {
+			return err
+		}
+
+		// Store the template
+		tm.templates[relPath] = tmpl
+		tm.modTimes[path] = info.ModTime()
+		return nil
+	})
+
+	if err != nil {
+		tm.errorHandler(fmt.Errorf("error loading templates: %w", err))
+	}
+}
+
+// reloadChanged reparses only the templates whose files changed since the
+// last load. Changes to the layout or partials, and added or removed
+// templates, fall back to a full Reload.
+func (tm *TemplateManager) reloadChanged() {
+	tm.mutex.RLock()
+	known, shared := tm.modTimes, tm.sharedModTimes
+	tm.mutex.RUnlock()
+	if known == nil || !maps.Equal(shared, tm.statShared()) {
+		tm.Reload()
+		return
+	}
+
+	type changedFile struct {
+		path, relPath string
+		modTime       time.Time
+	}
+	var changed []changedFile
+	seen := 0
+	err := tm.walkTemplates(func(path, relPath string, info os.FileInfo) error {
+		modTime, ok := known[path]
+		if !ok {
+			return errTemplatesChanged
+		}
+		seen++
+		if !modTime.Equal(info.ModTime()) {
+			changed = append(changed, changedFile{path, relPath, info.ModTime()})
+		}
+		return nil
+	})
+	if err != nil || seen != len(known) {
+		tm.Reload()
+		return
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	funcMap := tm.createFuncMap()
+	for _, file := range changed {
+		tmpl, err := tm.parseTemplate(file.path, file.relPath, funcMap)
+		if err != nil {
+			// Keep serving the previous version; the stale modTime makes the
+			// next render retry the parse
+			tm.errorHandler(err)
+			continue
+		}
+		tm.templates[file.relPath] = tmpl
+		tm.modTimes[file.path] = file.modTime
+	}
+}
+
+// errTemplatesChanged stops a walk as soon as a new template file appears
+var errTemplatesChanged = errors.New("template set changed")
+
+// walkTemplates calls fn for every template file, skipping the layout and
+// partials
+func (tm *TemplateManager) walkTemplates(fn func(path, relPath string, info os.FileInfo) error) error {
+	return filepath.Walk(tm.directory, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -139,58 +233,67 @@ func (tm *TemplateManager) Reload() {
 		if err != nil {
 			return err
 		}
+		return fn(path, relPath, info)
+	})
+}
 
-		// Create template with functions
-		var tmpl *template.Template
+// statShared returns the modification times of the layout and partials
+func (tm *TemplateManager) statShared() map[string]time.Time {
+	shared := make(map[string]time.Time)
+	files := tm.partials
+	if tm.layout != "" {
+		files = append([]string{tm.layout}, files...)
+	}
+	for _, file := range files {
+		if info, err := os.Stat(filepath.Join(tm.directory, file)); err == nil {
+			shared[file] = info.ModTime()
+		}
+	}
+	return shared
+}
 
-		// Start with base template
-		tmpl = template.New(filepath.Base(path)).Funcs(funcMap)
+// parseTemplate parses a template file together with the layout and partials
+func (tm *TemplateManager) parseTemplate(path, relPath string, funcMap template.FuncMap) (*template.Template, error) {
+	// Start with base template
+	tmpl := template.New(filepath.Base(path)).Funcs(funcMap)
 
-		// Add layout if specified
-		if tm.layout != "" {
-			layoutPath := filepath.Join(tm.directory, tm.layout)
-			layoutContent, err := os.ReadFile(layoutPath)
-			if err != nil {
-				return fmt.Errorf("error reading layout %s: %w", tm.layout, err)
-			}
-			tmpl, err = tmpl.Parse(string(layoutContent))
-			if err != nil {
-				return fmt.Errorf("error parsing layout %s: %w", tm.layout, err)
-			}
+	// Add layout if specified
+	if tm.layout != "" {
+		layoutPath := filepath.Join(tm.directory, tm.layout)
+		layoutContent, err := os.ReadFile(layoutPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading layout %s: %w", tm.layout, err)
 		}
-
-		// Add partials
-		for _, partial := range tm.partials {
-			partialPath := filepath.Join(tm.directory, partial)
-			partialContent, err := os.ReadFile(partialPath)
-			if err != nil {
-				return fmt.Errorf("error reading partial %s: %w", partial, err)
-			}
-			tmpl, err = tmpl.Parse(string(partialContent))
-			if err != nil {
-				return fmt.Errorf("error parsing partial %s: %w", partial, err)
-			}
+		tmpl, err = tmpl.Parse(string(layoutContent))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing layout %s: %w", tm.layout, err)
 		}
+	}
 
-		// Parse the template file itself
-		content, err := os.ReadFile(path)
+	// Add partials
+	for _, partial := range tm.partials {
+		partialPath := filepath.Join(tm.directory, partial)
+		partialContent, err := os.ReadFile(partialPath)
 		if err != nil {
-			return fmt.Errorf("error reading template %s: %w", relPath, err)
+			return nil, fmt.Errorf("error reading partial %s: %w", partial, err)
 		}
-
-		tmpl, err = tmpl.Parse(string(content))
+		tmpl, err = tmpl.Parse(string(partialContent))
 		if err != nil {
-			return fmt.Errorf("error parsing template %s: %w", relPath, err)
+			return nil, fmt.Errorf("error parsing partial %s: %w", partial, err)
 		}
+	}
 
-		// Store the template
-		tm.templates[relPath] = tmpl
-		return nil
-	})
+	// Parse the template file itself
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template %s: %w", relPath, err)
+	}
 
+	tmpl, err = tmpl.Parse(string(content))
 	if err != nil {
-		tm.errorHandler(fmt.Errorf("error loading templates: %w", err))
+		return nil, fmt.Errorf("error parsing template %s: %w", relPath, err)
 	}
+	return tmpl, nil
 }
 
 // createFuncMap builds the function map for templates
@@ -220,6 +323,14 @@ func (tm *TemplateManager) createFuncMap() template.FuncMap {
 		"lower":     strings.ToLower,
 		"upper":     strings.ToUpper,
 		"title":     strings.ToTitle,
+
+		// Request helpers; RenderTemplateView binds them to the request
+		"param":  func(string) string { return "" },
+		"query":  func(string) string { return "" },
+		"locale": func() string { return "" },
+		"route": func(name string, params ...string) (string, error) {
+			return "", fmt.Errorf("router not available in context")
+		},
 	}
 
 	// Add user-defined functions
@@ -258,9 +369,15 @@ func (tm *TemplateManager) createFuncMap() template.FuncMap {
 
 // Render renders a template with the given data
 func (tm *TemplateManager) Render(w io.Writer, name string, data interface{}) error {
-	// Reload templates in development mode or if cache is disabled
+	return tm.render(w, name, data, nil)
+}
+
+// render executes a clone of the cached template, so the cached one is never
+// executed and can be cloned again with request-specific functions
+func (tm *TemplateManager) render(w io.Writer, name string, data interface{}, funcs template.FuncMap) error {
+	// Reparse changed templates in development mode or if cache is disabled
 	if tm.disableCache || tm.development {
-		tm.Reload()
+		tm.reloadChanged()
 	}
 
 	// Get the template
@@ -272,6 +389,14 @@ func (tm *TemplateManager) Render(w io.Writer, name string, data interface{}) er
 		return fmt.Errorf("template %s not found", name)
 	}
 
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	if funcs != nil {
+		tmpl.Funcs(funcs)
+	}
+
 	// Execute the template in a buffer first for error handling
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
@@ -283,7 +408,7 @@ func (tm *TemplateManager) Render(w io.Writer, name string, data interface{}) er
 	}
 
 	// Write to the actual writer
-	_, err := buf.WriteTo(w)
+	_, err = buf.WriteTo(w)
 	return err
 }
 
@@ -339,6 +464,9 @@ func RenderTemplateView(w http.ResponseWriter, r *http.Request, name string, dat
 		"query": func(name string) string {
 			return r.URL.Query().Get(name)
 		},
+		"locale": func() string {
+			return Locale(r)
+		},
 		"route": func(name string, params ...string) (string, error) {
 			router, ok := ctx.Value(contextKey("router")).(*MoraRouter)
 			if !ok {
@@ -348,25 +476,13 @@ func RenderTemplateView(w http.ResponseWriter, r *http.Request, name string, dat
 		},
 	}
 
-	// Clone the template with request-specific functions
-	// Create a new instance instead of copying to avoid mutex issues
-	newTM := NewTemplateManager(tm.directory)
-	newTM.templates = tm.templates
-	newTM.layout = tm.layout
-	newTM.partials = tm.partials
-	newTM.cssMap = tm.cssMap
-	newTM.jsMap = tm.jsMap
-	newTM.errorHandler = tm.errorHandler
-	newTM.disableCache = tm.disableCache
-	newTM.development = tm.development
-
-	// Add the request-specific functions
-	for name, fn := range tm.funcMap {
-		newTM.funcMap[name] = fn
+	if tm.translations != nil {
+		for name, fn := range tm.translations.Funcs(Locale(r)) {
+			funcMap[name] = fn
+		}
 	}
-	newTM.WithFuncs(funcMap)
 
-	return newTM.Render(w, name, data)
+	return tm.render(w, name, data, funcMap)
 }
 
 // ConfigureStaticFiles configures static file serving for the router
@@ -419,3 +535,169 @@ func WithTemplates(directory string) Option {
 func RenderTemplate(w http.ResponseWriter, r *http.Request, name string, data interface{}) error {
 	return RenderTemplateView(w, r, name, data)
 }
+
+// Translations holds per-locale message catalogs for templates.
+//
+// Plural forms are stored as separate keys with a CLDR category suffix, e.g.
+// "items.one" and "items.other"; "items.zero" is used for a count of 0 when
+// present.
+type Translations struct {
+	// Fallback is the locale used when a message is missing in the
+	// requested one.
+	Fallback string
+	// Messages maps locale -> key -> message.
+	Messages map[string]map[string]string
+}
+
+// NewTranslations creates an empty bundle with the given fallback locale
+func NewTranslations(fallback string) *Translations {
+	return &Translations{Fallback: fallback, Messages: make(map[string]map[string]string)}
+}
+
+// Add merges messages into the catalog for locale
+func (t *Translations) Add(locale string, messages map[string]string) *Translations {
+	if t.Messages == nil {
+		t.Messages = make(map[string]map[string]string)
+	}
+	if t.Messages[locale] == nil {
+		t.Messages[locale] = make(map[string]string)
+	}
+	for key, msg := range messages {
+		t.Messages[locale][key] = msg
+	}
+	return t
+}
+
+// catalogs returns the message maps to search for locale in order: the
+// locale itself, its parent tags (es-MX -> es) and then the fallback locale
+func (t *Translations) catalogs(locale string) (tags []string, catalogs []map[string]string) {
+	for _, candidate := range []string{locale, t.Fallback} {
+		for tag := candidate; tag != ""; {
+			for name, messages := range t.Messages {
+				if strings.EqualFold(name, tag) {
+					tags, catalogs = append(tags, tag), append(catalogs, messages)
+				}
+			}
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return tags, catalogs
+}
+
+// T returns the message for key in locale, or key itself when missing
+func (t *Translations) T(locale, key string) string {
+	_, catalogs := t.catalogs(locale)
+	for _, messages := range catalogs {
+		if msg, ok := messages[key]; ok {
+			return msg
+		}
+	}
+	return key
+}
+
+// Tf formats the message for key in locale with fmt.Sprintf
+func (t *Translations) Tf(locale, key string, args ...interface{}) string {
+	return formatMessage(t.T(locale, key), args)
+}
+
+// Tn picks the plural form of key for n and formats it with n followed by
+// args. Forms are taken from the first catalog that defines any of them, so a
+// partial translation never mixes with the fallback's forms.
+func (t *Translations) Tn(locale, key string, n int, args ...interface{}) string {
+	args = append([]interface{}{n}, args...)
+	tags, catalogs := t.catalogs(locale)
+	for i, messages := range catalogs {
+		forms := []string{key + "." + pluralCategory(tags[i], n), key + ".other"}
+		if n == 0 {
+			forms = append([]string{key + ".zero"}, forms...)
+		}
+		for _, form := range forms {
+			if msg, ok := messages[form]; ok {
+				return formatMessage(msg, args)
+			}
+		}
+	}
+	return t.Tf(locale, key, args...)
+}
+
+// formatMessage applies args only when the message has verbs, so a message
+// like "no items" is not decorated with %!(EXTRA ...)
+func formatMessage(msg string, args []interface{}) string {
+	if len(args) == 0 || !strings.Contains(msg, "%") {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Funcs returns the t, tf and tn template functions bound to locale
+func (t *Translations) Funcs(locale string) template.FuncMap {
+	if locale == "" {
+		locale = t.Fallback
+	}
+	return template.FuncMap{
+		"t": func(key string) string {
+			return t.T(locale, key)
+		},
+		"tf": func(key string, args ...interface{}) string {
+			return t.Tf(locale, key, args...)
+		},
+		"tn": func(key string, n int, args ...interface{}) string {
+			return t.Tn(locale, key, n, args...)
+		},
+	}
+}
+
+// pluralCategory returns the CLDR plural category of an integer count for the
+// most common languages; anything else follows the English one/other rule
+func pluralCategory(locale string, n int) string {
+	lang, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	if n < 0 {
+		n = -n
+	}
+	mod10, mod100 := n%10, n%100
+	switch lang {
+	case "ja", "zh", "ko", "th", "vi", "id", "ms", "tr":
+		return "other"
+	case "fr", "pt":
+		if n <= 1 {
+			return "one"
+		}
+		return "other"
+	case "ru", "uk", "be", "sr", "hr", "bs":
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "pl":
+		switch {
+		case n == 1:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "cs", "sk":
+		switch {
+		case n == 1:
+			return "one"
+		case n >= 2 && n <= 4:
+			return "few"
+		default:
+			return "other"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
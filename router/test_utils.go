@@ -1,18 +1,36 @@
 package router
 
 import (
+	"bufio"
 	"bytes"
+	"cmp"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"io"
+	"maps"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // TestClient proporciona una API fluida para pruebas de integración con el router.
 type TestClient struct {
 	Router  http.Handler
 	headers map[string]string
+
+	// jar guarda las cookies recibidas cuando WithCookies está activo;
+	// nil significa que el cliente no recuerda cookies.
+	jarMu sync.Mutex
+	jar   map[string]*http.Cookie
 }
 
 // NewTestClient crea un nuevo cliente para testing con el router dado.
@@ -29,6 +47,114 @@ func (c *TestClient) WithHeader(key, value string) *TestClient {
 	return c
 }
 
+// WithCookies activa o desactiva el cookie jar del cliente. Con el jar
+// activo, las cookies de Set-Cookie se envían en las peticiones siguientes
+// respetando Path y expiración. Desactivarlo descarta las cookies guardadas.
+func (c *TestClient) WithCookies(enabled bool) *TestClient {
+	c.jarMu.Lock()
+	defer c.jarMu.Unlock()
+	if !enabled {
+		c.jar = nil
+	} else if c.jar == nil {
+		c.jar = make(map[string]*http.Cookie)
+	}
+	return c
+}
+
+// Cookies devuelve las cookies vigentes del jar ordenadas por nombre y path.
+func (c *TestClient) Cookies() []*http.Cookie {
+	c.jarMu.Lock()
+	defer c.jarMu.Unlock()
+	now := time.Now()
+	var cookies []*http.Cookie
+	for key, cookie := range c.jar {
+		if cookieExpired(cookie, now) {
+			delete(c.jar, key)
+			continue
+		}
+		copied := *cookie
+		cookies = append(cookies, &copied)
+	}
+	slices.SortFunc(cookies, func(a, b *http.Cookie) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Path, b.Path))
+	})
+	return cookies
+}
+
+// addCookies añade a req las cookies del jar que aplican a su path, sin
+// pisar las que la petición ya trae.
+func (c *TestClient) addCookies(req *http.Request) {
+	c.jarMu.Lock()
+	defer c.jarMu.Unlock()
+	if c.jar == nil {
+		return
+	}
+	now := time.Now()
+	for key, cookie := range c.jar {
+		if cookieExpired(cookie, now) {
+			delete(c.jar, key)
+			continue
+		}
+		if !cookiePathMatch(cookie.Path, req.URL.Path) {
+			continue
+		}
+		if _, err := req.Cookie(cookie.Name); err == nil {
+			continue
+		}
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+}
+
+// storeCookies guarda en el jar las cookies de la respuesta. Una cookie
+// con MaxAge negativo o Expires en el pasado borra la guardada.
+func (c *TestClient) storeCookies(req *http.Request, resp *http.Response) {
+	c.jarMu.Lock()
+	defer c.jarMu.Unlock()
+	if c.jar == nil {
+		return
+	}
+	now := time.Now()
+	for _, cookie := range resp.Cookies() {
+		if cookie.Path == "" || cookie.Path[0] != '/' {
+			cookie.Path = defaultCookiePath(req.URL.Path)
+		}
+		if cookie.MaxAge > 0 {
+			cookie.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		key := cookie.Name + "\x00" + cookie.Path
+		if cookie.MaxAge < 0 || cookieExpired(cookie, now) {
+			delete(c.jar, key)
+			continue
+		}
+		c.jar[key] = cookie
+	}
+}
+
+func cookieExpired(cookie *http.Cookie, now time.Time) bool {
+	return !cookie.Expires.IsZero() && !cookie.Expires.After(now)
+}
+
+// cookiePathMatch implementa la coincidencia de paths de RFC 6265 5.1.4.
+func cookiePathMatch(cookiePath, reqPath string) bool {
+	if cookiePath == reqPath {
+		return true
+	}
+	if !strings.HasPrefix(reqPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || reqPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath calcula el path por defecto de RFC 6265 5.1.4: el
+// directorio del path de la petición.
+func defaultCookiePath(reqPath string) string {
+	i := strings.LastIndex(reqPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return reqPath[:i]
+}
+
 // WithAuth configura la cabecera de autorización con un token.
 func (c *TestClient) WithAuth(token string) *TestClient {
 	c.headers["Authorization"] = "Bearer " + token
@@ -120,6 +246,117 @@ func (r *TestResponse) DecodeJSON(v interface{}) error {
 	return json.Unmarshal(r.Body, v)
 }
 
+// TestingT es la parte de testing.TB que usan las aserciones de TestResponse;
+// la cumplen *testing.T y *testing.B sin importar testing en el paquete.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertStatus falla la prueba si el código de estado no es el esperado.
+// Devuelve la misma respuesta para encadenar aserciones.
+func (r *TestResponse) AssertStatus(t TestingT, expected int) *TestResponse {
+	t.Helper()
+	if r.StatusCode != expected {
+		t.Errorf("Expected status %d, got %d (body: %s)", expected, r.StatusCode, truncateBody(r.Body))
+	}
+	return r
+}
+
+// AssertHeader falla la prueba si la cabecera no tiene el valor esperado.
+func (r *TestResponse) AssertHeader(t TestingT, key, expected string) *TestResponse {
+	t.Helper()
+	if got := r.Header.Get(key); got != expected {
+		t.Errorf("Expected header %s to be %q, got %q", key, expected, got)
+	}
+	return r
+}
+
+// AssertBodyContains falla la prueba si el cuerpo no contiene substr.
+func (r *TestResponse) AssertBodyContains(t TestingT, substr string) *TestResponse {
+	t.Helper()
+	if !bytes.Contains(r.Body, []byte(substr)) {
+		t.Errorf("Expected body to contain %q, got %s", substr, truncateBody(r.Body))
+	}
+	return r
+}
+
+// AssertJSON falla la prueba si el valor en path no es igual a expected.
+// path puede ser un JSON pointer ("/user/tags/0"), una ruta con puntos
+// ("user.tags.0") o vacío para comparar el documento completo. expected
+// se compara tras pasarlo por JSON, así que 1 y 1.0 son equivalentes.
+func (r *TestResponse) AssertJSON(t TestingT, path string, expected interface{}) *TestResponse {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal(r.Body, &doc); err != nil {
+		t.Errorf("Expected JSON body, got %s (%v)", truncateBody(r.Body), err)
+		return r
+	}
+	got, err := lookupJSON(doc, path)
+	if err != nil {
+		t.Errorf("Expected JSON value at %q, got error: %v", path, err)
+		return r
+	}
+	raw, err := json.Marshal(expected)
+	if err != nil {
+		t.Errorf("Could not encode expected value %v: %v", expected, err)
+		return r
+	}
+	var want interface{}
+	json.Unmarshal(raw, &want)
+	if !reflect.DeepEqual(got, want) {
+		gotRaw, _ := json.Marshal(got)
+		t.Errorf("Expected JSON at %q to be %s, got %s", path, raw, gotRaw)
+	}
+	return r
+}
+
+// lookupJSON recorre un documento JSON decodificado siguiendo path.
+func lookupJSON(doc interface{}, path string) (interface{}, error) {
+	var keys []string
+	switch {
+	case path == "" || path == "/":
+		return doc, nil
+	case strings.HasPrefix(path, "/"):
+		keys = strings.Split(path[1:], "/")
+		for i, k := range keys {
+			keys[i] = strings.ReplaceAll(strings.ReplaceAll(k, "~1", "/"), "~0", "~")
+		}
+	default:
+		keys = strings.Split(path, ".")
+	}
+
+	current := doc
+	for _, key := range keys {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", key)
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("index %q out of range", key)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("cannot descend into %T with %q", current, key)
+		}
+	}
+	return current, nil
+}
+
+// truncateBody acorta cuerpos largos para que los mensajes de error sean legibles.
+func truncateBody(body []byte) string {
+	const limit = 200
+	if len(body) > limit {
+		return string(body[:limit]) + "..."
+	}
+	return string(body)
+}
+
 // Get hace una petición GET a la ruta dada.
 func (c *TestClient) Get(path string) *TestResponse {
 	req := httptest.NewRequest(http.MethodGet, path, nil)
@@ -236,8 +473,10 @@ func (c *TestClient) Patch(path string, payload interface{}) *TestResponse {
 
 // exec ejecuta la petición HTTP y devuelve una TestResponse.
 func (c *TestClient) exec(req *http.Request) *TestResponse {
+	c.addCookies(req)
 	rr := httptest.NewRecorder()
 	c.Router.ServeHTTP(rr, req)
+	c.storeCookies(req, rr.Result())
 	return &TestResponse{
 		StatusCode: rr.Code,
 		Body:       rr.Body.Bytes(),
@@ -310,3 +549,222 @@ func (c *TestClient) PatchJSON(path string, payload interface{}) *TestResponse {
 	}
 	return c.exec(req)
 }
+
+// FileUpload es un archivo a enviar con PostMultipart.
+type FileUpload struct {
+	Filename string
+	Content  []byte
+	// Tipo MIME de la parte; por defecto application/octet-stream
+	ContentType string
+}
+
+// quoteEscaper escapa los parámetros de Content-Disposition como hace
+// multipart.Writer.CreateFormFile.
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// PostMultipart hace una petición POST con un cuerpo multipart/form-data que
+// contiene los campos y archivos dados, como lo enviaría un formulario HTML.
+func (c *TestClient) PostMultipart(path string, fields map[string]string, files map[string]FileUpload) *TestResponse {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+
+	// orden fijo para que el cuerpo sea reproducible entre ejecuciones
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if err := mw.WriteField(name, fields[name]); err != nil {
+			panic("failed to write multipart field: " + err.Error())
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		file := files[name]
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(name), quoteEscaper.Replace(file.Filename)))
+		header.Set("Content-Type", cmp.Or(file.ContentType, "application/octet-stream"))
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			panic("failed to create multipart file: " + err.Error())
+		}
+		part.Write(file.Content)
+	}
+	if err := mw.Close(); err != nil {
+		panic("failed to close multipart body: " + err.Error())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	return c.exec(req)
+}
+
+// testWebSocketTimeout limita cuánto esperan las operaciones de TestWebSocketConn.
+const testWebSocketTimeout = 5 * time.Second
+
+// TestWebSocketConn es el extremo cliente de una conexión WebSocket abierta
+// con TestClient.WebSocket.
+type TestWebSocketConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	done   chan struct{} // se cierra cuando el handler del router termina
+}
+
+// hijackRecorder es un ResponseRecorder que permite hacer Hijack sobre un
+// extremo de un net.Pipe.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn     net.Conn
+	hijacked chan struct{}
+	once     sync.Once
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.once.Do(func() { close(h.hijacked) })
+	return h.conn, bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn)), nil
+}
+
+// WebSocket realiza el upgrade contra el router en memoria y devuelve la
+// conexión del lado cliente.
+func (c *TestClient) WebSocket(path string) (*TestWebSocketConn, error) {
+	clientConn, serverConn := net.Pipe()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	c.addCookies(req)
+
+	rec := &hijackRecorder{
+		ResponseRecorder: httptest.NewRecorder(),
+		conn:             serverConn,
+		hijacked:         make(chan struct{}),
+	}
+	ws := &TestWebSocketConn{
+		conn:   clientConn,
+		reader: bufio.NewReader(clientConn),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(ws.done)
+		c.Router.ServeHTTP(rec, req)
+	}()
+
+	// el handler responde sin hacer Hijack si rechaza el upgrade
+	select {
+	case <-rec.hijacked:
+	case <-ws.done:
+		clientConn.Close()
+		serverConn.Close()
+		return nil, fmt.Errorf("websocket upgrade failed: status %d: %s", rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+
+	clientConn.SetReadDeadline(time.Now().Add(testWebSocketTimeout))
+	resp, err := http.ReadResponse(ws.reader, req)
+	if err != nil {
+		clientConn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		clientConn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: status %d", resp.StatusCode)
+	}
+	return ws, nil
+}
+
+// Send envía un mensaje de texto enmascarado como lo haría un navegador.
+func (ws *TestWebSocketConn) Send(msg []byte) error {
+	return ws.writeFrame(0x1, msg)
+}
+
+// Receive devuelve el siguiente mensaje de texto o binario del servidor,
+// respondiendo a los pings por el camino. Un frame de cierre devuelve error.
+func (ws *TestWebSocketConn) Receive() ([]byte, error) {
+	ws.conn.SetReadDeadline(time.Now().Add(testWebSocketTimeout))
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case 0x1, 0x2:
+			return payload, nil
+		case 0x8:
+			code, reason := parseClosePayload(payload)
+			return nil, fmt.Errorf("websocket closed: %d %s", code, reason)
+		case 0x9:
+			if err := ws.writeFrame(0xA, payload); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// Close envía un frame de cierre normal y espera a que el handler termine.
+func (ws *TestWebSocketConn) Close() error {
+	ws.conn.SetWriteDeadline(time.Now().Add(testWebSocketTimeout))
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, CloseNormalClosure)
+	ws.writeFrame(0x8, payload)
+	ws.conn.Close()
+
+	select {
+	case <-ws.done:
+		return nil
+	case <-time.After(testWebSocketTimeout):
+		return fmt.Errorf("websocket handler did not return after close")
+	}
+}
+
+// writeFrame escribe un frame con máscara, obligatoria para los clientes.
+func (ws *TestWebSocketConn) writeFrame(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode, 0}
+	mask := []byte{1, 2, 3, 4}
+	switch {
+	case len(payload) < 126:
+		frame[1] = 0x80 | byte(len(payload))
+	case len(payload) <= 65535:
+		frame[1] = 0x80 | 126
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame[1] = 0x80 | 127
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+// readFrame lee un frame sin máscara enviado por el servidor.
+func (ws *TestWebSocketConn) readFrame() (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(ws.reader, header); err != nil {
+		return 0, nil, err
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(ws.reader, ext); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(ws.reader, ext); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0] & 0x0F, payload, nil
+}
@@ -2,8 +2,11 @@ package router
 
 import (
 	"bytes"
+	"net"
 	"net/http"
 	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,10 +24,40 @@ type MoraRouter struct {
 	middlewares        []Middleware
 	notFound           HandlerFunc
 	namedRoutes        map[string]string
+	routeNames         map[string]string // routeKey(método, patrón) -> nombre, inverso de namedRoutes
+	render             *Render
+	services           *container
+	validator          *Validator
+	validationErrors   func(http.ResponseWriter, *http.Request, ValidationErrors) // de WithValidationErrorHandler
+	securitySchemes    map[string]map[string]interface{}                          // nombre -> esquema OpenAPI
+	openAPI            SwaggerOptions                                             // metadatos de la especificación OpenAPI
+	metrics            *MetricsCollector                                          // métricas de WithMetrics, compartidas con los clones
+	security           []string                                                   // esquemas exigidos a las rutas siguientes
+	debugAuth          func(*http.Request) bool                                   // acceso a los endpoints /_mora
 	mounts             []mount
 	middlewareRegistry map[string]Middleware
 	i18n               map[string]map[string]string
 	templateManager    *TemplateManager
+	trustedProxies     []*net.IPNet
+	strictParams       bool
+	lowercaseRedirect  bool
+	mergeSlashes       bool
+	recoveryOutermost  Middleware
+	cors               *CORSConfig
+	versioning         *VersionConfig
+	pathCleaning       *PathCleaningOptions
+	webSockets         *webSocketHubs // hubs que cierra ShutdownWebSockets, compartidos con los clones
+	// en los clones de With, el router donde se registran las rutas y los
+	// middlewares que se les añaden
+	withParent      *MoraRouter
+	withMiddlewares []Middleware
+	// mu protege routes, namedRoutes, routeNames, i18n y middlewareRegistry,
+	// que se comparten con los clones de With, para poder registrar
+	// rutas mientras se atienden peticiones
+	mu *sync.RWMutex
+	// routesShared indica que algún lector tiene el slice de routes sin el
+	// lock; hasta que se reemplace no se puede modificar en su sitio
+	routesShared atomic.Bool
 }
 
 // Alias para compatibilidad
@@ -43,6 +76,50 @@ type route struct {
 	pattern  string
 	segments []segment
 	handler  HandlerFunc
+	security []string     // esquemas de seguridad OpenAPI que exige la ruta
+	doc      operationDoc // cuerpos y ejemplos para OpenAPI (Accepts, Returns, Document)
+	query    []queryParam // parámetros de query documentados con Route.Query
+	// resumen, descripción y etiquetas de la operación OpenAPI (Route.Doc y Route.Tag)
+	summary     string
+	description string
+	tags        []string
+	// TTL de caché propio de Route.Cache; negativo con Route.NoCache y 0 para
+	// usar el de WithCache
+	cacheTTL time.Duration
+	// HotReloader que cargó la ruta desde su archivo; nil si se registró en
+	// código
+	loadedBy *HotReloader
+}
+
+// RouteInfo describe una ruta registrada; lo devuelve MoraRouter.Routes.
+type RouteInfo struct {
+	Method   string   `json:"method"`
+	Pattern  string   `json:"pattern"`
+	Name     string   `json:"name,omitempty"`     // nombre de Name o Route.Name para su método, si lo tiene
+	Params   []string `json:"params"`             // parámetros de ruta en orden
+	Segments []string `json:"segments"`           // segmentos con sus validadores, p. ej. ":id(^\d+$)"
+	Security []string `json:"security,omitempty"` // esquemas exigidos con Secure o WithJWT
+}
+
+// queryParam es un parámetro de query string declarado para OpenAPI.
+type queryParam struct {
+	name     string
+	typ      string // tipo de esquema OpenAPI: string, integer, number, boolean...
+	required bool
+}
+
+// Route lo devuelven Handle y sus atajos para completar la documentación de
+// la ruta recién registrada, p. ej. r.Get("/users", h).Query("page", "integer", false).
+type Route struct {
+	router  *MoraRouter
+	method  string
+	pattern string
+}
+
+// RouteRegistrar lo cumplen MoraRouter y RouteGroup; HandleJSON y HandleXML
+// registran sus rutas con él.
+type RouteRegistrar interface {
+	Handle(method, pattern string, handler HandlerFunc) *Route
 }
 
 // mount representa una ruta montada de http.Handler con prefijo.
@@ -55,6 +132,7 @@ type cacheEntry struct {
 	header http.Header
 	status int
 	body   []byte
+	etag   string
 	expire time.Time
 }
 
@@ -74,12 +152,51 @@ type responseBuffer struct {
 type RouteGroup struct {
 	prefix string
 	router *MoraRouter
+	// middlewares del router al crear el grupo; los que se añadan después con
+	// MoraRouter.Use no envuelven sus rutas
+	global []Middleware
+	// middlewares propios del grupo; envuelven solo las rutas que registra el
+	// grupo, por dentro de global
+	middlewares []Middleware
+}
+
+// ResourceRoutes lo devuelve Resource para añadir rutas de miembro y de
+// colección bajo el prefijo del recurso.
+type ResourceRoutes struct {
+	router *MoraRouter
+	prefix string // p. ej. /users
+	member string // p. ej. /users/:id
+	name   string // nombre base, p. ej. users
+	// posición en router.routes donde insertar rutas de colección para que
+	// precedan a la ruta de miembro (/users/search antes de /users/:id)
+	collectionAt int
 }
 
 // context key for params embedding
 type contextKey string
 
-const paramsKey contextKey = "routerParams"
+const (
+	paramsKey           contextKey = "routerParams"
+	patternKey          contextKey = "routerPattern"
+	nameKey             contextKey = "routerName"
+	renderKey           contextKey = "routerRender"
+	servicesKey         contextKey = "routerServices"
+	validatorKey        contextKey = "routerValidator"
+	validationErrorsKey contextKey = "routerValidationErrors"
+	auditKey            contextKey = "routerAudit"
+	localeKey           contextKey = "routerLocale"
+	cacheTTLKey         contextKey = "routerCacheTTL"
+	versionKey          contextKey = "routerVersion"
+)
+
+// AuditEvent describe una petición que modificó estado, emitida por WithAudit.
+type AuditEvent struct {
+	Identity  string // "sub" del JWT; vacío si la petición es anónima
+	Method    string
+	Pattern   string // patrón de la ruta que atendió la petición
+	Status    int
+	Timestamp time.Time
+}
 
 // ResourceController define los métodos que un controlador de recursos puede implementar.
 type ResourceController interface {
@@ -90,6 +207,13 @@ type ResourceController interface {
 	Delete(http.ResponseWriter, *http.Request, Params)
 }
 
+// ResourcePatcher lo implementan los controladores que tratan PATCH aparte de
+// PUT; si no, PATCH llama a Update. DefaultController no lo implementa para
+// que ese respaldo siga funcionando al embeberlo.
+type ResourcePatcher interface {
+	Patch(http.ResponseWriter, *http.Request, Params)
+}
+
 // DefaultController es una implementación vacía de ResourceController para embeber y extender.
 type DefaultController struct{}
 
@@ -2,10 +2,13 @@ package router
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ValidationError representa un error de validación con información detallada.
@@ -34,12 +37,19 @@ func (e ValidationErrors) Error() string {
 	return strings.Join(messages, "; ")
 }
 
+// ValidationTagName es el nombre de tag usado por los validadores que no definen TagName.
+var ValidationTagName = "validate"
+
 // Validator es un validador configurable para structs.
 type Validator struct {
+	// TagName es el tag de struct que contiene las reglas (por defecto ValidationTagName)
+	TagName string
 	// Custom validators map
 	customValidators map[string]func(interface{}) bool
 	// Field transformers
 	transformers map[string]func(interface{}) interface{}
+	// Mensajes registrados por regla ("required") o por campo y regla ("Email.required")
+	messages map[string]string
 }
 
 // NewValidator crea un nuevo validador.
@@ -47,6 +57,7 @@ func NewValidator() *Validator {
 	return &Validator{
 		customValidators: make(map[string]func(interface{}) bool),
 		transformers:     make(map[string]func(interface{}) interface{}),
+		messages:         make(map[string]string),
 	}
 }
 
@@ -60,7 +71,25 @@ func (v *Validator) RegisterTransformer(field string, fn func(interface{}) inter
 	v.transformers[field] = fn
 }
 
-// Validate valida un struct basado en tags `validate`.
+// RegisterMessage registra el mensaje de error para una regla ("required") o
+// para una regla de un campo concreto ("Email.required"). El tag `message`
+// del campo tiene prioridad sobre los mensajes registrados.
+func (v *Validator) RegisterMessage(key, message string) {
+	if v.messages == nil {
+		v.messages = make(map[string]string)
+	}
+	v.messages[key] = message
+}
+
+// tagName devuelve el tag configurado o el valor por defecto del paquete.
+func (v *Validator) tagName() string {
+	if v.TagName != "" {
+		return v.TagName
+	}
+	return ValidationTagName
+}
+
+// Validate valida un struct basado en tags `validate` (o el tag configurado).
 func (v *Validator) Validate(obj interface{}) ValidationErrors {
 	value := reflect.ValueOf(obj)
 	if value.Kind() == reflect.Ptr {
@@ -75,19 +104,43 @@ func (v *Validator) Validate(obj interface{}) ValidationErrors {
 		}}
 	}
 
+	errors := v.validateStruct(value, "")
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// validateStruct valida los campos de un struct y recorre los structs
+// anidados y embebidos. prefix es la ruta del struct dentro del objeto
+// validado (por ejemplo "Items[0].").
+func (v *Validator) validateStruct(value reflect.Value, prefix string) ValidationErrors {
 	var errors ValidationErrors
 
 	t := value.Type()
 	for i := 0; i < value.NumField(); i++ {
 		field := t.Field(i)
-		tag := field.Tag.Get("validate")
-		if tag == "" {
+		// Los campos no exportados no se pueden leer por reflexión
+		if !field.IsExported() {
 			continue
 		}
 
 		fieldValue := value.Field(i)
 		fieldName := field.Name
 
+		// Los campos de un struct embebido se validan como propios
+		if field.Anonymous {
+			if nested, ok := structValue(fieldValue); ok {
+				errors = append(errors, v.validateStruct(nested, prefix)...)
+			}
+			continue
+		}
+
+		tag := field.Tag.Get(v.tagName())
+		if tag == "-" {
+			continue
+		}
+
 		// Apply transformer if exists
 		if transformer, ok := v.transformers[fieldName]; ok {
 			if fieldValue.CanSet() {
@@ -101,108 +154,247 @@ func (v *Validator) Validate(obj interface{}) ValidationErrors {
 			}
 		}
 
-		// Check each validation rule
-		rules := strings.Split(tag, ",")
-		for _, rule := range rules {
-			parts := strings.SplitN(rule, "=", 2)
-			ruleName := parts[0]
-			ruleValue := ""
-			if len(parts) > 1 {
-				ruleValue = parts[1]
+		// Las reglas tras "dive" se aplican a cada elemento
+		var rules, elemRules []string
+		dive := false
+		if tag != "" {
+			for _, rule := range strings.Split(tag, ",") {
+				switch {
+				case rule == "dive" && !dive:
+					dive = true
+				case dive:
+					elemRules = append(elemRules, rule)
+				default:
+					rules = append(rules, rule)
+				}
 			}
+		}
 
-			var valid bool
-			var errMsg string
+		path := prefix + fieldName
+		if err := v.checkRules(value, fieldValue, path, rules); err != nil {
+			errors = append(errors, v.withMessage(field, *err))
+			continue
+		}
 
-			// Check built-in rules
-			switch ruleName {
-			case "required":
-				valid = !v.isZero(fieldValue)
-				if !valid {
-					errMsg = "is required"
-				}
+		if dive {
+			errors = append(errors, v.validateElements(value, field, fieldValue, path, elemRules)...)
+		} else if nested, ok := structValue(fieldValue); ok {
+			errors = append(errors, v.validateStruct(nested, path+".")...)
+		}
+	}
 
-			case "email":
-				if str, ok := fieldValue.Interface().(string); ok {
-					valid = v.isValidEmail(str)
-					if !valid {
-						errMsg = "must be a valid email address"
-					}
-				} else {
-					valid = false
-					errMsg = "must be a string for email validation"
-				}
+	return errors
+}
 
-			case "min":
-				minValue, err := strconv.Atoi(ruleValue)
-				if err != nil {
-					valid = false
-					errMsg = "invalid min value"
-				} else {
-					valid, errMsg = v.validateMin(fieldValue, minValue)
-				}
+// validateElements aplica rules a cada elemento de un slice, array o map y
+// valida los que son structs. Los elementos se nombran path[i] o path[clave].
+// parent es el struct que contiene la colección y field su campo.
+func (v *Validator) validateElements(parent reflect.Value, field reflect.StructField, value reflect.Value, path string, rules []string) ValidationErrors {
+	var errors ValidationErrors
 
-			case "max":
-				maxValue, err := strconv.Atoi(ruleValue)
-				if err != nil {
-					valid = false
-					errMsg = "invalid max value"
-				} else {
-					valid, errMsg = v.validateMax(fieldValue, maxValue)
+	validate := func(elem reflect.Value, elemPath string) {
+		if err := v.checkRules(parent, elem, elemPath, rules); err != nil {
+			errors = append(errors, v.withMessage(field, *err))
+			return
+		}
+		if nested, ok := structValue(elem); ok {
+			errors = append(errors, v.validateStruct(nested, elemPath+".")...)
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validate(value.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			validate(value.MapIndex(key), fmt.Sprintf("%s[%v]", path, key.Interface()))
+		}
+	}
+
+	return errors
+}
+
+// withMessage sustituye el mensaje de err por el del tag `message` del campo
+// o por uno registrado con RegisterMessage, si existen.
+func (v *Validator) withMessage(field reflect.StructField, err ValidationError) ValidationError {
+	if msg := field.Tag.Get("message"); msg != "" {
+		err.Message = msg
+		return err
+	}
+	ruleName, _, _ := strings.Cut(err.Rule, "=")
+	if msg, ok := v.messages[field.Name+"."+ruleName]; ok {
+		err.Message = msg
+	} else if msg, ok := v.messages[ruleName]; ok {
+		err.Message = msg
+	}
+	return err
+}
+
+// structValue devuelve el struct al que apunta value, siguiendo punteros no nulos.
+func structValue(value reflect.Value) (reflect.Value, bool) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return value, false
+		}
+		value = value.Elem()
+	}
+	return value, value.Kind() == reflect.Struct
+}
+
+// checkRules comprueba las reglas sobre un valor y devuelve el primer error.
+// parent es el struct que contiene el campo, para las reglas entre campos.
+func (v *Validator) checkRules(parent, fieldValue reflect.Value, path string, rules []string) *ValidationError {
+	// Check each validation rule
+	for _, rule := range rules {
+		parts := strings.SplitN(rule, "=", 2)
+		ruleName := parts[0]
+		ruleValue := ""
+		if len(parts) > 1 {
+			ruleValue = parts[1]
+		}
+
+		var valid bool
+		var errMsg string
+
+		// Check built-in rules
+		switch ruleName {
+		case "required":
+			valid = !v.isZero(fieldValue)
+			if !valid {
+				errMsg = "is required"
+			}
+
+		case "required_if", "required_with":
+			required, condition, err := v.requiredCondition(parent, ruleName, ruleValue)
+			if err != "" {
+				valid, errMsg = false, err
+				break
+			}
+			// Si la condición no se cumple el campo es opcional: vacío no se
+			// comprueban el resto de reglas
+			if v.isZero(fieldValue) {
+				if !required {
+					return nil
 				}
+				valid, errMsg = false, "is required when "+condition
+				break
+			}
+			valid = true
 
-			case "in":
-				allowedValues := strings.Split(ruleValue, "|")
-				valid = v.validateIn(fieldValue, allowedValues)
+		case "email":
+			if str, ok := fieldValue.Interface().(string); ok {
+				valid = v.isValidEmail(str)
 				if !valid {
-					errMsg = fmt.Sprintf("must be one of: %s", ruleValue)
+					errMsg = "must be a valid email address"
 				}
+			} else {
+				valid = false
+				errMsg = "must be a string for email validation"
+			}
 
-			case "regex":
-				if str, ok := fieldValue.Interface().(string); ok {
-					re, err := regexp.Compile(ruleValue)
-					if err != nil {
-						valid = false
-						errMsg = "invalid regex pattern"
-					} else {
-						valid = re.MatchString(str)
-						if !valid {
-							errMsg = fmt.Sprintf("must match pattern: %s", ruleValue)
-						}
-					}
-				} else {
-					valid = false
-					errMsg = "must be a string for regex validation"
+		case "url", "uuid", "alpha", "alphanum", "numeric":
+			format := stringFormats[ruleName]
+			if str, ok := fieldValue.Interface().(string); ok {
+				valid = format.check(str)
+				if !valid {
+					errMsg = format.message
 				}
+			} else {
+				valid = false
+				errMsg = fmt.Sprintf("must be a string for %s validation", ruleName)
+			}
+
+		case "min":
+			minValue, err := strconv.Atoi(ruleValue)
+			if err != nil {
+				valid = false
+				errMsg = "invalid min value"
+			} else {
+				valid, errMsg = v.validateMin(fieldValue, minValue)
+			}
+
+		case "max":
+			maxValue, err := strconv.Atoi(ruleValue)
+			if err != nil {
+				valid = false
+				errMsg = "invalid max value"
+			} else {
+				valid, errMsg = v.validateMax(fieldValue, maxValue)
+			}
+
+		case "len":
+			length, err := strconv.Atoi(ruleValue)
+			if err != nil {
+				valid = false
+				errMsg = "invalid len value"
+			} else {
+				valid, errMsg = v.validateLen(fieldValue, length)
+			}
+
+		case "gt", "gte", "lt", "lte":
+			limit, err := strconv.ParseFloat(ruleValue, 64)
+			if err != nil {
+				valid = false
+				errMsg = fmt.Sprintf("invalid %s value", ruleName)
+			} else {
+				valid, errMsg = v.validateNumber(fieldValue, ruleName, limit)
+			}
+
+		case "in":
+			allowedValues := strings.Split(ruleValue, "|")
+			valid = v.validateIn(fieldValue, allowedValues)
+			if !valid {
+				errMsg = fmt.Sprintf("must be one of: %s", ruleValue)
+			}
 
-			default:
-				// Check custom validators
-				if customValidator, ok := v.customValidators[ruleName]; ok {
-					valid = customValidator(fieldValue.Interface())
+		case "regex":
+			if str, ok := fieldValue.Interface().(string); ok {
+				re, err := regexp.Compile(ruleValue)
+				if err != nil {
+					valid = false
+					errMsg = "invalid regex pattern"
+				} else {
+					valid = re.MatchString(str)
 					if !valid {
-						errMsg = fmt.Sprintf("failed custom validation: %s", ruleName)
+						errMsg = fmt.Sprintf("must match pattern: %s", ruleValue)
 					}
-				} else {
-					// Unknown rule, skip
-					continue
 				}
+			} else {
+				valid = false
+				errMsg = "must be a string for regex validation"
 			}
 
-			// If validation failed, add error
-			if !valid {
-				errors = append(errors, ValidationError{
-					Field:   fieldName,
-					Message: errMsg,
-					Rule:    rule,
-					Value:   fmt.Sprintf("%v", fieldValue.Interface()),
-				})
-				break // Stop on first error for this field
+		case "eqfield", "nefield", "gtfield", "gtefield", "ltfield", "ltefield":
+			valid, errMsg = v.validateField(parent, fieldValue, ruleName, ruleValue)
+
+		default:
+			// Check custom validators
+			if customValidator, ok := v.customValidators[ruleName]; ok {
+				valid = customValidator(fieldValue.Interface())
+				if !valid {
+					errMsg = fmt.Sprintf("failed custom validation: %s", ruleName)
+				}
+			} else {
+				// Unknown rule, skip
+				continue
 			}
 		}
-	}
 
-	if len(errors) > 0 {
-		return errors
+		// If validation failed, report it; stop on first error for this field
+		if !valid {
+			return &ValidationError{
+				Field:   path,
+				Message: errMsg,
+				Rule:    rule,
+				Value:   fmt.Sprintf("%v", fieldValue.Interface()),
+			}
+		}
 	}
 	return nil
 }
@@ -226,6 +418,31 @@ func (v *Validator) isZero(value reflect.Value) bool {
 	return false
 }
 
+var (
+	uuidV4Pattern   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-4[0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$`)
+	alphaPattern    = regexp.MustCompile(`^[a-zA-Z]+$`)
+	alphanumPattern = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	numericPattern  = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// stringFormats are the built-in rules that check the format of a string.
+var stringFormats = map[string]struct {
+	check   func(string) bool
+	message string
+}{
+	"url":      {isValidURL, "must be a valid URL"},
+	"uuid":     {uuidV4Pattern.MatchString, "must be a valid UUID v4"},
+	"alpha":    {alphaPattern.MatchString, "must contain only letters"},
+	"alphanum": {alphanumPattern.MatchString, "must contain only letters and numbers"},
+	"numeric":  {numericPattern.MatchString, "must contain only digits"},
+}
+
+// isValidURL accepts absolute URLs with a scheme and a host.
+func isValidURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
 // isValidEmail validates an email with a regex pattern.
 func (v *Validator) isValidEmail(email string) bool {
 	pattern := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
@@ -233,7 +450,9 @@ func (v *Validator) isValidEmail(email string) bool {
 	return re.MatchString(email)
 }
 
-// validateMin validates minimum values for different types.
+// validateMin validates minimum values for different types: the length of
+// strings, slices, maps and arrays and the value of numbers. Use len for exact
+// lengths and gt/gte/lt/lte for numeric-only comparisons.
 func (v *Validator) validateMin(value reflect.Value, min int) (bool, string) {
 	switch value.Kind() {
 	case reflect.String:
@@ -291,6 +510,177 @@ func (v *Validator) validateMax(value reflect.Value, max int) (bool, string) {
 	return true, ""
 }
 
+// fieldComparisons describe cada regla entre campos: el resultado aceptado
+// de comparar el campo con el otro y el mensaje si no se cumple.
+var fieldComparisons = map[string]struct {
+	accept  func(cmp int) bool
+	message string
+}{
+	"eqfield":  {func(cmp int) bool { return cmp == 0 }, "must be equal to %s"},
+	"nefield":  {func(cmp int) bool { return cmp != 0 }, "must not be equal to %s"},
+	"gtfield":  {func(cmp int) bool { return cmp > 0 }, "must be greater than %s"},
+	"gtefield": {func(cmp int) bool { return cmp >= 0 }, "must be greater than or equal to %s"},
+	"ltfield":  {func(cmp int) bool { return cmp < 0 }, "must be less than %s"},
+	"ltefield": {func(cmp int) bool { return cmp <= 0 }, "must be less than or equal to %s"},
+}
+
+// validateField compara un campo con otro campo del mismo struct.
+func (v *Validator) validateField(parent, value reflect.Value, rule, other string) (bool, string) {
+	otherValue := reflect.Value{}
+	if parent.Kind() == reflect.Struct {
+		otherValue = parent.FieldByName(other)
+	}
+	if !otherValue.IsValid() {
+		return false, fmt.Sprintf("unknown field %s", other)
+	}
+
+	cmp, ok := compareValues(value, otherValue)
+	if !ok {
+		return false, fmt.Sprintf("cannot be compared with %s", other)
+	}
+	comparison := fieldComparisons[rule]
+	if !comparison.accept(cmp) {
+		return false, fmt.Sprintf(comparison.message, other)
+	}
+	return true, ""
+}
+
+// requiredCondition evalúa la condición de required_if ("Campo valor", varios
+// pares deben cumplirse todos) o required_with ("Campo", basta con que uno de
+// los campos tenga valor). Devuelve si el campo es obligatorio, la condición
+// para el mensaje de error, o un error si la regla está mal escrita.
+func (v *Validator) requiredCondition(parent reflect.Value, rule, args string) (bool, string, string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || (rule == "required_if" && len(fields)%2 != 0) {
+		return false, "", fmt.Sprintf("invalid %s value", rule)
+	}
+
+	lookup := func(name string) (reflect.Value, bool) {
+		if parent.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		other := parent.FieldByName(name)
+		return other, other.IsValid()
+	}
+
+	if rule == "required_with" {
+		for _, name := range fields {
+			other, ok := lookup(name)
+			if !ok {
+				return false, "", fmt.Sprintf("unknown field %s", name)
+			}
+			if !v.isZero(other) {
+				return true, name + " is present", ""
+			}
+		}
+		return false, "", ""
+	}
+
+	conditions := make([]string, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		name, want := fields[i], fields[i+1]
+		other, ok := lookup(name)
+		if !ok {
+			return false, "", fmt.Sprintf("unknown field %s", name)
+		}
+		other = reflect.Indirect(other)
+		if !other.IsValid() || fmt.Sprintf("%v", other.Interface()) != want {
+			return false, "", ""
+		}
+		conditions = append(conditions, name+" is "+want)
+	}
+	return true, strings.Join(conditions, " and "), ""
+}
+
+// compareValues compara strings, números o time.Time y devuelve -1, 0 o 1.
+// ok es false si los valores no son comparables entre sí.
+func compareValues(a, b reflect.Value) (cmp int, ok bool) {
+	a, b = reflect.Indirect(a), reflect.Indirect(b)
+	if !a.IsValid() || !b.IsValid() {
+		return 0, false
+	}
+
+	if ta, isTime := a.Interface().(time.Time); isTime {
+		tb, isTime := b.Interface().(time.Time)
+		if !isTime {
+			return 0, false
+		}
+		return ta.Compare(tb), true
+	}
+
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), true
+	}
+
+	fa, okA := numericValue(a)
+	fb, okB := numericValue(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	switch {
+	case fa < fb:
+		return -1, true
+	case fa > fb:
+		return 1, true
+	}
+	return 0, true
+}
+
+// numericValue convierte enteros y flotantes a float64.
+func numericValue(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+	return 0, false
+}
+
+// validateLen validates the exact length of strings, slices, maps and arrays.
+func (v *Validator) validateLen(value reflect.Value, length int) (bool, string) {
+	switch value.Kind() {
+	case reflect.String:
+		if len(value.String()) != length {
+			return false, fmt.Sprintf("length must be exactly %d", length)
+		}
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if value.Len() != length {
+			return false, fmt.Sprintf("must contain exactly %d items", length)
+		}
+	default:
+		return false, "len validation not supported for this type"
+	}
+	return true, ""
+}
+
+// numberComparisons maps the numeric comparators to their check and message.
+var numberComparisons = map[string]struct {
+	accept  func(n, limit float64) bool
+	message string
+}{
+	"gt":  {func(n, limit float64) bool { return n > limit }, "must be greater than %v"},
+	"gte": {func(n, limit float64) bool { return n >= limit }, "must be greater than or equal to %v"},
+	"lt":  {func(n, limit float64) bool { return n < limit }, "must be less than %v"},
+	"lte": {func(n, limit float64) bool { return n <= limit }, "must be less than or equal to %v"},
+}
+
+// validateNumber compares numeric values with limit; unlike min and max it
+// never looks at lengths.
+func (v *Validator) validateNumber(value reflect.Value, rule string, limit float64) (bool, string) {
+	n, ok := numericValue(value)
+	if !ok {
+		return false, fmt.Sprintf("must be a number for %s validation", rule)
+	}
+	comparison := numberComparisons[rule]
+	if !comparison.accept(n, limit) {
+		return false, fmt.Sprintf(comparison.message, limit)
+	}
+	return true, ""
+}
+
 // validateIn validates that a value is in a set of allowed values.
 func (v *Validator) validateIn(value reflect.Value, allowedValues []string) bool {
 	strValue := fmt.Sprintf("%v", value.Interface())
@@ -303,10 +693,11 @@ func (v *Validator) validateIn(value reflect.Value, allowedValues []string) bool
 }
 
 // DefaultValidator es una instancia global del validador para uso conveniente.
+// Lo usan ValidateStruct y los helpers de binding, así que los validadores y
+// mensajes registrados en él se aplican a BindJSON, BindRequest, etc.
 var DefaultValidator = NewValidator()
 
-// ValidateStruct valida un struct usando tags validate
+// ValidateStruct valida un struct usando tags validate con DefaultValidator
 func ValidateStruct(obj interface{}) ValidationErrors {
-	v := NewValidator()
-	return v.Validate(obj)
+	return DefaultValidator.Validate(obj)
 }
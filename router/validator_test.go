@@ -0,0 +1,469 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestValidatorCustomTagName verifica el uso de un tag de validación personalizado
+func TestValidatorCustomTagName(t *testing.T) {
+	type Signup struct {
+		Email string `binding:"required,email"`
+		Age   int    `binding:"min=18" validate:"max=10"`
+	}
+
+	v := NewValidator()
+	v.TagName = "binding"
+
+	errs := v.Validate(Signup{Email: "not-an-email", Age: 16})
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors under 'binding' tag, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Field != "Email" || errs[1].Field != "Age" {
+		t.Errorf("Unexpected fields in errors: %v", errs)
+	}
+
+	if errs := v.Validate(Signup{Email: "dev@example.com", Age: 30}); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	// El valor por defecto del paquete afecta a ValidateStruct
+	previous := ValidationTagName
+	ValidationTagName = "binding"
+	defer func() { ValidationTagName = previous }()
+
+	if errs := ValidateStruct(Signup{Age: 30}); len(errs) != 1 || errs[0].Field != "Email" {
+		t.Errorf("Expected ValidateStruct to use package default tag, got %v", errs)
+	}
+}
+
+// TestValidatorNested verifica la validación de structs anidados y la regla dive
+func TestValidatorNested(t *testing.T) {
+	type Item struct {
+		SKU   string  `validate:"required"`
+		Price float64 `validate:"min=1"`
+	}
+	type Address struct {
+		City string `validate:"required"`
+	}
+	type Audit struct {
+		CreatedBy string `validate:"required"`
+	}
+	type Order struct {
+		Audit
+		Items    []Item   `validate:"min=1,dive"`
+		Tags     []string `validate:"dive,min=2"`
+		Shipping *Address
+		Billing  Address
+		Notes    map[string]string `validate:"dive,max=5"`
+	}
+
+	order := Order{
+		Audit:    Audit{CreatedBy: "ana"},
+		Items:    []Item{{SKU: "A1", Price: 10}, {SKU: "", Price: 5}, {SKU: "C3", Price: 0}},
+		Tags:     []string{"ok", "x"},
+		Shipping: &Address{},
+		Billing:  Address{City: "Lima"},
+		Notes:    map[string]string{"gift": "too long"},
+	}
+
+	errs := NewValidator().Validate(&order)
+	got := make(map[string]string)
+	for _, err := range errs {
+		got[err.Field] = err.Rule
+	}
+	want := map[string]string{
+		"Items[1].SKU":   "required",
+		"Items[2].Price": "min=1",
+		"Tags[1]":        "min=2",
+		"Shipping.City":  "required",
+		"Notes[gift]":    "max=5",
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d errors, got %v", len(want), errs)
+	}
+	for field, rule := range want {
+		if got[field] != rule {
+			t.Errorf("Expected %s to fail %q, got %q (%v)", field, rule, got[field], errs)
+		}
+	}
+
+	// Las reglas antes de dive se aplican al slice, y los embebidos se validan sin prefijo
+	errs = NewValidator().Validate(Order{Billing: Address{City: "Lima"}})
+	got = make(map[string]string)
+	for _, err := range errs {
+		got[err.Field] = err.Rule
+	}
+	if got["Items"] != "min=1" || got["CreatedBy"] != "required" || len(errs) != 2 {
+		t.Errorf("Expected Items min=1 and CreatedBy required, got %v", errs)
+	}
+}
+
+// TestValidatorCrossField verifica las reglas que comparan campos hermanos
+func TestValidatorCrossField(t *testing.T) {
+	type Signup struct {
+		Password        string
+		ConfirmPassword string `validate:"eqfield=Password"`
+		Username        string
+		Nickname        string `validate:"nefield=Username"`
+	}
+	type Booking struct {
+		StartDate time.Time
+		EndDate   time.Time `validate:"gtfield=StartDate"`
+		MinGuests int
+		MaxGuests int64 `validate:"gtefield=MinGuests"`
+		Price     float64
+		Discount  float64 `validate:"ltfield=Price"`
+		Missing   string  `validate:"eqfield=Nope"`
+	}
+
+	errs := NewValidator().Validate(Signup{Password: "s3cret", ConfirmPassword: "s3cret", Username: "ana", Nickname: "anita"})
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	errs = NewValidator().Validate(Signup{Password: "s3cret", ConfirmPassword: "secret", Username: "ana", Nickname: "ana"})
+	if len(errs) != 2 || errs[0].Error() != "ConfirmPassword: must be equal to Password" || errs[1].Error() != "Nickname: must not be equal to Username" {
+		t.Errorf("Expected eqfield and nefield errors, got %v", errs)
+	}
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	errs = NewValidator().Validate(Booking{
+		StartDate: start,
+		EndDate:   start.Add(-time.Hour),
+		MinGuests: 2,
+		MaxGuests: 2,
+		Price:     100,
+		Discount:  150,
+	})
+	got := make(map[string]string)
+	for _, err := range errs {
+		got[err.Field] = err.Message
+	}
+	want := map[string]string{
+		"EndDate":  "must be greater than StartDate",
+		"Discount": "must be less than Price",
+		"Missing":  "unknown field Nope",
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d errors, got %v", len(want), errs)
+	}
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Errorf("Expected %s: %q, got %q", field, msg, got[field])
+		}
+	}
+}
+
+// TestValidatorCustomMessages verifica los mensajes de error personalizados
+func TestValidatorCustomMessages(t *testing.T) {
+	type Contact struct {
+		Email string   `validate:"required,email" message:"Email is mandatory"`
+		Name  string   `validate:"required"`
+		Phone string   `validate:"min=7"`
+		Tags  []string `validate:"dive,min=2" message:"Each tag needs two letters"`
+	}
+
+	v := NewValidator()
+	v.RegisterMessage("required", "cannot be blank")
+	v.RegisterMessage("Phone.min", "Phone is too short")
+
+	errs := v.Validate(Contact{Email: "not-an-email", Phone: "123", Tags: []string{"a"}})
+	got := make(map[string]string)
+	for _, err := range errs {
+		got[err.Field] = err.Message
+	}
+	want := map[string]string{
+		"Email":   "Email is mandatory", // el tag gana para cualquier regla
+		"Name":    "cannot be blank",
+		"Phone":   "Phone is too short",
+		"Tags[0]": "Each tag needs two letters",
+	}
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Errorf("Expected %s: %q, got %q", field, msg, got[field])
+		}
+	}
+
+	// Los mensajes de DefaultValidator llegan a BindJSON
+	DefaultValidator.RegisterMessage("Name.required", "Tell us your name")
+	t.Cleanup(func() { delete(DefaultValidator.messages, "Name.required") })
+
+	r := New()
+	r.Post("/contacts", BindJSON(func(w http.ResponseWriter, r *http.Request, p Params, c Contact) {}))
+	resp := NewTestClient(r).PostJSON("/contacts", map[string]any{"email": "ana@example.com", "phone": "5551234"})
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(resp.Text(), `"message":"Tell us your name"`) {
+		t.Errorf("Expected 400 with the registered message, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+}
+
+// TestValidatorLengthAndNumbers verifica len y los comparadores numéricos
+func TestValidatorLengthAndNumbers(t *testing.T) {
+	type Payment struct {
+		Currency string   `validate:"len=3"`
+		Cards    []string `validate:"len=2"`
+		Amount   float64  `validate:"gt=0"`
+		Quantity int      `validate:"gte=1,lte=10"`
+		Discount uint     `validate:"lt=50"`
+		Code     string   `validate:"gt=3"`
+	}
+
+	valid := Payment{Currency: "EUR", Cards: []string{"a", "b"}, Amount: 0.5, Quantity: 10, Discount: 49}
+	errs := NewValidator().Validate(valid)
+	if len(errs) != 1 || errs[0].Field != "Code" || errs[0].Message != "must be a number for gt validation" {
+		t.Errorf("Expected only the non-numeric gt error, got %v", errs)
+	}
+
+	errs = NewValidator().Validate(Payment{Currency: "EURO", Cards: []string{"a"}, Amount: 0, Quantity: 11, Discount: 50})
+	got := make(map[string]string)
+	for _, err := range errs {
+		got[err.Field] = err.Message
+	}
+	want := map[string]string{
+		"Currency": "length must be exactly 3",
+		"Cards":    "must contain exactly 2 items",
+		"Amount":   "must be greater than 0",
+		"Quantity": "must be less than or equal to 10",
+		"Discount": "must be less than 50",
+		"Code":     "must be a number for gt validation",
+	}
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Errorf("Expected %s: %q, got %q", field, msg, got[field])
+		}
+	}
+}
+
+// TestValidatorFormats verifica las reglas de formato url, uuid, alpha, alphanum y numeric
+func TestValidatorFormats(t *testing.T) {
+	tests := []struct {
+		rule    string
+		valid   []string
+		invalid []string
+		message string
+	}{
+		{"url", []string{"https://example.com/path?q=1", "ftp://files.example.com"}, []string{"example.com", "/relative", "http://", "not a url"}, "must be a valid URL"},
+		{"uuid", []string{"f47ac10b-58cc-4372-a567-0e02b2c3d479", "F47AC10B-58CC-4372-A567-0E02B2C3D479"}, []string{"f47ac10b-58cc-1372-a567-0e02b2c3d479", "f47ac10b58cc4372a5670e02b2c3d479", "xyz"}, "must be a valid UUID v4"},
+		{"alpha", []string{"abc", "ABCdef"}, []string{"abc1", "ab c", ""}, "must contain only letters"},
+		{"alphanum", []string{"abc123", "ABC"}, []string{"abc-123", "ab_c", ""}, "must contain only letters and numbers"},
+		{"numeric", []string{"0", "12345"}, []string{"12.5", "-1", "1a", ""}, "must contain only digits"},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		for _, value := range tt.valid {
+			if errs := v.checkRules(reflect.Value{}, reflect.ValueOf(value), "Field", []string{tt.rule}); errs != nil {
+				t.Errorf("Expected %q to pass %s, got %v", value, tt.rule, errs)
+			}
+		}
+		for _, value := range tt.invalid {
+			err := v.checkRules(reflect.Value{}, reflect.ValueOf(value), "Field", []string{tt.rule})
+			if err == nil || err.Message != tt.message {
+				t.Errorf("Expected %q to fail %s with %q, got %v", value, tt.rule, tt.message, err)
+			}
+		}
+	}
+
+	// Las reglas de formato se aplican desde los tags y exigen cadenas
+	type Profile struct {
+		Website string `validate:"url"`
+		Zip     int    `validate:"numeric"`
+	}
+	errs := v.Validate(Profile{Website: "example.com", Zip: 12345})
+	if len(errs) != 2 || errs[0].Message != "must be a valid URL" || errs[1].Message != "must be a string for numeric validation" {
+		t.Errorf("Expected url and numeric errors, got %v", errs)
+	}
+}
+
+// TestValidatorRequiredIf verifica las reglas obligatorias condicionadas a otros campos
+func TestValidatorRequiredIf(t *testing.T) {
+	type Payment struct {
+		Method     string
+		CardNumber string `validate:"required_if=Method card,len=16"`
+		IBAN       string
+		BIC        string `validate:"required_with=IBAN"`
+	}
+
+	// Sin tarjeta ni IBAN los campos condicionados son opcionales
+	if errs := NewValidator().Validate(Payment{Method: "paypal"}); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	errs := NewValidator().Validate(Payment{Method: "card", IBAN: "ES9121000418450200051332"})
+	if len(errs) != 2 ||
+		errs[0].Error() != "CardNumber: is required when Method is card" ||
+		errs[1].Error() != "BIC: is required when IBAN is present" {
+		t.Errorf("Expected required_if and required_with errors, got %v", errs)
+	}
+
+	// Con valor el resto de reglas se siguen comprobando
+	errs = NewValidator().Validate(Payment{Method: "card", CardNumber: "4111"})
+	if len(errs) != 1 || errs[0].Rule != "len=16" {
+		t.Errorf("Expected a len error, got %v", errs)
+	}
+	if errs := NewValidator().Validate(Payment{Method: "card", CardNumber: "4111111111111111"}); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	type Broken struct {
+		A string `validate:"required_if=Method"`
+		B string `validate:"required_with=Nope"`
+	}
+	errs = NewValidator().Validate(Broken{})
+	if len(errs) != 2 || errs[0].Message != "invalid required_if value" || errs[1].Message != "unknown field Nope" {
+		t.Errorf("Expected errors for malformed rules, got %v", errs)
+	}
+}
+
+// TestBindValidationErrors verifica la respuesta JSON de los errores de validación y su reemplazo
+func TestBindValidationErrors(t *testing.T) {
+	type Item struct {
+		SKU   string  `json:"sku" xml:"sku" validate:"required"`
+		Price float64 `json:"price" xml:"price" validate:"gt=0"`
+	}
+
+	r := New()
+	r.Post("/json", BindJSON(func(w http.ResponseWriter, r *http.Request, p Params, item Item) {}))
+	r.Post("/xml", BindXML(func(w http.ResponseWriter, r *http.Request, p Params, item Item) {}))
+
+	requests := map[string]*http.Request{
+		"/json": httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"price": -1}`)),
+		"/xml":  httptest.NewRequest(http.MethodPost, "/xml", strings.NewReader(`<Item><price>-1</price></Item>`)),
+	}
+	for path, req := range requests {
+		resp := NewTestClient(r).exec(req)
+		if resp.StatusCode != http.StatusBadRequest || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			t.Fatalf("Expected JSON 400 for %s, got %d '%s'", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		var body struct {
+			Errors []ValidationError `json:"errors"`
+		}
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			t.Fatalf("Expected a JSON body for %s, got '%s'", path, resp.Text())
+		}
+		if len(body.Errors) != 2 ||
+			body.Errors[0].Field != "SKU" || body.Errors[0].Rule != "required" ||
+			body.Errors[1].Field != "Price" || body.Errors[1].Rule != "gt=0" {
+			t.Errorf("Expected SKU and Price errors for %s, got %+v", path, body.Errors)
+		}
+	}
+
+	// El formato se puede reemplazar por router sin afectar a los demás
+	custom := New(WithValidationErrorHandler(func(w http.ResponseWriter, r *http.Request, errs ValidationErrors) {
+		JSON(w, http.StatusUnprocessableEntity, map[string]any{"ok": false, "problems": len(errs)})
+	}))
+	custom.Post("/json", BindJSON(func(w http.ResponseWriter, r *http.Request, p Params, item Item) {}))
+	resp := NewTestClient(custom).exec(httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"price": -1}`)))
+	if resp.StatusCode != http.StatusUnprocessableEntity || strings.TrimSpace(resp.Text()) != `{"ok":false,"problems":2}` {
+		t.Errorf("Expected the custom handler response, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+	resp = NewTestClient(r).exec(httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"price": -1}`)))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 from the default router, got %d", resp.StatusCode)
+	}
+}
+
+// TestRouterValidator verifica que las reglas de WithValidator no afecten al validador global
+func TestRouterValidator(t *testing.T) {
+	type Order struct {
+		Code string `json:"code" validate:"sku"`
+	}
+
+	v := NewValidator()
+	v.RegisterValidator("sku", func(value interface{}) bool {
+		s, _ := value.(string)
+		return strings.HasPrefix(s, "SKU-")
+	})
+
+	handler := BindJSON(func(w http.ResponseWriter, r *http.Request, p Params, o Order) {
+		w.Write([]byte("ok"))
+	})
+	scoped := New(WithValidator(v))
+	scoped.Post("/orders", handler)
+	global := New()
+	global.Post("/orders", handler)
+
+	if resp := NewTestClient(scoped).PostJSON("/orders", map[string]string{"code": "ABC"}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 from the router validator, got %d", resp.StatusCode)
+	}
+	if resp := NewTestClient(scoped).PostJSON("/orders", map[string]string{"code": "SKU-1"}); !resp.IsOK() {
+		t.Errorf("Expected status 200 for a valid code, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+
+	// El validador global no conoce la regla y la ignora
+	if _, ok := DefaultValidator.customValidators["sku"]; ok {
+		t.Fatalf("Expected DefaultValidator not to know the sku rule")
+	}
+	if resp := NewTestClient(global).PostJSON("/orders", map[string]string{"code": "ABC"}); !resp.IsOK() {
+		t.Errorf("Expected status 200 with the global validator, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+}
+
+// TestBindContentType verifica el 415 de BindJSON y BindXML y el despacho de BindAuto
+func TestBindContentType(t *testing.T) {
+	type Item struct {
+		SKU   string `json:"sku" xml:"sku" form:"sku" validate:"required"`
+		Count int    `json:"count" xml:"count" form:"count"`
+	}
+
+	r := New()
+	echo := func(w http.ResponseWriter, r *http.Request, p Params, item Item) {
+		w.Write([]byte(item.SKU + ":" + strconv.Itoa(item.Count)))
+	}
+	r.Post("/json", BindJSON(echo))
+	r.Post("/xml", BindXML(echo))
+	r.Post("/auto", BindAuto(echo))
+
+	send := func(path, contentType, body string) *TestResponse {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return NewTestClient(r).exec(req)
+	}
+
+	// Un formulario enviado a BindJSON es un 415 con un mensaje claro
+	resp := send("/json", "application/x-www-form-urlencoded", "sku=A1")
+	if resp.StatusCode != http.StatusUnsupportedMediaType || !strings.Contains(resp.Text(), `"application/x-www-form-urlencoded": expected application/json`) {
+		t.Errorf("Expected 415 for a form sent to BindJSON, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+	if resp := send("/xml", "application/json", `{"sku":"A1"}`); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for JSON sent to BindXML, got %d", resp.StatusCode)
+	}
+
+	// Los parámetros, los sufijos +json/+xml y la ausencia de Content-Type se aceptan
+	accepted := []struct{ path, contentType, body string }{
+		{"/json", "application/json; charset=utf-8", `{"sku":"A1","count":2}`},
+		{"/json", "application/merge-patch+json", `{"sku":"A1","count":2}`},
+		{"/json", "", `{"sku":"A1","count":2}`},
+		{"/xml", "text/xml", `<Item><sku>A1</sku><count>2</count></Item>`},
+		{"/xml", "application/atom+xml", `<Item><sku>A1</sku><count>2</count></Item>`},
+		{"/auto", "application/json", `{"sku":"A1","count":2}`},
+		{"/auto", "application/xml", `<Item><sku>A1</sku><count>2</count></Item>`},
+		{"/auto", "application/x-www-form-urlencoded", "sku=A1&count=2"},
+		{"/auto", "", `{"sku":"A1","count":2}`},
+	}
+	for _, tc := range accepted {
+		if resp := send(tc.path, tc.contentType, tc.body); resp.StatusCode != http.StatusOK || resp.Text() != "A1:2" {
+			t.Errorf("Expected 200 'A1:2' for %s with %q, got %d '%s'", tc.path, tc.contentType, resp.StatusCode, resp.Text())
+		}
+	}
+
+	// BindAuto también lee multipart y valida el resultado
+	resp = NewTestClient(r).PostMultipart("/auto", map[string]string{"sku": "B7", "count": "5"}, nil)
+	if resp.StatusCode != http.StatusOK || resp.Text() != "B7:5" {
+		t.Errorf("Expected 200 'B7:5' for multipart, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+	if resp := send("/auto", "application/x-www-form-urlencoded", "count=2"); resp.StatusCode != http.StatusBadRequest || !strings.Contains(resp.Text(), `"rule":"required"`) {
+		t.Errorf("Expected 400 validation errors for a form without sku, got %d '%s'", resp.StatusCode, resp.Text())
+	}
+	if resp := send("/auto", "text/plain", "A1"); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for text/plain sent to BindAuto, got %d", resp.StatusCode)
+	}
+}
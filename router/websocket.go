@@ -2,18 +2,27 @@ package router
 
 import (
 	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 // Global variables for hub management
@@ -30,25 +39,66 @@ type WebSocketConnection struct {
 	ID          string
 	Hub         *WebSocketHub
 	Send        chan []byte
-	isConnected bool
+	isConnected atomic.Bool
 	closeMutex  sync.Mutex
 
+	// Serializes frame writes from the send loop, pings and handlers; with
+	// permessage-deflate it also keeps compression in the order frames go out
+	writeMu sync.Mutex
+
 	// Hijacked connection components
 	netConn net.Conn
 	bufrw   *bufio.ReadWriter
+
+	// Close status received from the peer, if any
+	closeCode   uint16
+	closeReason string
+
+	// Unix nanoseconds of the last pong (or connection time)
+	lastPong atomic.Int64
+
+	// Whether the connection holds one of the hub's MaxConnections slots
+	hasSlot bool
+
+	// permessage-deflate state, nil unless the extension was negotiated
+	deflate *wsDeflate
+
+	// Connection-scoped context, canceled when the connection closes
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Closed by the hub once OnConnect has returned
+	registered chan struct{}
+
+	// Per-connection state set by OnConnect or handlers
+	valuesMu sync.RWMutex
+	values   map[string]any
 }
 
+// WebSocket close status codes (RFC 6455, section 7.4.1)
+const (
+	CloseNormalClosure    uint16 = 1000
+	CloseGoingAway        uint16 = 1001
+	CloseProtocolError    uint16 = 1002
+	CloseUnsupportedData  uint16 = 1003
+	CloseNoStatusReceived uint16 = 1005
+	CloseInvalidPayload   uint16 = 1007
+	ClosePolicyViolation  uint16 = 1008
+	CloseMessageTooBig    uint16 = 1009
+	CloseInternalError    uint16 = 1011
+)
+
+// maxCloseReasonLen is the longest reason that fits in a control frame
+// alongside the 2-byte status code
+const maxCloseReasonLen = 123
+
 // SendText sends a text message to the client
 func (c *WebSocketConnection) SendText(msg string) error {
-	if !c.isConnected {
+	if !c.isConnected.Load() {
 		return fmt.Errorf("connection closed")
 	}
 	log.Printf("Sending text to client %s: %s", c.ID, msg)
-	frame := newTextFrame([]byte(msg))
-
-	// Set write deadline to prevent blocked connections
-	c.netConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-	_, err := c.netConn.Write(frame)
+	err := c.writeData(0x1, []byte(msg))
 	if err != nil {
 		log.Printf("ERROR: Failed to send message to client %s: %v", c.ID, err)
 	}
@@ -66,37 +116,99 @@ func (c *WebSocketConnection) SendJSON(v interface{}) error {
 
 // Send binary data to the client
 func (c *WebSocketConnection) SendBinary(data []byte) error {
-	if !c.isConnected {
+	if !c.isConnected.Load() {
 		return fmt.Errorf("connection closed")
 	}
-	frame := newBinaryFrame(data)
-	_, err := c.netConn.Write(frame)
-	return err
+	return c.writeData(0x2, data)
+}
+
+// Ping sends a ping frame with the given payload; the client is expected to
+// answer with a pong carrying the same payload, reported through OnPong
+func (c *WebSocketConnection) Ping(payload []byte) error {
+	if !c.isConnected.Load() {
+		return fmt.Errorf("connection closed")
+	}
+	if len(payload) > 125 {
+		return fmt.Errorf("ping payload too large: %d bytes (max 125)", len(payload))
+	}
+	return c.writeControl(newPingFrame(payload))
+}
+
+// Context returns a context carrying the upgrade request's values (route
+// params, claims, ...) that is canceled when the connection closes
+func (c *WebSocketConnection) Context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	if c.Request != nil {
+		return c.Request.Context()
+	}
+	return context.Background()
+}
+
+// SetValue stores per-connection state, such as the authenticated user or a
+// subscription filter, for later use in MessageHandler
+func (c *WebSocketConnection) SetValue(key string, value any) {
+	c.valuesMu.Lock()
+	defer c.valuesMu.Unlock()
+	if c.values == nil {
+		c.values = make(map[string]any)
+	}
+	c.values[key] = value
+}
+
+// Value returns the state stored under key, or nil if there is none
+func (c *WebSocketConnection) Value(key string) any {
+	c.valuesMu.RLock()
+	defer c.valuesMu.RUnlock()
+	return c.values[key]
+}
+
+// Values returns a copy of all per-connection state
+func (c *WebSocketConnection) Values() map[string]any {
+	c.valuesMu.RLock()
+	defer c.valuesMu.RUnlock()
+	return maps.Clone(c.values)
 }
 
 // Close the connection with normal closure
 func (c *WebSocketConnection) Close() {
-	c.closeMutex.Lock()
-	defer c.closeMutex.Unlock()
+	c.CloseWithCode(CloseNormalClosure, "")
+}
 
-	if !c.isConnected {
+// CloseWithCode closes the connection sending the given status code and a
+// UTF-8 reason, truncated to 123 bytes
+func (c *WebSocketConnection) CloseWithCode(code uint16, reason string) {
+	c.closeMutex.Lock()
+	if !c.isConnected.Swap(false) {
+		c.closeMutex.Unlock()
 		return
 	}
 
+	if c.cancel != nil {
+		c.cancel()
+	}
+
 	// Send close frame
-	closeFrame := []byte{0x88, 0x02, 0x03, 0xE8} // Normal closure (1000)
 	if c.netConn != nil {
-		c.netConn.Write(closeFrame)
+		c.writeControl(newCloseFrame(code, reason))
 		c.netConn.Close()
 	}
-	c.isConnected = false
+	c.closeMutex.Unlock()
 
-	// Remove from hub if present
+	// Remove from hub if present; done outside the lock so OnDisconnect may call Close
 	if c.Hub != nil {
-		c.Hub.Unregister <- c
+		c.Hub.unregister(c)
 	}
 }
 
+// CloseStatus returns the close code and reason sent by the peer. The code is
+// zero if the peer never sent a close frame, so OnDisconnect can tell a clean
+// client-initiated close from a dropped connection
+func (c *WebSocketConnection) CloseStatus() (uint16, string) {
+	return c.closeCode, c.closeReason
+}
+
 // WebSocketHub manages a collection of connections
 type WebSocketHub struct {
 	// Registered connections
@@ -116,6 +228,33 @@ type WebSocketHub struct {
 
 	// Configuration
 	Config WebSocketConfig
+
+	// Named rooms and their members, guarded by roomsMu
+	rooms   map[string]map[*WebSocketConnection]bool
+	roomsMu sync.RWMutex
+
+	// Messages targeted at a room, a single client or everyone but one client
+	outbound chan hubMessage
+
+	// Connections accepted or being upgraded, used to enforce MaxConnections
+	slots atomic.Int64
+
+	// quit asks the event loop to stop and makes senders give up waiting on
+	// it; done is closed once every connection has been closed
+	quit     chan struct{}
+	done     chan struct{}
+	quitOnce sync.Once
+
+	// snapshot asks the event loop for the current connections
+	snapshot chan chan []*WebSocketConnection
+}
+
+// hubMessage is a message queued for delivery to a subset of the connections
+type hubMessage struct {
+	room   string               // deliver only to members of this room
+	to     string               // deliver only to the connection with this ID
+	except *WebSocketConnection // skip this connection
+	msg    []byte
 }
 
 // NewWebSocketHub creates a new hub
@@ -127,6 +266,12 @@ func NewWebSocketHub(room string, cfg WebSocketConfig) *WebSocketHub {
 		Broadcast:   make(chan []byte),
 		Room:        room,
 		Config:      cfg,
+
+		rooms:    make(map[string]map[*WebSocketConnection]bool),
+		outbound: make(chan hubMessage),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+		snapshot: make(chan chan []*WebSocketConnection),
 	}
 }
 
@@ -135,6 +280,10 @@ func (h *WebSocketHub) Run() {
 	log.Printf("Starting WebSocket hub for room: %s", h.Room)
 	for {
 		select {
+		case <-h.quit:
+			h.closeAll()
+			return
+
 		case conn := <-h.Register:
 			// Add the connection to our map
 			h.Connections[conn] = true
@@ -143,12 +292,15 @@ func (h *WebSocketHub) Run() {
 			if h.Config.OnConnect != nil {
 				h.Config.OnConnect(conn)
 			}
+			if conn.registered != nil {
+				close(conn.registered)
+			}
 
 		case conn := <-h.Unregister:
 			// Remove the connection from our map if it exists
 			if _, ok := h.Connections[conn]; ok {
 				log.Printf("Hub: unregistered connection %s, remaining: %d", conn.ID, len(h.Connections)-1)
-				delete(h.Connections, conn)
+				h.removeConnection(conn)
 				// Call the OnDisconnect handler if provided
 				if h.Config.OnDisconnect != nil {
 					h.Config.OnDisconnect(conn)
@@ -162,7 +314,7 @@ func (h *WebSocketHub) Run() {
 			log.Printf("Hub: broadcasting message to %d connections: %s", len(h.Connections), string(msg))
 			// Send the message to all connected clients
 			for conn := range h.Connections {
-				if !conn.isConnected {
+				if !conn.isConnected.Load() {
 					// Skip disconnected clients
 					log.Printf("Hub: skipping disconnected client %s", conn.ID)
 					continue
@@ -177,17 +329,315 @@ func (h *WebSocketHub) Run() {
 					// Client's buffer is full, likely stuck or slow
 					log.Printf("Hub: failed to send to connection %s, removing", conn.ID)
 					close(conn.Send)
-					delete(h.Connections, conn)
+					h.removeConnection(conn)
 				}
 			}
+
+		case reply := <-h.snapshot:
+			conns := make([]*WebSocketConnection, 0, len(h.Connections))
+			for conn := range h.Connections {
+				conns = append(conns, conn)
+			}
+			reply <- conns
+
+		case m := <-h.outbound:
+			for _, conn := range h.recipients(m) {
+				if !conn.isConnected.Load() {
+					continue
+				}
+				select {
+				case conn.Send <- m.msg:
+				default:
+					log.Printf("Hub: failed to send to connection %s, removing", conn.ID)
+					close(conn.Send)
+					h.removeConnection(conn)
+				}
+			}
+		}
+	}
+}
+
+// removeConnection drops a connection from the hub, its rooms and its slot
+func (h *WebSocketHub) removeConnection(conn *WebSocketConnection) {
+	delete(h.Connections, conn)
+	h.leaveAllRooms(conn)
+	if conn.hasSlot {
+		h.releaseSlot()
+	}
+}
+
+// acquireSlot reserves room for a new connection, failing once max is reached.
+// A max of zero or less means no limit
+func (h *WebSocketHub) acquireSlot(max int) bool {
+	for {
+		current := h.slots.Load()
+		if max > 0 && current >= int64(max) {
+			return false
 		}
+		if h.slots.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
+// releaseSlot frees a slot taken by acquireSlot
+func (h *WebSocketHub) releaseSlot() {
+	h.slots.Add(-1)
+}
+
+// recipients resolves the registered connections a hubMessage is addressed to
+func (h *WebSocketHub) recipients(m hubMessage) []*WebSocketConnection {
+	var candidates []*WebSocketConnection
+	if m.room != "" {
+		h.roomsMu.RLock()
+		for conn := range h.rooms[m.room] {
+			candidates = append(candidates, conn)
+		}
+		h.roomsMu.RUnlock()
+	} else {
+		for conn := range h.Connections {
+			candidates = append(candidates, conn)
+		}
+	}
+
+	result := candidates[:0]
+	for _, conn := range candidates {
+		if _, ok := h.Connections[conn]; !ok || conn == m.except {
+			continue
+		}
+		if m.to != "" && conn.ID != m.to {
+			continue
+		}
+		result = append(result, conn)
+	}
+	return result
+}
+
+// Join adds the connection to a named room of its hub
+func (c *WebSocketConnection) Join(room string) {
+	if c.Hub != nil {
+		c.Hub.joinRoom(room, c)
+	}
+}
+
+// Leave removes the connection from a named room of its hub
+func (c *WebSocketConnection) Leave(room string) {
+	if c.Hub != nil {
+		c.Hub.leaveRoom(room, c)
+	}
+}
+
+// BroadcastToRoom sends a message to every connection that joined the room
+func (h *WebSocketHub) BroadcastToRoom(room string, msg []byte) {
+	h.send(hubMessage{room: room, msg: msg})
+}
+
+// BroadcastExcept sends a message to every connection except the given one
+func (h *WebSocketHub) BroadcastExcept(conn *WebSocketConnection, msg []byte) {
+	h.send(hubMessage{except: conn, msg: msg})
+}
+
+// SendTo sends a message to the connection with the given ID, if registered
+func (h *WebSocketHub) SendTo(id string, msg []byte) {
+	h.send(hubMessage{to: id, msg: msg})
+}
+
+// send queues a targeted message, dropping it if the hub is shutting down
+func (h *WebSocketHub) send(m hubMessage) {
+	select {
+	case h.outbound <- m:
+	case <-h.quit:
+	}
+}
+
+// RoomCount returns the number of connections in a room
+func (h *WebSocketHub) RoomCount(room string) int {
+	h.roomsMu.RLock()
+	defer h.roomsMu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Rooms returns the names of the rooms that currently have members
+func (h *WebSocketHub) Rooms() []string {
+	h.roomsMu.RLock()
+	defer h.roomsMu.RUnlock()
+	names := make([]string, 0, len(h.rooms))
+	for name := range h.rooms {
+		names = append(names, name)
+	}
+	return names
+}
+
+// joinRoom registers a connection as a member of a room
+func (h *WebSocketHub) joinRoom(room string, conn *WebSocketConnection) {
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	members, ok := h.rooms[room]
+	if !ok {
+		members = make(map[*WebSocketConnection]bool)
+		h.rooms[room] = members
+	}
+	members[conn] = true
+}
+
+// leaveRoom removes a connection from a room, deleting the room once empty
+func (h *WebSocketHub) leaveRoom(room string, conn *WebSocketConnection) {
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	h.removeFromRoom(room, conn)
+}
+
+// leaveAllRooms removes a connection from every room it joined
+func (h *WebSocketHub) leaveAllRooms(conn *WebSocketConnection) {
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	for room := range h.rooms {
+		h.removeFromRoom(room, conn)
+	}
+}
+
+// removeFromRoom must be called with roomsMu held
+func (h *WebSocketHub) removeFromRoom(room string, conn *WebSocketConnection) {
+	members, ok := h.rooms[room]
+	if !ok {
+		return
+	}
+	delete(members, conn)
+	if len(members) == 0 {
+		delete(h.rooms, room)
 	}
 }
 
 // Broadcast sends a message to all connected clients
 func (h *WebSocketHub) BroadcastMessage(msg []byte) {
 	log.Printf("Broadcasting message to hub (active connections: %d): %s", len(h.Connections), string(msg))
-	h.Broadcast <- msg
+	select {
+	case h.Broadcast <- msg:
+	case <-h.quit:
+	}
+}
+
+// Shutdown stops the hub's event loop, closes every connection with a
+// "going away" close frame and removes the hub from the endpoint registry.
+// It returns ctx.Err() if the loop doesn't stop before ctx is done
+func (h *WebSocketHub) Shutdown(ctx context.Context) error {
+	h.quitOnce.Do(func() {
+		close(h.quit)
+	})
+
+	hubsMu.Lock()
+	for key, hub := range hubs {
+		if hub == h {
+			delete(hubs, key)
+		}
+	}
+	hubsMu.Unlock()
+
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeAll runs on the event loop once quit is closed
+func (h *WebSocketHub) closeAll() {
+	defer close(h.done)
+	log.Printf("Hub: shutting down, closing %d connections", len(h.Connections))
+	for conn := range h.Connections {
+		conn.CloseWithCode(CloseGoingAway, "server shutdown")
+		close(conn.Send)
+		h.removeConnection(conn)
+	}
+}
+
+// stopped reports whether the hub's event loop has been shut down
+func (h *WebSocketHub) stopped() bool {
+	select {
+	case <-h.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// unregister hands a connection to the event loop for removal, unless the
+// hub is shutting down
+func (h *WebSocketHub) unregister(conn *WebSocketConnection) {
+	select {
+	case h.Unregister <- conn:
+	case <-h.quit:
+	}
+}
+
+// CloseAll sends reason to every connected client as a text message and then
+// closes each connection with code and reason. The hub keeps running and
+// accepts new connections
+func (h *WebSocketHub) CloseAll(code uint16, reason string) {
+	reply := make(chan []*WebSocketConnection, 1)
+	select {
+	case h.snapshot <- reply:
+	case <-h.quit:
+		return
+	}
+	// Closing unregisters through the event loop, so do it outside of it
+	for _, conn := range <-reply {
+		if reason != "" {
+			conn.SendText(reason)
+		}
+		conn.CloseWithCode(code, reason)
+	}
+}
+
+// webSocketHubs are the hubs of the endpoints registered on a router with
+// WebSocket or WithWebSocketHandler; the router's clones share them
+type webSocketHubs struct {
+	mu   sync.Mutex
+	hubs []*WebSocketHub
+}
+
+// add records hub unless it is already there
+func (s *webSocketHubs) add(hub *WebSocketHub) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Contains(s.hubs, hub) {
+		s.hubs = append(s.hubs, hub)
+	}
+}
+
+// ShutdownWebSockets tells the clients of the router's WebSocket endpoints
+// that the server is going away (a "server shutting down" message and a 1001
+// close) and shuts their hubs down. Hubs of other routers keep running
+func (r *MoraRouter) ShutdownWebSockets(ctx context.Context) error {
+	r.webSockets.mu.Lock()
+	all := r.webSockets.hubs
+	r.webSockets.hubs = nil
+	r.webSockets.mu.Unlock()
+
+	var errs []error
+	for _, hub := range all {
+		hub.CloseAll(CloseGoingAway, "server shutting down")
+		if err := hub.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CloseAllHubs shuts down every WebSocket hub created by WebSocketHandler,
+// mainly so tests don't leak hub goroutines
+func CloseAllHubs() {
+	hubsMu.Lock()
+	all := make([]*WebSocketHub, 0, len(hubs))
+	for _, hub := range hubs {
+		all = append(all, hub)
+	}
+	hubsMu.Unlock()
+
+	for _, hub := range all {
+		hub.Shutdown(context.Background())
+	}
 }
 
 // Count returns the number of active connections
@@ -201,13 +651,40 @@ type WebSocketConfig struct {
 	MaxMessageSize int
 	PingInterval   time.Duration
 	AllowedOrigins []string
+	// AllowAnyOrigin disables the same-origin check applied when AllowedOrigins is empty
+	AllowAnyOrigin bool
 	MessageHandler func(conn *WebSocketConnection, msg []byte)
 	OnConnect      func(conn *WebSocketConnection)
-	OnDisconnect   func(conn *WebSocketConnection)
+	// OnDisconnect is called once the connection leaves the hub; use
+	// conn.CloseStatus() to read the code and reason sent by the peer
+	OnDisconnect func(conn *WebSocketConnection)
+	// OnPing is called with the payload of every ping frame received from the client
+	OnPing func(conn *WebSocketConnection, payload []byte)
+	// OnPong is called with the payload of every pong frame received from the client
+	OnPong func(conn *WebSocketConnection, payload []byte)
+	// MaxConnections caps the simultaneous connections on this endpoint's hub;
+	// further upgrades are refused with 503 (0 means no limit)
+	MaxConnections int
+	// MissedPongLimit is how many ping intervals may pass without a pong
+	// before the connection is considered dead and closed (default 3)
+	MissedPongLimit int
+	// EnableCompression negotiates permessage-deflate (RFC 7692) with clients
+	// that offer it
+	EnableCompression bool
+	// CompressionContextTakeover keeps the deflate window between messages,
+	// which compresses repetitive traffic better at the cost of ~32KB per
+	// connection and direction; when false every message is compressed alone
+	CompressionContextTakeover bool
 }
 
 // WebSocketHandler handles a WebSocket connection
 func WebSocketHandler(config WebSocketConfig) HandlerFunc {
+	handler, _ := webSocketHandler(config)
+	return handler
+}
+
+// webSocketHandler is WebSocketHandler that also returns the endpoint's hub
+func webSocketHandler(config WebSocketConfig) (HandlerFunc, *WebSocketHub) {
 	if config.MaxMessageSize == 0 {
 		config.MaxMessageSize = 4096 // 4KB default
 	}
@@ -215,6 +692,10 @@ func WebSocketHandler(config WebSocketConfig) HandlerFunc {
 	if config.PingInterval == 0 {
 		config.PingInterval = 30 * time.Second
 	}
+
+	if config.MissedPongLimit == 0 {
+		config.MissedPongLimit = 3
+	}
 	// Create a shared hub for all connections to this endpoint
 	// Use a static map to store hubs by path
 	hubKey := config.Path
@@ -230,42 +711,54 @@ func WebSocketHandler(config WebSocketConfig) HandlerFunc {
 	}
 	hubsMu.Unlock()
 
-	return func(w http.ResponseWriter, r *http.Request, params Params) {
-		// Check origin if configured
-		if len(config.AllowedOrigins) > 0 {
-			origin := r.Header.Get("Origin")
-			allowed := false
-			for _, o := range config.AllowedOrigins {
-				if o == "*" || o == origin {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				http.Error(w, "Origin not allowed", http.StatusForbidden)
-				return
-			}
+	handler := func(w http.ResponseWriter, r *http.Request, params Params) {
+		// Check origin to prevent cross-site WebSocket hijacking
+		if !checkOrigin(r, config) {
+			http.Error(w, "Origin not allowed", http.StatusForbidden)
+			return
 		}
 
 		// Verify it's a websocket upgrade request
 		if !isWebSocketUpgrade(r) {
 			http.Error(w, "Expected WebSocket Upgrade", http.StatusBadRequest)
 			return
-		} // Get the underlying connection using hijack before doing the handshake
+		}
+
+		// Refuse the upgrade before hijacking if the hub is gone or full
+		if hub.stopped() {
+			http.Error(w, "WebSocket endpoint shut down", http.StatusServiceUnavailable)
+			return
+		}
+		if !hub.acquireSlot(config.MaxConnections) {
+			http.Error(w, "Too many WebSocket connections", http.StatusServiceUnavailable)
+			return
+		}
+
+		// Get the underlying connection using hijack before doing the handshake
 		hijacker, ok := w.(http.Hijacker)
 		if !ok {
+			hub.releaseSlot()
 			http.Error(w, "WebSocket error: connection doesn't support hijacking", http.StatusInternalServerError)
 			return
 		}
 
 		netConn, bufrw, err := hijacker.Hijack()
 		if err != nil {
+			hub.releaseSlot()
 			http.Error(w, fmt.Sprintf("WebSocket hijack failed: %v", err), http.StatusInternalServerError)
 			return
 		}
 
+		// Negotiate compression before answering the handshake
+		var deflate *wsDeflate
+		var extensions string
+		if config.EnableCompression {
+			deflate, extensions = negotiateDeflate(r.Header.Get("Sec-WebSocket-Extensions"), config.CompressionContextTakeover)
+		}
+
 		// Perform handshake by writing directly to the hijacked connection
-		if err := writeHandshake(netConn, r); err != nil {
+		if err := writeHandshake(netConn, r, extensions); err != nil {
+			hub.releaseSlot()
 			netConn.Close()
 			return
 		}
@@ -273,19 +766,35 @@ func WebSocketHandler(config WebSocketConfig) HandlerFunc {
 		connID := fmt.Sprintf("%d", time.Now().UnixNano())
 		log.Printf("New WebSocket connection: %s (path: %s)", connID, config.Path)
 
+		ctx, cancel := context.WithCancel(r.Context())
 		conn := &WebSocketConnection{
-			Conn:        w,
-			Request:     r,
-			ID:          connID,
-			Hub:         hub,
-			Send:        make(chan []byte, 256),
-			isConnected: true,
-			netConn:     netConn,
-			bufrw:       bufrw,
+			Conn:    w,
+			Request: r,
+			ID:      connID,
+			Hub:     hub,
+			Send:    make(chan []byte, 256),
+			netConn: netConn,
+			bufrw:   bufrw,
+			hasSlot: true,
+			deflate: deflate,
+			ctx:     ctx,
+			cancel:  cancel,
+
+			registered: make(chan struct{}),
 		}
 
+		conn.isConnected.Store(true)
+
 		// Register this connection with the hub
-		hub.Register <- conn
+		select {
+		case hub.Register <- conn:
+		case <-hub.quit:
+			conn.CloseWithCode(CloseGoingAway, "server shutdown")
+			return
+		}
+
+		// Wait for OnConnect so its state is visible to the first message
+		<-conn.registered
 
 		// Debug output
 		log.Printf("Registered connection %s with hub. Calling handleWebSocketConnection", connID)
@@ -294,6 +803,33 @@ func WebSocketHandler(config WebSocketConfig) HandlerFunc {
 		// since we already hijacked the connection
 		handleWebSocketConnection(conn, config)
 	}
+	return handler, hub
+}
+
+// checkOrigin validates the Origin header against AllowedOrigins or, when none
+// are configured, requires it to match the request Host unless AllowAnyOrigin is set
+func checkOrigin(r *http.Request, config WebSocketConfig) bool {
+	origin := r.Header.Get("Origin")
+
+	if len(config.AllowedOrigins) > 0 {
+		for _, o := range config.AllowedOrigins {
+			if o == "*" || o == origin {
+				return true
+			}
+		}
+		return false
+	}
+
+	if config.AllowAnyOrigin || origin == "" {
+		// Non-browser clients don't send Origin
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
 }
 
 // isWebSocketUpgrade checks if the request is a WebSocket upgrade
@@ -327,8 +863,9 @@ func performHandshake(w http.ResponseWriter, r *http.Request) bool {
 	return true
 }
 
-// writeHandshake writes the WebSocket handshake directly to the connection
-func writeHandshake(conn net.Conn, r *http.Request) error {
+// writeHandshake writes the WebSocket handshake directly to the connection,
+// including the negotiated Sec-WebSocket-Extensions value if any
+func writeHandshake(conn net.Conn, r *http.Request, extensions string) error {
 	// Get the WebSocket key
 	key := r.Header.Get("Sec-WebSocket-Key")
 	if key == "" {
@@ -345,9 +882,13 @@ func writeHandshake(conn net.Conn, r *http.Request) error {
 		"HTTP/1.1 101 Switching Protocols\r\n"+
 			"Upgrade: websocket\r\n"+
 			"Connection: Upgrade\r\n"+
-			"Sec-WebSocket-Accept: %s\r\n\r\n",
+			"Sec-WebSocket-Accept: %s\r\n",
 		acceptKey,
 	)
+	if extensions != "" {
+		handshake += "Sec-WebSocket-Extensions: " + extensions + "\r\n"
+	}
+	handshake += "\r\n"
 
 	_, err := conn.Write([]byte(handshake))
 	return err
@@ -358,15 +899,24 @@ func handleWebSocketConnection(conn *WebSocketConnection, config WebSocketConfig
 	defer func() {
 		// When this function returns, the connection is closed
 		conn.netConn.Close()
-		// Ensure we unregister from the hub
-		if conn.Hub != nil && conn.isConnected {
-			conn.Hub.Unregister <- conn
+		if conn.cancel != nil {
+			conn.cancel()
+		}
+		// Ensure we unregister from the hub; a failed write only flags the
+		// connection, and the hub ignores connections it already removed
+		conn.isConnected.Store(false)
+		if conn.Hub != nil {
+			conn.Hub.unregister(conn)
 		}
 	}()
 
 	// Set initial read deadline
 	conn.netConn.SetReadDeadline(time.Now().Add(config.PingInterval + 10*time.Second))
 
+	// Liveness is measured from the moment the connection is served
+	conn.lastPong.Store(time.Now().UnixNano())
+	pongTimeout := config.PingInterval * time.Duration(config.MissedPongLimit)
+
 	// Send ping frames periodically to keep connection alive
 	pingTicker := time.NewTicker(config.PingInterval)
 	defer pingTicker.Stop()
@@ -386,16 +936,13 @@ func handleWebSocketConnection(conn *WebSocketConnection, config WebSocketConfig
 					return
 				}
 
-				if !conn.isConnected {
+				if !conn.isConnected.Load() {
 					return
 				}
 
-				frame := newTextFrame(message)
-				// Set a write deadline to prevent blocked connections
-				conn.netConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-				if _, err := conn.netConn.Write(frame); err != nil {
+				if err := conn.writeData(0x1, message); err != nil {
 					// If we can't write to the connection, it's likely dead
-					conn.isConnected = false
+					conn.isConnected.Store(false)
 					// Don't use Unregister here to avoid race conditions
 					return
 				}
@@ -410,15 +957,19 @@ func handleWebSocketConnection(conn *WebSocketConnection, config WebSocketConfig
 		for {
 			select {
 			case <-pingTicker.C:
-				if !conn.isConnected {
+				if !conn.isConnected.Load() {
+					return
+				}
+				// Too many pings went unanswered: drop the ghost connection
+				if time.Since(time.Unix(0, conn.lastPong.Load())) > pongTimeout {
+					log.Printf("No pong from client %s in %s, closing", conn.ID, pongTimeout)
+					conn.CloseWithCode(CloseGoingAway, "pong timeout")
 					return
 				}
 				// Send a ping frame
-				pingFrame := newPingFrame([]byte{})
-				conn.netConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-				if _, err := conn.netConn.Write(pingFrame); err != nil {
+				if err := conn.writeControl(newPingFrame([]byte{})); err != nil {
 					// Connection is dead
-					conn.isConnected = false
+					conn.isConnected.Store(false)
 					return
 				}
 			case <-done:
@@ -437,6 +988,7 @@ func handleWebSocketConnection(conn *WebSocketConnection, config WebSocketConfig
 
 		// Parse first two bytes for opcode and mask bit
 		fin := (frameHeader[0] & 0x80) != 0
+		compressed := (frameHeader[0] & 0x40) != 0
 		opcode := frameHeader[0] & 0x0F
 		masked := (frameHeader[1] & 0x80) != 0
 		payloadLen := int(frameHeader[1] & 0x7F)
@@ -459,7 +1011,7 @@ func handleWebSocketConnection(conn *WebSocketConnection, config WebSocketConfig
 		// Limit payload size
 		if payloadLen > config.MaxMessageSize {
 			log.Printf("WebSocket message too large: %d bytes", payloadLen)
-			conn.Close()
+			conn.CloseWithCode(CloseMessageTooBig, "message too large")
 			break
 		}
 
@@ -485,6 +1037,27 @@ func handleWebSocketConnection(conn *WebSocketConnection, config WebSocketConfig
 			}
 		}
 
+		// RSV1 marks a compressed data frame and is only valid once
+		// permessage-deflate has been negotiated
+		if compressed {
+			if conn.deflate == nil || opcode&0x8 != 0 {
+				conn.CloseWithCode(CloseProtocolError, "unexpected RSV1 bit")
+				break
+			}
+			inflated, err := conn.deflate.decompress(payload, config.MaxMessageSize)
+			if err == errMessageTooBig {
+				log.Printf("WebSocket message too large once inflated")
+				conn.CloseWithCode(CloseMessageTooBig, "message too large")
+				break
+			}
+			if err != nil {
+				log.Printf("WebSocket: failed to inflate message from client %s: %v", conn.ID, err)
+				conn.CloseWithCode(CloseInvalidPayload, "invalid compressed data")
+				break
+			}
+			payload = inflated
+		}
+
 		// Handle based on opcode
 		switch opcode {
 		case 0x1: // Text frame
@@ -508,19 +1081,32 @@ func handleWebSocketConnection(conn *WebSocketConnection, config WebSocketConfig
 			conn.netConn.SetReadDeadline(time.Now().Add(config.PingInterval + 10*time.Second))
 
 		case 0x8: // Close frame
-			log.Printf("Received close frame from client %s", conn.ID)
-			conn.Close()
+			code, reason := parseClosePayload(payload)
+			log.Printf("Received close frame from client %s: %d %s", conn.ID, code, reason)
+			conn.closeCode = code
+			conn.closeReason = reason
+			// Echo the peer's status code back, as required by RFC 6455
+			if code == CloseNoStatusReceived {
+				code = CloseNormalClosure
+			}
+			conn.CloseWithCode(code, "")
 			return
 
 		case 0x9: // Ping frame, respond with pong
 			log.Printf("Received ping from client %s", conn.ID)
-			pongFrame := newPongFrame(payload)
-			conn.netConn.Write(pongFrame)
+			conn.writeControl(newPongFrame(payload))
+			if config.OnPing != nil {
+				config.OnPing(conn, payload)
+			}
 			// Reset read deadline after processing ping
 			conn.netConn.SetReadDeadline(time.Now().Add(config.PingInterval + 10*time.Second))
 
 		case 0xA: // Pong frame, reset deadline
 			log.Printf("Received pong from client %s", conn.ID)
+			conn.lastPong.Store(time.Now().UnixNano())
+			if config.OnPong != nil {
+				config.OnPong(conn, payload)
+			}
 			conn.netConn.SetReadDeadline(time.Now().Add(config.PingInterval + 10*time.Second))
 		}
 
@@ -548,6 +1134,64 @@ func newPongFrame(data []byte) []byte {
 	return createFrame(0xA, data)
 }
 
+// newCloseFrame builds a close frame carrying a status code and reason
+func newCloseFrame(code uint16, reason string) []byte {
+	if len(reason) > maxCloseReasonLen {
+		// Back up to a rune boundary so a multi-byte character isn't cut in half
+		cut := maxCloseReasonLen
+		for cut > 0 && !utf8.RuneStart(reason[cut]) {
+			cut--
+		}
+		reason = reason[:cut]
+	}
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, code)
+	payload = append(payload, reason...)
+	return createFrame(0x8, payload)
+}
+
+// parseClosePayload extracts the status code and reason from a close frame
+func parseClosePayload(payload []byte) (uint16, string) {
+	if len(payload) < 2 {
+		return CloseNoStatusReceived, ""
+	}
+	return binary.BigEndian.Uint16(payload[:2]), string(payload[2:])
+}
+
+// writeData compresses and writes a text or binary frame while holding the
+// write lock, so compressed frames leave in the order they were compressed
+func (c *WebSocketConnection) writeData(opcode byte, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.write(c.dataFrame(opcode, data))
+}
+
+// writeControl writes a ping, pong or close frame while holding the write lock
+func (c *WebSocketConnection) writeControl(frame []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.write(frame)
+}
+
+// write sends a frame with a deadline to prevent blocked connections; the
+// caller holds writeMu
+func (c *WebSocketConnection) write(frame []byte) error {
+	c.netConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.netConn.Write(frame)
+	return err
+}
+
+// dataFrame builds a text or binary frame, compressing the payload and
+// setting RSV1 when permessage-deflate was negotiated; the caller holds writeMu
+func (c *WebSocketConnection) dataFrame(opcode byte, data []byte) []byte {
+	if c.deflate == nil {
+		return createFrame(opcode, data)
+	}
+	frame := createFrame(opcode, c.deflate.compress(data))
+	frame[0] |= 0x40
+	return frame
+}
+
 func createFrame(opcode byte, data []byte) []byte {
 	length := len(data)
 	var header []byte
@@ -581,6 +1225,120 @@ func createFrame(opcode byte, data []byte) []byte {
 	return frame
 }
 
+// deflateTail is appended to a compressed message before inflating it: the
+// sync marker stripped by the sender plus an empty final block, so the
+// reader ends with io.EOF (RFC 7692, section 7.2.2)
+const deflateTail = "\x00\x00\xff\xff\x01\x00\x00\xff\xff"
+
+// deflateWindow is the LZ77 window size used for context takeover
+const deflateWindow = 32 << 10
+
+var errMessageTooBig = errors.New("message exceeds MaxMessageSize")
+
+// wsDeflate holds the permessage-deflate state negotiated for a connection
+type wsDeflate struct {
+	// Keep the compressor window between outgoing messages
+	serverTakeover bool
+	// The client keeps its window, so inflate with the previous messages
+	clientTakeover bool
+
+	// Compressor state, guarded by the connection's writeMu
+	fw  *flate.Writer
+	buf bytes.Buffer
+
+	// Tail of the inflated client messages, used as dictionary; only the
+	// read loop touches it
+	history []byte
+}
+
+// negotiateDeflate picks the first permessage-deflate offer it can honor from
+// a Sec-WebSocket-Extensions header and returns the connection state and the
+// response value, or nil if no offer is acceptable
+func negotiateDeflate(header string, takeover bool) (*wsDeflate, string) {
+	for _, offer := range strings.Split(header, ",") {
+		params := strings.Split(offer, ";")
+		if strings.TrimSpace(params[0]) != "permessage-deflate" {
+			continue
+		}
+
+		d := &wsDeflate{serverTakeover: takeover, clientTakeover: takeover}
+		acceptable := true
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			switch strings.TrimSpace(name) {
+			case "server_no_context_takeover":
+				d.serverTakeover = false
+			case "client_no_context_takeover":
+				d.clientTakeover = false
+			case "server_max_window_bits":
+				// compress/flate always uses a 32KB window
+				if strings.Trim(strings.TrimSpace(value), `"`) != "15" {
+					acceptable = false
+				}
+			case "client_max_window_bits":
+				// Inflating handles any window size; leaving it out of the
+				// response keeps the client's default
+			default:
+				acceptable = false
+			}
+		}
+		if !acceptable {
+			continue
+		}
+
+		response := "permessage-deflate"
+		if !d.serverTakeover {
+			response += "; server_no_context_takeover"
+		}
+		if !d.clientTakeover {
+			response += "; client_no_context_takeover"
+		}
+		return d, response
+	}
+	return nil, ""
+}
+
+// compress deflates a message and strips the trailing sync marker
+func (d *wsDeflate) compress(data []byte) []byte {
+	d.buf.Reset()
+	if d.fw == nil {
+		d.fw, _ = flate.NewWriter(&d.buf, flate.BestSpeed)
+	} else if !d.serverTakeover {
+		d.fw.Reset(&d.buf)
+	}
+	d.fw.Write(data)
+	d.fw.Flush()
+
+	out := bytes.TrimSuffix(d.buf.Bytes(), []byte(deflateTail[:4]))
+	return append([]byte(nil), out...)
+}
+
+// decompress inflates a message, refusing to produce more than limit bytes
+func (d *wsDeflate) decompress(payload []byte, limit int) ([]byte, error) {
+	var dict []byte
+	if d.clientTakeover {
+		dict = d.history
+	}
+	fr := flate.NewReaderDict(io.MultiReader(bytes.NewReader(payload), strings.NewReader(deflateTail)), dict)
+	defer fr.Close()
+
+	out, err := io.ReadAll(io.LimitReader(fr, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > limit {
+		return nil, errMessageTooBig
+	}
+
+	if d.clientTakeover {
+		d.history = append(d.history, out...)
+		if len(d.history) > deflateWindow {
+			d.history = append([]byte(nil), d.history[len(d.history)-deflateWindow:]...)
+		}
+	}
+	return out, nil
+}
+
 // WebSocket functions for the router
 
 // WithGorillaWebSocket adds WebSocket support to the router (compatibility layer but implements natively)
@@ -707,16 +1465,24 @@ func (r *MoraRouter) WebSocket(path string, handler func(*WebSocketConnection, [
 	}
 
 	log.Printf("Registering WebSocket handler for path: %s", path)
-	r.Get(path, WebSocketHandler(config))
+	r.webSocketRoute(config)
 }
 
 // WithWebSocketHandler adds a WebSocket handler with custom configuration
 func WithWebSocketHandler(config WebSocketConfig) Option {
 	return func(r *MoraRouter) {
-		r.Get(config.Path, WebSocketHandler(config))
+		r.webSocketRoute(config)
 	}
 }
 
+// webSocketRoute registers the endpoint and records its hub so that
+// ShutdownWebSockets closes it along with the router
+func (r *MoraRouter) webSocketRoute(config WebSocketConfig) {
+	handler, hub := webSocketHandler(config)
+	r.webSockets.add(hub)
+	r.Get(config.Path, handler)
+}
+
 // WithWebSockets allows multiple WebSocket endpoints to be defined at once
 func WithWebSockets(handlers map[string]func(*WebSocketConnection, []byte)) Option {
 	return func(r *MoraRouter) {
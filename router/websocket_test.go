@@ -0,0 +1,736 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// dialWebSocket abre una conexión WebSocket cruda contra el servidor de prueba
+func dialWebSocket(t *testing.T, server *httptest.Server, path string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, reader, resp := upgradeWebSocket(t, server, path, "")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected status 101, got %d", resp.StatusCode)
+	}
+	return conn, reader
+}
+
+// upgradeWebSocket envía la petición de upgrade y devuelve la respuesta del servidor
+func upgradeWebSocket(t *testing.T, server *httptest.Server, path, origin string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	headers := map[string]string{}
+	if origin != "" {
+		headers["Origin"] = origin
+	}
+	return upgradeWebSocketWithHeaders(t, server, path, headers)
+}
+
+// upgradeWebSocketWithHeaders envía la petición de upgrade con cabeceras adicionales
+func upgradeWebSocketWithHeaders(t *testing.T, server *httptest.Server, path string, headers map[string]string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	host := strings.TrimPrefix(server.URL, "http://")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatalf("Error dialing server: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	handshake := "GET " + path + " HTTP/1.1\r\n" +
+		"Host: " + host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n"
+	for k, v := range headers {
+		handshake += k + ": " + v + "\r\n"
+	}
+	handshake += "\r\n"
+	if _, err := conn.Write([]byte(handshake)); err != nil {
+		t.Fatalf("Error writing handshake: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Error reading handshake response: %v", err)
+	}
+	return conn, reader, resp
+}
+
+// writeClientFrame envía un frame enmascarado como lo haría un cliente
+func writeClientFrame(t *testing.T, conn net.Conn, opcode byte, payload []byte) {
+	t.Helper()
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 65535:
+		frame = append(frame, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	default:
+		ext := make([]byte, 8)
+		binary.BigEndian.PutUint64(ext, uint64(len(payload)))
+		frame = append(frame, 0x80|127)
+		frame = append(frame, ext...)
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("Error writing frame: %v", err)
+	}
+}
+
+// readServerFrame lee un frame sin máscara enviado por el servidor
+func readServerFrame(t *testing.T, reader *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		t.Fatalf("Error reading frame header: %v", err)
+	}
+	length := int(header[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		io.ReadFull(reader, ext)
+		length = int(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		io.ReadFull(reader, ext)
+		length = int(binary.BigEndian.Uint64(ext))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf("Error reading frame payload: %v", err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+// TestWebSocketPingPongCallbacks verifica Ping y los callbacks OnPing/OnPong
+func TestWebSocketPingPongCallbacks(t *testing.T) {
+	pongs := make(chan []byte, 1)
+	pings := make(chan []byte, 1)
+
+	r := New(WithWebSocketHandler(WebSocketConfig{
+		Path: "/ws-ping-pong",
+		OnConnect: func(conn *WebSocketConnection) {
+			if err := conn.Ping([]byte("heartbeat")); err != nil {
+				t.Errorf("Unexpected ping error: %v", err)
+			}
+		},
+		OnPing: func(conn *WebSocketConnection, payload []byte) {
+			pings <- payload
+		},
+		OnPong: func(conn *WebSocketConnection, payload []byte) {
+			pongs <- payload
+		},
+	}))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, reader := dialWebSocket(t, server, "/ws-ping-pong")
+	defer conn.Close()
+
+	// El servidor inicia el ping de aplicación al conectar
+	opcode, payload := readServerFrame(t, reader)
+	if opcode != 0x9 || string(payload) != "heartbeat" {
+		t.Fatalf("Expected ping 'heartbeat', got opcode %x payload '%s'", opcode, payload)
+	}
+	writeClientFrame(t, conn, 0xA, payload)
+
+	select {
+	case got := <-pongs:
+		if !bytes.Equal(got, []byte("heartbeat")) {
+			t.Errorf("Expected pong payload 'heartbeat', got '%s'", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnPong was not called")
+	}
+
+	// Un ping del cliente recibe su pong y dispara OnPing
+	writeClientFrame(t, conn, 0x9, []byte("client"))
+	opcode, payload = readServerFrame(t, reader)
+	if opcode != 0xA || string(payload) != "client" {
+		t.Errorf("Expected pong 'client', got opcode %x payload '%s'", opcode, payload)
+	}
+	select {
+	case got := <-pings:
+		if string(got) != "client" {
+			t.Errorf("Expected ping payload 'client', got '%s'", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnPing was not called")
+	}
+}
+
+// TestWebSocketRooms verifica Join, Leave y BroadcastToRoom
+func TestWebSocketRooms(t *testing.T) {
+	r := New(WithWebSocketHandler(WebSocketConfig{
+		Path: "/ws-rooms",
+		MessageHandler: func(conn *WebSocketConnection, msg []byte) {
+			cmd, arg, _ := strings.Cut(string(msg), ":")
+			switch cmd {
+			case "join":
+				conn.Join(arg)
+				conn.SendText("joined " + arg)
+			case "leave":
+				conn.Leave(arg)
+				conn.SendText("left " + arg)
+			case "say":
+				conn.Hub.BroadcastToRoom("lobby", []byte(arg))
+			}
+		},
+	}))
+
+	hubsMu.Lock()
+	hub := hubs["/ws-rooms"]
+	hubsMu.Unlock()
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	member, memberReader := dialWebSocket(t, server, "/ws-rooms")
+	defer member.Close()
+	outsider, outsiderReader := dialWebSocket(t, server, "/ws-rooms")
+	defer outsider.Close()
+
+	writeClientFrame(t, member, 0x1, []byte("join:lobby"))
+	if _, payload := readServerFrame(t, memberReader); string(payload) != "joined lobby" {
+		t.Fatalf("Expected join confirmation, got '%s'", payload)
+	}
+	if hub.RoomCount("lobby") != 1 {
+		t.Errorf("Expected 1 member in lobby, got %d", hub.RoomCount("lobby"))
+	}
+
+	// Solo los miembros de la sala reciben el mensaje
+	writeClientFrame(t, outsider, 0x1, []byte("say:hello"))
+	if _, payload := readServerFrame(t, memberReader); string(payload) != "hello" {
+		t.Errorf("Expected 'hello' in lobby, got '%s'", payload)
+	}
+	outsider.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := outsiderReader.ReadByte(); err == nil {
+		t.Errorf("Outsider should not receive room messages")
+	}
+
+	// Al quedar vacía la sala se elimina
+	writeClientFrame(t, member, 0x1, []byte("leave:lobby"))
+	if _, payload := readServerFrame(t, memberReader); string(payload) != "left lobby" {
+		t.Fatalf("Expected leave confirmation, got '%s'", payload)
+	}
+	if len(hub.Rooms()) != 0 {
+		t.Errorf("Expected empty rooms to be removed, got %v", hub.Rooms())
+	}
+}
+
+// TestWebSocketBroadcastExceptAndSendTo verifica los envíos dirigidos del hub
+func TestWebSocketBroadcastExceptAndSendTo(t *testing.T) {
+	r := New(WithWebSocketHandler(WebSocketConfig{
+		Path: "/ws-except",
+		MessageHandler: func(conn *WebSocketConnection, msg []byte) {
+			cmd, arg, _ := strings.Cut(string(msg), ":")
+			switch cmd {
+			case "whoami":
+				conn.SendText(conn.ID)
+			case "others":
+				conn.Hub.BroadcastExcept(conn, []byte(arg))
+			case "to":
+				id, text, _ := strings.Cut(arg, "=")
+				conn.Hub.SendTo(id, []byte(text))
+			}
+		},
+	}))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	sender, senderReader := dialWebSocket(t, server, "/ws-except")
+	defer sender.Close()
+	receiver, receiverReader := dialWebSocket(t, server, "/ws-except")
+	defer receiver.Close()
+
+	writeClientFrame(t, receiver, 0x1, []byte("whoami"))
+	_, receiverID := readServerFrame(t, receiverReader)
+
+	// El emisor no recibe su propio broadcast
+	writeClientFrame(t, sender, 0x1, []byte("others:hi"))
+	if _, payload := readServerFrame(t, receiverReader); string(payload) != "hi" {
+		t.Errorf("Expected 'hi', got '%s'", payload)
+	}
+	sender.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := senderReader.ReadByte(); err == nil {
+		t.Errorf("Sender should not receive its own broadcast")
+	}
+	sender.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// Mensaje directo por ID
+	writeClientFrame(t, sender, 0x1, []byte("to:"+string(receiverID)+"=private"))
+	if _, payload := readServerFrame(t, receiverReader); string(payload) != "private" {
+		t.Errorf("Expected 'private', got '%s'", payload)
+	}
+}
+
+// TestWebSocketCloseCodes verifica el envío y la recepción de códigos de cierre
+func TestWebSocketCloseCodes(t *testing.T) {
+	type status struct {
+		code   uint16
+		reason string
+	}
+	closed := make(chan status, 1)
+
+	r := New(WithWebSocketHandler(WebSocketConfig{
+		Path: "/ws-close",
+		MessageHandler: func(conn *WebSocketConnection, msg []byte) {
+			conn.CloseWithCode(ClosePolicyViolation, strings.Repeat("ñ", 100))
+		},
+		OnDisconnect: func(conn *WebSocketConnection) {
+			code, reason := conn.CloseStatus()
+			closed <- status{code, reason}
+		},
+	}))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// El cliente cierra con código y motivo
+	conn, reader := dialWebSocket(t, server, "/ws-close")
+	payload := append([]byte{0x03, 0xE9}, []byte("going away")...) // 1001
+	writeClientFrame(t, conn, 0x8, payload)
+
+	opcode, echo := readServerFrame(t, reader)
+	if opcode != 0x8 || len(echo) < 2 || uint16(echo[0])<<8|uint16(echo[1]) != CloseGoingAway {
+		t.Errorf("Expected close frame echoing 1001, got opcode %x payload %v", opcode, echo)
+	}
+	select {
+	case st := <-closed:
+		if st.code != CloseGoingAway || st.reason != "going away" {
+			t.Errorf("Expected OnDisconnect to see 1001 'going away', got %d '%s'", st.code, st.reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnDisconnect was not called")
+	}
+	conn.Close()
+
+	// El servidor cierra con código y un motivo truncado a 123 bytes
+	conn, reader = dialWebSocket(t, server, "/ws-close")
+	defer conn.Close()
+	writeClientFrame(t, conn, 0x1, []byte("bye"))
+
+	opcode, payload = readServerFrame(t, reader)
+	if opcode != 0x8 {
+		t.Fatalf("Expected close frame, got opcode %x", opcode)
+	}
+	code, reason := parseClosePayload(payload)
+	if code != ClosePolicyViolation {
+		t.Errorf("Expected code 1008, got %d", code)
+	}
+	if len(reason) > 123 || !utf8.ValidString(reason) {
+		t.Errorf("Expected valid UTF-8 reason of at most 123 bytes, got %d bytes", len(reason))
+	}
+}
+
+// TestWebSocketPongDeadline verifica que se cierren conexiones que no responden a los pings
+func TestWebSocketPongDeadline(t *testing.T) {
+	disconnected := make(chan struct{}, 1)
+
+	r := New(WithWebSocketHandler(WebSocketConfig{
+		Path:            "/ws-pong-deadline",
+		PingInterval:    50 * time.Millisecond,
+		MissedPongLimit: 2,
+		OnDisconnect: func(conn *WebSocketConnection) {
+			disconnected <- struct{}{}
+		},
+	}))
+
+	hubsMu.Lock()
+	hub := hubs["/ws-pong-deadline"]
+	hubsMu.Unlock()
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// El cliente lee los pings pero nunca responde con pong
+	conn, reader := dialWebSocket(t, server, "/ws-pong-deadline")
+	defer conn.Close()
+	go io.Copy(io.Discard, reader)
+
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected connection without pongs to be closed")
+	}
+	if hub.Count() != 0 {
+		t.Errorf("Expected hub to have no connections, got %d", hub.Count())
+	}
+}
+
+// TestWebSocketMaxConnections verifica que se rechacen conexiones por encima del límite
+func TestWebSocketMaxConnections(t *testing.T) {
+	const limit = 2
+	r := New(WithWebSocketHandler(WebSocketConfig{
+		Path:           "/ws-max-conns",
+		MaxConnections: limit,
+	}))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	for i := 0; i < limit; i++ {
+		conn, _ := dialWebSocket(t, server, "/ws-max-conns")
+		defer conn.Close()
+	}
+
+	// La conexión N+1 se rechaza con 503 antes del upgrade
+	conn, _, resp := upgradeWebSocket(t, server, "/ws-max-conns", "")
+	defer conn.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 over the limit, got %d", resp.StatusCode)
+	}
+}
+
+// TestWebSocketOriginCheck verifica la comprobación de origen por defecto
+func TestWebSocketOriginCheck(t *testing.T) {
+	r := New(
+		WithWebSocketHandler(WebSocketConfig{Path: "/ws-origin"}),
+		WithWebSocketHandler(WebSocketConfig{Path: "/ws-origin-any", AllowAnyOrigin: true}),
+	)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// Mismo origen: permitido
+	conn, _, resp := upgradeWebSocket(t, server, "/ws-origin", server.URL)
+	conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Expected same-origin upgrade to succeed, got %d", resp.StatusCode)
+	}
+
+	// Otro origen: rechazado por defecto
+	conn, _, resp = upgradeWebSocket(t, server, "/ws-origin", "https://evil.example.com")
+	conn.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected cross-origin upgrade to be refused, got %d", resp.StatusCode)
+	}
+
+	// AllowAnyOrigin conserva el comportamiento permisivo
+	conn, _, resp = upgradeWebSocket(t, server, "/ws-origin-any", "https://evil.example.com")
+	conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Expected AllowAnyOrigin to accept any origin, got %d", resp.StatusCode)
+	}
+}
+
+// TestWebSocketHubShutdown verifica el cierre ordenado de un hub
+func TestWebSocketHubShutdown(t *testing.T) {
+	r := New(WithWebSocketHandler(WebSocketConfig{Path: "/ws-shutdown"}))
+
+	hubsMu.Lock()
+	hub := hubs["/ws-shutdown"]
+	hubsMu.Unlock()
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, reader := dialWebSocket(t, server, "/ws-shutdown")
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+
+	// El cliente recibe un frame de cierre "going away"
+	opcode, payload := readServerFrame(t, reader)
+	if code, _ := parseClosePayload(payload); opcode != 0x8 || code != CloseGoingAway {
+		t.Errorf("Expected close frame 1001, got opcode %x code %d", opcode, code)
+	}
+
+	hubsMu.Lock()
+	_, registered := hubs["/ws-shutdown"]
+	hubsMu.Unlock()
+	if registered {
+		t.Errorf("Expected hub to be removed from the registry")
+	}
+
+	// Las operaciones sobre un hub detenido no bloquean y rechazan nuevas conexiones
+	hub.BroadcastMessage([]byte("ignored"))
+	newConn, _, resp := upgradeWebSocket(t, server, "/ws-shutdown", "")
+	newConn.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 after shutdown, got %d", resp.StatusCode)
+	}
+
+	// CloseAllHubs detiene el resto de hubs registrados
+	New(WithWebSocketHandler(WebSocketConfig{Path: "/ws-shutdown-all"}))
+	CloseAllHubs()
+	hubsMu.Lock()
+	remaining := len(hubs)
+	hubsMu.Unlock()
+	if remaining != 0 {
+		t.Errorf("Expected no hubs after CloseAllHubs, got %d", remaining)
+	}
+}
+
+// TestServeShutdownWebSockets verifica que el apagado de Serve avise a los
+// clientes WebSocket del router sin cerrar los de otros routers
+func TestServeShutdownWebSockets(t *testing.T) {
+	connected := make(chan struct{}, 1)
+	r := New(WithWebSocketHandler(WebSocketConfig{
+		Path:      "/ws-serve-shutdown",
+		OnConnect: func(conn *WebSocketConnection) { connected <- struct{}{} },
+	}))
+	other := New(WithWebSocketHandler(WebSocketConfig{Path: "/ws-serve-other"}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error reserving port: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := &http.Server{Addr: addr, ReadHeaderTimeout: time.Second}
+	served := make(chan error, 1)
+	go func() {
+		served <- r.Serve(ctx, srv)
+	}()
+	for i := 0; i < 50; i++ {
+		if c, err := net.Dial("tcp", addr); err == nil {
+			c.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server := &httptest.Server{URL: "http://" + addr}
+	conn, reader := dialWebSocket(t, server, "/ws-serve-shutdown")
+	defer conn.Close()
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the connection to register")
+	}
+
+	// Iniciar el apagado ordenado
+	cancel()
+
+	opcode, payload := readServerFrame(t, reader)
+	if opcode != 0x1 || string(payload) != "server shutting down" {
+		t.Errorf("Expected 'server shutting down' text frame, got opcode %x '%s'", opcode, payload)
+	}
+	opcode, payload = readServerFrame(t, reader)
+	if code, reason := parseClosePayload(payload); opcode != 0x8 || code != CloseGoingAway || reason != "server shutting down" {
+		t.Errorf("Expected close frame 1001 'server shutting down', got opcode %x code %d '%s'", opcode, code, reason)
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Expected Serve to return nil after shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for Serve to return")
+	}
+	if srv.Handler != r {
+		t.Error("Expected the router to be the server's handler")
+	}
+
+	// El hub de otro router sigue aceptando conexiones
+	ws, err := NewTestClient(other).WebSocket("/ws-serve-other")
+	if err != nil {
+		t.Fatalf("Expected the other router's hub to keep running, got %v", err)
+	}
+	ws.Close()
+}
+
+// TestClientWebSocket verifica el cliente WebSocket en memoria de TestClient
+func TestClientWebSocket(t *testing.T) {
+	r := New(WithWebSocketHandler(WebSocketConfig{
+		Path: "/ws-client",
+		MessageHandler: func(conn *WebSocketConnection, msg []byte) {
+			cmd, arg, _ := strings.Cut(string(msg), ":")
+			switch cmd {
+			case "echo":
+				conn.SendText(arg)
+			case "all":
+				conn.Hub.BroadcastMessage([]byte(arg))
+			}
+		},
+	}))
+	client := NewTestClient(r)
+
+	first, err := client.WebSocket("/ws-client")
+	if err != nil {
+		t.Fatalf("Unexpected upgrade error: %v", err)
+	}
+	second, err := client.WebSocket("/ws-client")
+	if err != nil {
+		t.Fatalf("Unexpected upgrade error: %v", err)
+	}
+
+	// Eco al propio cliente
+	if err := first.Send([]byte("echo:hello")); err != nil {
+		t.Fatalf("Unexpected send error: %v", err)
+	}
+	if msg, err := first.Receive(); err != nil || string(msg) != "hello" {
+		t.Errorf("Expected echo 'hello', got '%s' (%v)", msg, err)
+	}
+
+	// Broadcast a todas las conexiones del hub
+	if err := second.Send([]byte("all:news")); err != nil {
+		t.Fatalf("Unexpected send error: %v", err)
+	}
+	for _, ws := range []*TestWebSocketConn{first, second} {
+		if msg, err := ws.Receive(); err != nil || string(msg) != "news" {
+			t.Errorf("Expected broadcast 'news', got '%s' (%v)", msg, err)
+		}
+	}
+
+	if err := first.Close(); err != nil {
+		t.Errorf("Unexpected close error: %v", err)
+	}
+	if err := second.Close(); err != nil {
+		t.Errorf("Unexpected close error: %v", err)
+	}
+
+	// Un upgrade rechazado devuelve error con el estado
+	if _, err := client.WithHeader("Origin", "http://evil.example").WebSocket("/ws-client"); err == nil {
+		t.Errorf("Expected cross-origin upgrade to fail")
+	}
+}
+
+// TestWebSocketConnectionValues verifica el estado por conexión y su contexto
+func TestWebSocketConnectionValues(t *testing.T) {
+	closed := make(chan struct{})
+	r := New(WithWebSocketHandler(WebSocketConfig{
+		Path: "/ws-values",
+		OnConnect: func(conn *WebSocketConnection) {
+			conn.SetValue("user", conn.Request.URL.Query().Get("user"))
+			go func() {
+				<-conn.Context().Done()
+				close(closed)
+			}()
+		},
+		MessageHandler: func(conn *WebSocketConnection, msg []byte) {
+			user, _ := conn.Value("user").(string)
+			conn.SetValue("last", string(msg))
+			conn.SendText(fmt.Sprintf("%s:%s:%d", user, conn.Values()["last"], len(conn.Values())))
+		},
+	}))
+
+	ws, err := NewTestClient(r).WebSocket("/ws-values?user=ana")
+	if err != nil {
+		t.Fatalf("Unexpected upgrade error: %v", err)
+	}
+	if err := ws.Send([]byte("hi")); err != nil {
+		t.Fatalf("Unexpected send error: %v", err)
+	}
+	if msg, err := ws.Receive(); err != nil || string(msg) != "ana:hi:2" {
+		t.Errorf("Expected 'ana:hi:2', got '%s' (%v)", msg, err)
+	}
+
+	// Cerrar la conexión cancela su contexto
+	ws.Close()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Error("Expected connection context to be canceled on close")
+	}
+}
+
+// TestWebSocketCompression verifica la negociación y el uso de permessage-deflate
+func TestWebSocketCompression(t *testing.T) {
+	echo := func(conn *WebSocketConnection, msg []byte) {
+		conn.SendText(string(msg))
+	}
+	r := New(
+		WithWebSocketHandler(WebSocketConfig{Path: "/ws-deflate", EnableCompression: true, MessageHandler: echo}),
+		WithWebSocketHandler(WebSocketConfig{Path: "/ws-deflate-takeover", EnableCompression: true, CompressionContextTakeover: true, MessageHandler: echo}),
+		WithWebSocketHandler(WebSocketConfig{Path: "/ws-plain", MessageHandler: echo}),
+	)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	offer := map[string]string{"Sec-WebSocket-Extensions": "permessage-deflate; client_max_window_bits"}
+
+	// Sin EnableCompression no se negocia la extensión
+	conn, _, resp := upgradeWebSocketWithHeaders(t, server, "/ws-plain", offer)
+	conn.Close()
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		t.Errorf("Expected no extensions by default, got '%s'", ext)
+	}
+
+	// Una ventana menor que la de compress/flate no se puede aceptar
+	conn, _, resp = upgradeWebSocketWithHeaders(t, server, "/ws-deflate", map[string]string{
+		"Sec-WebSocket-Extensions": "permessage-deflate; server_max_window_bits=10",
+	})
+	conn.Close()
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		t.Errorf("Expected small window offer to be declined, got '%s'", ext)
+	}
+
+	tests := []struct {
+		path     string
+		takeover bool
+		want     string
+	}{
+		{"/ws-deflate", false, "permessage-deflate; server_no_context_takeover; client_no_context_takeover"},
+		{"/ws-deflate-takeover", true, "permessage-deflate"},
+	}
+	for _, tt := range tests {
+		conn, reader, resp := upgradeWebSocketWithHeaders(t, server, tt.path, offer)
+		if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != tt.want {
+			t.Errorf("%s: expected extensions '%s', got '%s'", tt.path, tt.want, ext)
+		}
+
+		// El cliente comprime con una ventana compartida entre mensajes solo
+		// si hay context takeover
+		var out bytes.Buffer
+		fw, _ := flate.NewWriter(&out, flate.BestSpeed)
+		var history []byte
+		for _, text := range []string{"hello hello hello hello", "hello hello hello hello again"} {
+			out.Reset()
+			if !tt.takeover {
+				fw.Reset(&out)
+			}
+			fw.Write([]byte(text))
+			fw.Flush()
+			writeClientFrame(t, conn, 0x40|0x1, bytes.TrimSuffix(out.Bytes(), []byte{0, 0, 0xff, 0xff}))
+
+			first, _ := reader.Peek(1)
+			if first[0]&0x40 == 0 {
+				t.Fatalf("%s: expected RSV1 on compressed reply", tt.path)
+			}
+			_, payload := readServerFrame(t, reader)
+			var dict []byte
+			if tt.takeover {
+				dict = history
+			}
+			fr := flate.NewReaderDict(io.MultiReader(bytes.NewReader(payload), strings.NewReader(deflateTail)), dict)
+			inflated, err := io.ReadAll(fr)
+			if err != nil {
+				t.Fatalf("%s: error inflating reply: %v", tt.path, err)
+			}
+			if string(inflated) != text {
+				t.Errorf("%s: expected echo '%s', got '%s'", tt.path, text, inflated)
+			}
+			history = append(history, inflated...)
+		}
+		conn.Close()
+	}
+}